@@ -3,13 +3,26 @@ package actor
 import (
 	"context"
 	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/profile"
 	"github.com/dzm2020/gas/pkg/lib/component"
+	"time"
 )
 
 const (
 	ComponentName = "system"
 )
 
+// Config actor 组件配置
+type Config struct {
+	MigrateForwardTTL time.Duration `json:"migrateForwardTTL" yaml:"migrateForwardTTL"` // 迁移后原 pid 保留转发的时长，超时后原 pid 不再可用
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		MigrateForwardTTL: 10 * time.Minute,
+	}
+}
+
 // NewComponent 创建 actor 组件
 func NewComponent() *Component {
 	return &Component{}
@@ -25,9 +38,14 @@ func (c *Component) Name() string {
 }
 
 func (c *Component) Start(ctx context.Context, node iface.INode) error {
+	conf := defaultConfig()
+	if err := profile.Get(c.Name(), conf); err != nil {
+		return err
+	}
 	c.System = NewSystem(node)
+	c.System.forwardTTL = conf.MigrateForwardTTL
 	node.SetSystem(c.System)
-	return nil
+	return c.System.registerMigrator()
 }
 
 func (c *Component) Stop(ctx context.Context) error {
@@ -2,10 +2,13 @@
 package actor
 
 import (
+	"context"
 	"github.com/dzm2020/gas/internal/iface"
 	"github.com/dzm2020/gas/internal/session"
 	"github.com/dzm2020/gas/pkg/glog"
 	"github.com/dzm2020/gas/pkg/lib"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+	"github.com/dzm2020/gas/pkg/trace"
 	"time"
 
 	"github.com/duke-git/lancet/v2/convertor"
@@ -18,14 +21,17 @@ const DefaultCallTimeout = 3 * time.Second
 var _ iface.IContext = (*actorContext)(nil)
 
 type actorContext struct {
-	process iface.IProcess // 保存自己的 process 引用
-	pid     *iface.Pid
-	actor   iface.IActor
-	router  iface.IRouter
-	msg     *iface.ActorMessage
-	node    iface.INode
-	system  iface.ISystem
-	timeout time.Duration
+	process  iface.IProcess // 保存自己的 process 引用
+	pid      *iface.Pid
+	actor    iface.IActor
+	router   iface.IRouter
+	msg      *iface.ActorMessage
+	node     iface.INode
+	system   iface.ISystem
+	timeout  time.Duration
+	span     *trace.Span     // 当前消息的追踪 span
+	msgCtx   context.Context // 当前消息的 context，携带截止时间
+	migrated iface.IProcess  // 迁移后接管原 pid 的转发进程，邮箱中剩余的消息交给它处理
 }
 
 func (a *actorContext) ID() *iface.Pid {
@@ -47,10 +53,24 @@ func (a *actorContext) Actor() iface.IActor {
 func (a *actorContext) Message() *iface.ActorMessage {
 	return a.msg
 }
+
+// Context 返回当前消息的 context，携带消息截止时间，消息处理结束后被取消
+// 不在消息处理过程中时返回 context.Background()
+func (a *actorContext) Context() context.Context {
+	if a.msgCtx == nil {
+		return context.Background()
+	}
+	return a.msgCtx
+}
 func (a *actorContext) InvokerMessage(msg interface{}) error {
+	if a.migrated != nil {
+		if m, ok := msg.(iface.IMessage); ok {
+			return a.migrated.PostMessage(m)
+		}
+	}
 	switch m := msg.(type) {
 	case *iface.TaskMessage:
-		return m.Task(a)
+		return m.Task.Run(a)
 	case *iface.ActorMessage:
 		return a.handleMessage(m)
 	}
@@ -59,23 +79,88 @@ func (a *actorContext) InvokerMessage(msg interface{}) error {
 
 // handleMessage 处理 Actor 消息
 // 如果消息有对应的路由，则通过路由处理；否则调用 actor.OnMessage
-func (a *actorContext) handleMessage(m *iface.ActorMessage) error {
-	a.msg = m
+func (a *actorContext) handleMessage(m *iface.ActorMessage) (err error) {
 	methodName := m.Message.GetMethod()
+
+	// 已超过截止时间的消息调用方已放弃等待，不再执行处理器
+	if m.IsExpired() {
+		m.Response(nil, iface.ErrDeadlineExceeded)
+		return xerror.Wrapf(iface.ErrDeadlineExceeded, "丢弃过期消息 (method=%s)", methodName)
+	}
+
+	// 会话消息可能在处理过程中重入，需要恢复外层消息的状态
+	prevMsg, prevSpan, prevCtx := a.msg, a.span, a.msgCtx
+	var cancel context.CancelFunc
+	a.msg = m
+	a.msgCtx, cancel = newMessageContext(m)
+	a.span = trace.Start("actor.handle", m.GetTraceParent())
+	if a.span.IsRecording() {
+		a.span.SetAttr("method", methodName).SetAttr("pid", a.pid.String())
+	}
+	defer func() {
+		a.span.End(err)
+		cancel()
+		a.msg, a.span, a.msgCtx = prevMsg, prevSpan, prevCtx
+	}()
+
+	// 远程提交的具名任务
+	if methodName == iface.TaskMethod {
+		err = a.runRemoteTask(m.Message)
+		m.Response(nil, err)
+		return err
+	}
+
 	if a.router != nil && methodName != "" && a.router.HasRoute(methodName) {
 		data, err := a.execHandler(m.Message)
 		m.Response(data, err)
 		return err
 	}
-	// 如果没有路由，调用 actor.OnMessage
-	err := a.actor.OnMessage(a, m.Message)
+	// 如果没有路由，调用 actor.OnMessage，已注册类型的消息解析为对应的值
+	err = a.actor.OnMessage(a, a.decodeValue(m))
 	m.Response(nil, err)
-	a.msg = nil
 
 	glog.Warn("actor没有找到消息路由,执行默认方法", zap.Any("pid", a.ID()), zap.String("method", methodName))
 	return err
 }
 
+// decodeValue 解析 OnMessage 收到的消息：本地投递的原值直接使用，携带已注册类型名的消息反序列化为该类型，
+// 其余情况（未携带类型名、类型未注册或解析失败）传入原始的 *iface.Message
+func (a *actorContext) decodeValue(m *iface.ActorMessage) interface{} {
+	if value := m.GetValue(); value != nil {
+		return value
+	}
+	typeName := m.GetTypeName()
+	if typeName == "" {
+		return m.Message
+	}
+	value, err := iface.NewTypedValue(typeName)
+	if err == nil {
+		err = a.node.UnmarshalWith(a.ContentType(), m.GetData(), value)
+	}
+	if err != nil {
+		glog.Warn("actor解析消息类型失败,传入原始消息", zap.Any("pid", a.ID()), zap.String("type", typeName), zap.Error(err))
+		return m.Message
+	}
+	return value
+}
+
+// newMessageContext 创建消息处理期间的 context，有截止时间的消息会在到期时自动取消
+func newMessageContext(m *iface.ActorMessage) (context.Context, context.CancelFunc) {
+	if !m.HasDeadline() {
+		return context.Background(), func() {}
+	}
+	return context.WithDeadline(context.Background(), m.DeadlineTime())
+}
+
+// runRemoteTask 执行其他节点提交的具名任务
+func (a *actorContext) runRemoteTask(msg *iface.Message) error {
+	request := &iface.TaskRequest{}
+	if err := a.node.UnmarshalWith(msg.GetContentType(), msg.GetData(), request); err != nil {
+		return xerror.Wrap(err, "解析远程任务失败")
+	}
+	return iface.RunTask(a, request.GetName(), request.GetArgs())
+}
+
 // execHandler 基于方法名执行处理器
 func (a *actorContext) execHandler(msg *iface.Message) ([]byte, error) {
 	s := session.NewWithSession(msg.GetSession())
@@ -83,15 +168,48 @@ func (a *actorContext) execHandler(msg *iface.Message) ([]byte, error) {
 	return a.router.Handle(a, msg.GetMethod(), s, msg.GetData())
 }
 
+// ContentType 当前消息 data 的序列化格式
+// 消息未携带格式时（本地消息或来自网关的消息）按 actor 为该方法指定的格式处理
+func (a *actorContext) ContentType() uint32 {
+	if a.msg == nil {
+		return 0
+	}
+	if contentType := a.msg.GetContentType(); contentType != 0 {
+		return contentType
+	}
+	return a.methodContentType(a.msg.GetMethod())
+}
+
+// methodContentType actor 为方法指定的序列化格式，未指定时返回 0
+func (a *actorContext) methodContentType(method string) uint32 {
+	if selector, ok := a.actor.(iface.ISerializerSelector); ok {
+		return selector.ContentType(method)
+	}
+	return 0
+}
+
+// outgoingContentType 发出消息使用的序列化格式
+func (a *actorContext) outgoingContentType(method string) uint32 {
+	if contentType := a.methodContentType(method); contentType != 0 {
+		return contentType
+	}
+	return a.node.ContentType()
+}
+
+// Send 异步发送消息；已注册类型（iface.RegisterType）发往本地 actor 时直接投递原值，
+// 发送后调用方不应再修改 request
 func (a *actorContext) Send(pid *iface.Pid, methodName string, request interface{}) (err error) {
-	var data []byte
-	data, err = a.node.Marshal(request)
-	if err != nil {
+	message := iface.NewActorMessage(a.pid, pid, methodName, nil)
+	message.Async = true
+	message.ContentType = a.outgoingContentType(methodName)
+	if err = a.encodeRequest(message, request, pid.GetNodeId() == a.node.GetID()); err != nil {
 		return
 	}
 
-	message := iface.NewActorMessage(a.pid, pid, methodName, data)
-	message.Async = true
+	span := a.startSpan("actor.send", message)
+	defer func() {
+		span.End(err)
+	}()
 	return a.system.Send(message)
 }
 
@@ -101,21 +219,76 @@ func (a *actorContext) SetCallTimeout(timeout time.Duration) {
 
 // Call 带超时的同步调用
 func (a *actorContext) Call(to *iface.Pid, methodName string, request interface{}, reply interface{}) (err error) {
-	var data []byte
-	data, err = a.node.Marshal(request)
+	contentType := a.outgoingContentType(methodName)
+	message := iface.NewActorMessage(a.pid, to, methodName, nil)
+	message.Async = false
+	message.ContentType = contentType
+	if err = a.encodeRequest(message, request, false); err != nil {
+		return
+	}
+
+	timeout, err := a.callTimeout()
 	if err != nil {
 		return
 	}
+	message.SetTimeout(timeout)
 
-	message := iface.NewActorMessage(a.pid, to, methodName, data)
-	message.Deadline = time.Now().Add(a.timeout).Unix()
-	message.Async = false
+	span := a.startSpan("actor.call", message)
+	defer func() {
+		span.End(err)
+	}()
 
-	data, err = a.system.Call(message)
+	data, err := a.system.Call(message)
 	if err != nil {
 		return
 	}
-	return a.node.Unmarshal(data, reply)
+	// 接收方按请求的格式序列化回复
+	return a.node.UnmarshalWith(contentType, data, reply)
+}
+
+// encodeRequest 写入请求数据，已注册类型同时写入类型名；
+// local 为 true 时已注册类型不序列化，由接收方直接使用原值
+func (a *actorContext) encodeRequest(message *iface.ActorMessage, request interface{}, local bool) error {
+	if typeName, ok := iface.TypeNameOf(request); ok {
+		message.TypeName = typeName
+		if local {
+			message.Value = request
+			return nil
+		}
+	}
+	data, err := a.node.MarshalWith(message.ContentType, request)
+	if err != nil {
+		return err
+	}
+	message.Data = data
+	return nil
+}
+
+// callTimeout 计算子调用的超时时间，不超过当前消息剩余的时间预算
+func (a *actorContext) callTimeout() (time.Duration, error) {
+	timeout := a.timeout
+	deadline, ok := a.Context().Deadline()
+	if !ok {
+		return timeout, nil
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0, iface.ErrDeadlineExceeded
+	}
+	if remaining < timeout {
+		timeout = remaining
+	}
+	return timeout, nil
+}
+
+// startSpan 以当前消息的 span 为父节点创建出站 span，并写入消息的 traceparent
+func (a *actorContext) startSpan(name string, message *iface.ActorMessage) *trace.Span {
+	span := trace.Start(name, a.span.TraceParent())
+	if span.IsRecording() {
+		span.SetAttr("method", message.GetMethod()).SetAttr("to", message.GetTo().String())
+	}
+	message.TraceParent = span.TraceParent()
+	return span
 }
 
 func (a *actorContext) Forward(to *iface.Pid, method string) error {
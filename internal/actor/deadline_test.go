@@ -0,0 +1,154 @@
+package actor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+)
+
+type payload struct {
+	Text string `json:"text"`
+}
+
+// echoActor 等待 delay 后原样回复
+type echoActor struct {
+	iface.Actor
+	delay    time.Duration
+	handled  atomic.Int32
+	deadline chan time.Time
+}
+
+func (a *echoActor) Echo(ctx iface.IContext, request *payload, response *payload) error {
+	a.handled.Add(1)
+	if a.deadline != nil {
+		deadline, _ := ctx.Context().Deadline()
+		a.deadline <- deadline
+	}
+	time.Sleep(a.delay)
+	response.Text = request.Text
+	return nil
+}
+
+type relayResult struct {
+	elapsed time.Duration
+	err     error
+}
+
+// relayActor 收到请求后以较长的超时调用 target
+type relayActor struct {
+	iface.Actor
+	target *iface.Pid
+	result chan relayResult
+}
+
+func (a *relayActor) Relay(ctx iface.IContext, request *payload, response *payload) error {
+	ctx.SetCallTimeout(5 * time.Second)
+	start := time.Now()
+	err := ctx.Call(a.target, "Echo", request, response)
+	a.result <- relayResult{elapsed: time.Since(start), err: err}
+	return err
+}
+
+func newCall(t *testing.T, to *iface.Pid, method string, timeout time.Duration) *iface.ActorMessage {
+	t.Helper()
+	data, err := json.Marshal(&payload{Text: "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := iface.NewActorMessage(nil, to, method, data)
+	msg.SetTimeout(timeout)
+	return msg
+}
+
+// TestDeadlinePropagation 测试截止时间随消息传到其它节点，子调用的超时不超过调用方剩余的时间
+func TestDeadlinePropagation(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+
+	echo := &echoActor{delay: time.Second, deadline: make(chan time.Time, 1)}
+	echoPid := n2.System().Spawn(echo)
+	relay := &relayActor{target: echoPid, result: make(chan relayResult, 1)}
+	relayPid := n1.System().Spawn(relay)
+
+	msg := newCall(t, relayPid, "Relay", 200*time.Millisecond)
+	start := time.Now()
+	if _, err := n1.System().Call(msg); err == nil {
+		t.Fatal("want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("调用方等待了 %v", elapsed)
+	}
+
+	select {
+	case deadline := <-echo.deadline:
+		if diff := deadline.Sub(msg.DeadlineTime()); diff > 0 || diff < -50*time.Millisecond {
+			t.Fatalf("远程处理器的截止时间 %v 与调用方 %v 不一致", deadline, msg.DeadlineTime())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("远程处理器未执行")
+	}
+	select {
+	case result := <-relay.result:
+		if result.err == nil || result.elapsed > 500*time.Millisecond {
+			t.Fatalf("子调用未受调用方截止时间限制: elapsed=%v err=%v", result.elapsed, result.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("子调用未返回")
+	}
+}
+
+// TestExpiredMessageDropped 测试出队时已超过截止时间的消息不再执行处理器
+func TestExpiredMessageDropped(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+
+	for _, caller := range []iface.ISystem{n2.System(), n1.System()} {
+		echo := &echoActor{}
+		pid := n2.System().Spawn(echo)
+		// 让 actor 忙于前一个任务，调用在排队期间过期
+		busy := iface.Task(func(ctx iface.IContext) error {
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		})
+		if err := n2.System().SubmitTask(pid, busy); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := caller.Call(newCall(t, pid, "Echo", 50*time.Millisecond)); err == nil {
+			t.Fatal("want timeout")
+		}
+		time.Sleep(300 * time.Millisecond)
+		if handled := echo.handled.Load(); handled != 0 {
+			t.Fatalf("过期消息被处理了 %d 次", handled)
+		}
+	}
+}
+
+// TestCallExpiredDeadline 测试已过期的调用不再发出
+func TestCallExpiredDeadline(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+	echo := &echoActor{}
+	local, remote := n1.System().Spawn(echo), n2.System().Spawn(echo)
+
+	for _, pid := range []*iface.Pid{local, remote} {
+		msg := newCall(t, pid, "Echo", time.Second)
+		msg.Deadline = time.Now().Add(-time.Millisecond).UnixMilli()
+		if _, err := n1.System().Call(msg); !errors.Is(err, iface.ErrDeadlineExceeded) {
+			t.Fatalf("want ErrDeadlineExceeded, got %v", err)
+		}
+	}
+	msg := newCall(t, remote, "Echo", time.Second)
+	msg.Deadline = time.Now().Add(-time.Millisecond).UnixMilli()
+	if _, err := n1.Cluster().Call(msg); !errors.Is(err, iface.ErrDeadlineExceeded) {
+		t.Fatalf("want ErrDeadlineExceeded, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if handled := echo.handled.Load(); handled != 0 {
+		t.Fatalf("过期调用被处理了 %d 次", handled)
+	}
+}
@@ -0,0 +1,324 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrActorNotMigratable      = errors.New("actor 不支持迁移")
+	ErrMigratableNotRegistered = errors.New("可迁移 actor 类型未注册")
+	ErrMigrateRemotePid        = errors.New("只能迁移本节点的进程")
+	ErrMigrateToSelf           = errors.New("不能迁移到本节点")
+	ErrProcessMigrated         = errors.New("进程已迁移，闭包任务无法转发")
+	ErrMigrateBufferFull       = errors.New("进程迁移中，缓存已满")
+)
+
+const (
+	// migratorName 每个节点上接收迁移请求的进程名
+	migratorName   = "$migrator"
+	migratorMethod = "Migrate"
+	// migrateBufferSize 迁移期间最多缓存的消息数
+	migrateBufferSize = 10000
+)
+
+var (
+	migratables sync.Map // 类型名 -> func() iface.IMigratable
+)
+
+// RegisterMigratable 注册可迁移的 actor 类型，所有可能作为迁移目标的节点都需要注册
+func RegisterMigratable(producer func() iface.IMigratable) {
+	migratables.Store(migrateTypeName(producer()), producer)
+}
+
+// migrateTypeName 使用包路径和类型名标识可迁移类型，不同包中的同名类型不会冲突
+func migrateTypeName(actor iface.IActor) string {
+	t := reflect.TypeOf(actor)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// Migrate 将本节点的 actor 迁移到目标节点，返回新的 pid
+//
+// 迁移在 actor 邮箱中执行：序列化状态后原 pid 被替换为转发进程，之后到达的消息被缓存；
+// 目标节点创建实例并恢复状态后，缓存的消息和后续发往原 pid 的消息都会被转发到新 pid。
+// 目标节点创建失败时回滚，缓存的消息重新投递给原 actor；迁移成功后原实例调用 OnStop。
+// 迁移需要等待 actor 邮箱，不能在该 actor 自身的消息处理中调用：调用会等到超时后返回错误，actor 不会被迁移。
+func (s *System) Migrate(pid *iface.Pid, nodeId uint64, timeout time.Duration) (*iface.Pid, error) {
+	if !s.isLocalPid(pid) {
+		return nil, ErrMigrateRemotePid
+	}
+	if nodeId == s.node.GetID() {
+		return nil, ErrMigrateToSelf
+	}
+	if s.node.Cluster() == nil {
+		return nil, ErrClusterIsNil
+	}
+	process, ok := s.GetProcess(pid).(*Process)
+	if !ok {
+		return nil, xerror.Wrapf(ErrProcessNotFound, "pid=%v", pid)
+	}
+	if _, ok = process.ctx.actor.(iface.IMigratable); !ok {
+		return nil, xerror.Wrapf(ErrActorNotMigratable, "pid=%v", pid)
+	}
+
+	deadline := time.Now().Add(timeout)
+	var newPid *iface.Pid
+	err := s.SubmitTaskAndWait(pid, iface.Task(func(ctx iface.IContext) (err error) {
+		// 调用方已超时返回，不再迁移
+		if time.Now().After(deadline) {
+			return xerror.Wrapf(iface.ErrDeadlineExceeded, "pid=%v", pid)
+		}
+		newPid, err = s.migrate(process.ctx, nodeId, deadline)
+		return err
+	}), timeout)
+	return newPid, err
+}
+
+// migrate 在 actor 邮箱中执行迁移
+func (s *System) migrate(ctx *actorContext, nodeId uint64, deadline time.Time) (*iface.Pid, error) {
+	actor := ctx.actor.(iface.IMigratable)
+	oldPid := ctx.pid
+
+	state, err := actor.MarshalState()
+	if err != nil {
+		return nil, xerror.Wrap(err, "序列化 actor 状态失败")
+	}
+
+	// 挂起: 之后发往原 pid 的消息进入转发进程缓存，邮箱中剩余的消息也交给转发进程
+	forwarder := newForwardProcess(s, oldPid)
+	s.Add(oldPid, forwarder)
+	ctx.migrated = forwarder
+
+	// 全局名字需要先释放，目标节点才能注册
+	name := oldPid.GetName()
+	global := name != "" && oldPid.IsGlobalName()
+	if global {
+		if err = s.Unname(oldPid); err != nil {
+			glog.Warn("迁移: 释放全局名字失败", zap.String("name", name), zap.Error(err))
+		}
+	}
+
+	newPid, err := s.spawnRemote(oldPid, nodeId, &iface.MigrateRequest{
+		Type:  migrateTypeName(actor),
+		State: state,
+		Name:  name,
+	}, deadline)
+	if err != nil {
+		// 回滚
+		ctx.migrated = nil
+		if global {
+			s.nameDict.Set(name, oldPid)
+			if namedErr := s.clusterNamed(name); namedErr != nil {
+				glog.Error("迁移: 恢复全局名字失败", zap.String("name", name), zap.Error(namedErr))
+			}
+		}
+		forwarder.rollback(ctx.process)
+		return nil, xerror.Wrapf(err, "迁移进程失败 (pid=%v, nodeId=%d)", oldPid, nodeId)
+	}
+
+	forwarder.setTarget(newPid, s.forwardTTL)
+	// 原实例不再处理消息，释放其持有的资源；原 pid 已由转发进程占用，不从系统中移除
+	if err = actor.OnStop(ctx); err != nil {
+		glog.Warn("迁移: 原实例 OnStop 失败", zap.Any("pid", oldPid), zap.Error(err))
+	}
+	glog.Info("迁移: 进程迁移完成", zap.Any("old", oldPid), zap.Any("new", newPid))
+	return newPid, nil
+}
+
+func (s *System) spawnRemote(from *iface.Pid, nodeId uint64, request *iface.MigrateRequest, deadline time.Time) (*iface.Pid, error) {
+	data, err := s.node.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	msg := iface.NewActorMessage(from, iface.NewPidWithName(migratorName, nodeId), migratorMethod, data)
+	msg.SetTimeout(time.Until(deadline))
+	bin, err := s.Call(msg)
+	if err != nil {
+		return nil, err
+	}
+	response := &iface.MigrateResponse{}
+	if err = s.node.Unmarshal(bin, response); err != nil {
+		return nil, err
+	}
+	return response.GetPid(), nil
+}
+
+// registerMigrator 注册接收迁移请求的进程
+func (s *System) registerMigrator() error {
+	pid := s.NewPid()
+	s.Add(pid, &migratorProcess{system: s})
+	return s.Named(migratorName, pid)
+}
+
+var _ iface.IProcess = (*migratorProcess)(nil)
+
+// migratorProcess 在目标节点上创建迁移过来的 actor
+type migratorProcess struct {
+	system *System
+}
+
+func (m *migratorProcess) Context() iface.IContext {
+	return nil
+}
+
+func (m *migratorProcess) PostMessage(message iface.IMessage) error {
+	msg, ok := message.(*iface.ActorMessage)
+	if !ok {
+		return xerror.Wrapf(ErrProcessMigrated, "message=%T", message)
+	}
+	request := &iface.MigrateRequest{}
+	if err := m.system.node.UnmarshalWith(msg.GetContentType(), msg.GetData(), request); err != nil {
+		return xerror.Wrap(err, "解析迁移请求失败")
+	}
+	pid, err := m.spawn(request)
+	if err != nil {
+		msg.Response(nil, err)
+		return nil
+	}
+	data, err := m.system.node.MarshalWith(msg.GetContentType(), &iface.MigrateResponse{Pid: pid})
+	msg.Response(data, err)
+	return nil
+}
+
+func (m *migratorProcess) spawn(request *iface.MigrateRequest) (*iface.Pid, error) {
+	value, ok := migratables.Load(request.GetType())
+	if !ok {
+		return nil, xerror.Wrapf(ErrMigratableNotRegistered, "type=%s", request.GetType())
+	}
+	actor := value.(func() iface.IMigratable)()
+	if err := actor.UnmarshalState(request.GetState()); err != nil {
+		return nil, xerror.Wrapf(err, "恢复 actor 状态失败 (type=%s)", request.GetType())
+	}
+	pid := m.system.Spawn(actor)
+	if name := request.GetName(); name != "" {
+		if err := m.system.Named(name, pid); err != nil {
+			if process := m.system.GetProcess(pid); process != nil {
+				_ = process.Shutdown()
+			}
+			return nil, err
+		}
+	}
+	return pid, nil
+}
+
+func (m *migratorProcess) Shutdown() error {
+	return nil
+}
+
+var _ iface.IProcess = (*forwardProcess)(nil)
+
+// forwardProcess 占用已迁移进程的原 pid，迁移完成前缓存消息，完成后转发到新 pid
+type forwardProcess struct {
+	system   *System
+	pid      *iface.Pid
+	mu       sync.Mutex
+	target   *iface.Pid
+	buffer   []iface.IMessage
+	fallback iface.IProcess // 迁移失败后恢复的原进程
+}
+
+func newForwardProcess(system *System, pid *iface.Pid) *forwardProcess {
+	return &forwardProcess{
+		system: system,
+		pid:    pid,
+	}
+}
+
+func (f *forwardProcess) Context() iface.IContext {
+	return nil
+}
+
+func (f *forwardProcess) PostMessage(message iface.IMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fallback != nil {
+		return f.fallback.PostMessage(message)
+	}
+	if f.target == nil {
+		if len(f.buffer) >= migrateBufferSize {
+			return ErrMigrateBufferFull
+		}
+		f.buffer = append(f.buffer, message)
+		return nil
+	}
+	return f.forward(message)
+}
+
+// forward 转发到新 pid，需要持有锁以保证消息顺序
+func (f *forwardProcess) forward(message iface.IMessage) error {
+	switch m := message.(type) {
+	case *iface.ActorMessage:
+		if m.IsExpired() {
+			m.Response(nil, iface.ErrDeadlineExceeded)
+			return nil
+		}
+		// 复制后转发，避免修改调用方的消息和覆盖其响应回调
+		out := m.Redirect(f.target)
+		if out.GetAsync() {
+			return f.system.Send(out)
+		}
+		grs.Go(func(ctx context.Context) {
+			data, err := f.system.Call(out)
+			m.Response(data, err)
+		})
+		return nil
+	case *iface.TaskMessage:
+		if named, ok := m.Task.(*iface.NamedTask); ok {
+			return f.system.SubmitTask(f.target, named)
+		}
+		return ErrProcessMigrated
+	}
+	return xerror.Wrapf(ErrProcessMigrated, "message=%T", message)
+}
+
+// setTarget 迁移完成，转发缓存的消息，并在 ttl 后释放原 pid
+func (f *forwardProcess) setTarget(target *iface.Pid, ttl time.Duration) {
+	f.mu.Lock()
+	f.target = target
+	buffer := f.buffer
+	f.buffer = nil
+	for _, message := range buffer {
+		if err := f.forward(message); err != nil {
+			glog.Warn("迁移: 转发缓存消息失败", zap.Any("pid", f.pid), zap.Error(err))
+		}
+	}
+	f.mu.Unlock()
+
+	time.AfterFunc(ttl, func() {
+		_ = f.Shutdown()
+	})
+}
+
+// rollback 迁移失败，恢复原进程并将缓存的消息重新投递给它
+func (f *forwardProcess) rollback(process iface.IProcess) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.system.Add(f.pid, process)
+	f.fallback = process
+	for _, message := range f.buffer {
+		if err := process.PostMessage(message); err != nil {
+			glog.Warn("迁移: 回滚消息失败", zap.Any("pid", f.pid), zap.Error(err))
+		}
+	}
+	f.buffer = nil
+}
+
+func (f *forwardProcess) Shutdown() error {
+	if f.system.GetProcess(f.pid) != iface.IProcess(f) {
+		return nil
+	}
+	return f.system.Remove(f.pid)
+}
@@ -0,0 +1,19 @@
+package actor
+
+import (
+	"testing"
+
+	"github.com/dzm2020/gas/internal/iface"
+)
+
+type namedActor struct {
+	iface.Actor
+}
+
+// TestMigrateTypeName 测试可迁移类型名包含包路径
+func TestMigrateTypeName(t *testing.T) {
+	want := "github.com/dzm2020/gas/internal/actor.namedActor"
+	if name := migrateTypeName(&namedActor{}); name != want {
+		t.Fatalf("want %s, got %s", want, name)
+	}
+}
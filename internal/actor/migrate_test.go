@@ -0,0 +1,145 @@
+package actor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/actor"
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+)
+
+type scoreReply struct {
+	NodeId uint64 `json:"nodeId"`
+	Score  int    `json:"score"`
+}
+
+// scoreActor 可迁移的 actor，Add 累加分数并返回所在节点
+type scoreActor struct {
+	iface.Actor
+	Score    int `json:"score"`
+	stopped  *atomic.Int32
+	migrated chan error
+}
+
+func (a *scoreActor) MarshalState() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+func (a *scoreActor) UnmarshalState(data []byte) error {
+	return json.Unmarshal(data, a)
+}
+
+func (a *scoreActor) OnStop(ctx iface.IContext) error {
+	if a.stopped != nil {
+		a.stopped.Add(1)
+	}
+	return nil
+}
+
+func (a *scoreActor) Add(ctx iface.IContext, request *scoreReply, response *scoreReply) error {
+	a.Score += request.Score
+	response.NodeId = ctx.Node().GetID()
+	response.Score = a.Score
+	return nil
+}
+
+// MigrateSelf 在自身的消息处理中迁移自己
+func (a *scoreActor) MigrateSelf(ctx iface.IContext, request *scoreReply, response *scoreReply) error {
+	_, err := ctx.System().Migrate(ctx.ID(), request.NodeId, 100*time.Millisecond)
+	a.migrated <- err
+	return nil
+}
+
+func init() {
+	actor.RegisterMigratable(func() iface.IMigratable { return &scoreActor{} })
+}
+
+func add(t *testing.T, system iface.ISystem, pid *iface.Pid, score int) *scoreReply {
+	t.Helper()
+	data, err := json.Marshal(&scoreReply{Score: score})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := iface.NewActorMessage(nil, pid, "Add", data)
+	msg.SetTimeout(time.Second)
+	bin, err := system.Call(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetTo() != pid {
+		t.Fatalf("调用方的消息被修改: to=%v", msg.GetTo())
+	}
+	reply := &scoreReply{}
+	if err = json.Unmarshal(bin, reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+// TestMigrate 测试迁移后状态保留、原 pid 的消息转发到新节点、原实例被停止，转发在配置的时长后释放
+func TestMigrate(t *testing.T) {
+	c := nodetest.New(t, "system:\n  migrateForwardTTL: 200ms\n")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+	stopped := &atomic.Int32{}
+	oldPid := n1.System().Spawn(&scoreActor{stopped: stopped})
+	add(t, n1.System(), oldPid, 3)
+
+	newPid, err := n1.System().Migrate(oldPid, 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPid.GetNodeId() != 2 {
+		t.Fatalf("want pid on node 2, got %v", newPid)
+	}
+	if stopped.Load() != 1 {
+		t.Fatalf("原实例 OnStop 调用了 %d 次", stopped.Load())
+	}
+	if reply := add(t, n2.System(), newPid, 4); reply.NodeId != 2 || reply.Score != 7 {
+		t.Fatalf("want score 7 on node 2, got %+v", reply)
+	}
+	if reply := add(t, n1.System(), oldPid, 1); reply.NodeId != 2 || reply.Score != 8 {
+		t.Fatalf("原 pid 未转发到新实例: %+v", reply)
+	}
+
+	nodetest.Eventually(t, time.Second, func() bool {
+		return n1.System().GetProcess(oldPid) == nil
+	}, "转发进程未在配置的时长后释放")
+}
+
+// TestMigrateErrors 测试迁移到本节点、迁移不可迁移的 actor 返回错误，在自身消息处理中迁移超时后不迁移
+func TestMigrateErrors(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, _ := c.Start(1, "game"), c.Start(2, "game")
+	a := &scoreActor{migrated: make(chan error, 1)}
+	pid := n1.System().Spawn(a)
+
+	if _, err := n1.System().Migrate(pid, 1, time.Second); !errors.Is(err, actor.ErrMigrateToSelf) {
+		t.Fatalf("want ErrMigrateToSelf, got %v", err)
+	}
+	if _, err := n1.System().Migrate(n1.System().Spawn(&counterActor{}), 2, time.Second); !errors.Is(err, actor.ErrActorNotMigratable) {
+		t.Fatalf("want ErrActorNotMigratable, got %v", err)
+	}
+
+	data, _ := json.Marshal(&scoreReply{NodeId: 2})
+	msg := iface.NewActorMessage(nil, pid, "MigrateSelf", data)
+	msg.Async = true
+	if err := n1.System().Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-a.migrated:
+		if err == nil {
+			t.Fatal("在自身消息处理中迁移未返回错误")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("迁移未返回")
+	}
+	// 迁移失败后 actor 仍可正常处理消息
+	if reply := add(t, n1.System(), pid, 1); reply.NodeId != 1 || reply.Score != 1 {
+		t.Fatalf("want score 1 on node 1, got %+v", reply)
+	}
+}
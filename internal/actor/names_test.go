@@ -0,0 +1,51 @@
+package actor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+
+	"golang.org/x/exp/slices"
+)
+
+var errAddTag = errors.New("同步标签失败")
+
+// failingCluster 同步标签总是失败的集群
+type failingCluster struct {
+	iface.ICluster
+}
+
+func (c failingCluster) AddTag(tag string) error {
+	return errAddTag
+}
+
+// TestGlobalNameReleasedOnFailure 测试全局名字占用成功但标签同步失败时释放占用，名字仍可被其他节点注册
+func TestGlobalNameReleasedOnFailure(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+	cluster := n1.Cluster()
+	registry := cluster.Discovery().(discovery.INameRegistry)
+
+	n1.SetCluster(failingCluster{ICluster: cluster})
+	err := n1.System().Named("Boss", n1.System().Spawn(&counterActor{}))
+	n1.SetCluster(cluster)
+	if !errors.Is(err, errAddTag) {
+		t.Fatalf("want errAddTag, got %v", err)
+	}
+	if owner, ok := registry.Lookup("Boss"); ok {
+		t.Fatalf("名字仍被节点 %d 占用", owner)
+	}
+	if n1.System().HasName("Boss") || slices.Contains(n1.Info().Tags, "Boss") {
+		t.Fatal("失败的名字仍注册在本地")
+	}
+
+	if err = n2.System().Named("Boss", n2.System().Spawn(&counterActor{})); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := registry.Lookup("Boss"); owner != 2 {
+		t.Fatalf("want owner 2, got %d", owner)
+	}
+}
@@ -54,9 +54,9 @@ func (p *Process) Shutdown() error {
 	}
 
 	// 创建一个退出任务，通过 mailbox 发送，确保在消息处理完成后才执行退出
-	msg := iface.NewTaskMessage(func(ctx iface.IContext) error {
+	msg := iface.NewTaskMessage(iface.Task(func(ctx iface.IContext) error {
 		return p.ctx.exit()
-	})
+	}))
 
 	return p.mailbox.PostMessage(msg)
 }
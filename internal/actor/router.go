@@ -309,7 +309,7 @@ func (r *Router) handleSyncMessage(ctx iface.IContext, methodName string, data [
 
 	// 序列化响应（response 是最后一个参数）
 	responseValue := callArgs[len(callArgs)-1]
-	responseData, err := ctx.Node().Marshal(responseValue.Interface())
+	responseData, err := ctx.Node().MarshalWith(ctx.ContentType(), responseValue.Interface())
 	if err != nil {
 		return nil, xerror.Wrap(err, "序列化响应失败")
 	}
@@ -346,6 +346,17 @@ func (r *Router) handleSessionOnlyMessage(ctx iface.IContext, methodName string,
 
 // createRequestValue 从 Data 创建请求值
 func (r *Router) createRequestValue(requestType reflect.Type, isByteRequest bool, data []byte, ctx iface.IContext) (reflect.Value, error) {
+	// 本地投递的原值类型一致时直接使用，否则先序列化再按处理器的参数类型解析
+	if value := ctx.Message().GetValue(); value != nil {
+		if reflect.TypeOf(value) == requestType {
+			return reflect.ValueOf(value), nil
+		}
+		var err error
+		if data, err = ctx.Node().MarshalWith(ctx.ContentType(), value); err != nil {
+			return reflect.Value{}, xerror.Wrapf(err, "序列化请求参数失败 (type=%T)", value)
+		}
+	}
+
 	// 如果消息类型是 []byte，则不需要反序列化，直接使用原始数据
 	if isByteRequest {
 		return reflect.ValueOf(data), nil
@@ -354,7 +365,7 @@ func (r *Router) createRequestValue(requestType reflect.Type, isByteRequest bool
 	// 其他类型（指针类型）需要反序列化
 	requestValue := reflect.New(requestType.Elem())
 
-	if err := ctx.Node().Unmarshal(data, requestValue.Interface()); err != nil {
+	if err := ctx.Node().UnmarshalWith(ctx.ContentType(), data, requestValue.Interface()); err != nil {
 		return reflect.Value{}, xerror.Wrapf(err, "反序列化请求参数失败 (type=%v)", requestType)
 	}
 	return requestValue, nil
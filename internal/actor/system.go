@@ -12,7 +12,6 @@ import (
 
 	"github.com/duke-git/lancet/v2/maputil"
 	"go.uber.org/zap"
-	"golang.org/x/exp/slices"
 )
 
 var (
@@ -39,6 +38,7 @@ type System struct {
 	nameDict     *maputil.ConcurrentMap[string, *iface.Pid]     // 名字到进程ID的映射
 	shuttingDown atomic.Bool
 	node         iface.INode
+	forwardTTL   time.Duration // 迁移后原 pid 保留转发的时长
 }
 
 func NewSystem(node iface.INode) *System {
@@ -47,6 +47,7 @@ func NewSystem(node iface.INode) *System {
 		uniqId:      atomic.Uint64{},
 		processDict: maputil.NewConcurrentMap[uint64, iface.IProcess](10),
 		nameDict:    maputil.NewConcurrentMap[string, *iface.Pid](10),
+		forwardTTL:  defaultConfig().MigrateForwardTTL,
 	}
 }
 
@@ -55,7 +56,7 @@ func NewSystem(node iface.INode) *System {
 // Spawn 创建新的 Actor 进程
 func (s *System) Spawn(actor iface.IActor, args ...interface{}) *iface.Pid {
 	node := s.node
-	pid := iface.NewPid(node.GetID(), s.uniqId.Add(1))
+	pid := s.NewPid()
 
 	ctx := &actorContext{
 		process: nil,
@@ -75,15 +76,20 @@ func (s *System) Spawn(actor iface.IActor, args ...interface{}) *iface.Pid {
 	s.Add(pid, process)
 
 	// 提交初始化任务，如果失败则记录日志但不影响进程创建
-	if err := s.SubmitTask(pid, func(ctx iface.IContext) error {
+	if err := s.SubmitTask(pid, iface.Task(func(ctx iface.IContext) error {
 		return ctx.Actor().OnInit(ctx, args)
-	}); err != nil {
+	})); err != nil {
 		glog.Error("提交Actor初始化任务失败", zap.Any("pid", pid), zap.Error(err))
 	}
 
 	return pid
 }
 
+// NewPid 分配本地进程 ID
+func (s *System) NewPid() *iface.Pid {
+	return iface.NewPid(s.node.GetID(), s.uniqId.Add(1))
+}
+
 // Add 注册进程到系统中
 func (s *System) Add(pid *iface.Pid, process iface.IProcess) {
 	s.processDict.Set(pid.GetServiceId(), process)
@@ -137,6 +143,16 @@ func (s *System) GetAllProcesses() []iface.IProcess {
 	return processes
 }
 
+// ProcessCount 系统中的进程数量
+func (s *System) ProcessCount() int {
+	count := 0
+	s.processDict.Range(func(_ uint64, _ iface.IProcess) bool {
+		count++
+		return true
+	})
+	return count
+}
+
 // ==================== 名字管理 ====================
 
 // Named 为进程注册名字
@@ -153,26 +169,39 @@ func (s *System) Named(name string, pid *iface.Pid) error {
 		return ErrNameAlreadyRegistered
 	}
 
-	pid.Name = name
-	s.nameDict.Set(name, pid)
-
-	if !pid.IsGlobalName() {
-		return nil
+	// 全局名字先在集群中占用成功，再注册到本地
+	if iface.IsGlobalName(name) {
+		if err := s.clusterNamed(name); err != nil {
+			return err
+		}
 	}
 
-	return s.clusterNamed(name)
+	pid.Name = name
+	s.nameDict.Set(name, pid)
+	return nil
 }
 
+// clusterNamed 在集群中占用全局名字，服务发现支持名字注册表时原子占用，并通过节点标签参与路由
 func (s *System) clusterNamed(name string) error {
 	cluster := s.node.Cluster()
 	if cluster == nil {
 		return ErrClusterIsNil
 	}
 
-	info := s.node.Info()
-	info.Tags = append(info.Tags, name)
+	registry, ok := cluster.Discovery().(discovery.INameRegistry)
+	if ok {
+		if owner, err := registry.Claim(name, s.node.GetID()); err != nil {
+			return xerror.Wrapf(err, "占用全局名字失败 (name=%s, owner=%d)", name, owner)
+		}
+	}
 
-	if err := cluster.UpdateMember(); err != nil {
+	if err := cluster.AddTag(name); err != nil {
+		// 标签未同步时其他节点无法路由到本节点，释放占用以免名字不可用
+		if ok {
+			if releaseErr := registry.Release(name, s.node.GetID()); releaseErr != nil {
+				glog.Error("释放全局名字失败", zap.String("name", name), zap.Error(releaseErr))
+			}
+		}
 		return err
 	}
 	return nil
@@ -197,7 +226,7 @@ func (s *System) Unname(pid *iface.Pid) error {
 		return nil
 	}
 
-	return s.clusterNamed(name)
+	return s.clusterUnname(name)
 }
 
 func (s *System) clusterUnname(name string) error {
@@ -205,22 +234,53 @@ func (s *System) clusterUnname(name string) error {
 	if cluster == nil {
 		return ErrClusterIsNil
 	}
-	info := s.node.Info()
-
-	info.Tags = slices.DeleteFunc(info.Tags, func(s string) bool {
-		return s == name
-	})
-
-	if err := cluster.UpdateMember(); err != nil {
+	if err := cluster.RemoveTag(name); err != nil {
 		return err
 	}
+
+	if registry, ok := cluster.Discovery().(discovery.INameRegistry); ok {
+		if err := registry.Release(name, s.node.GetID()); err != nil {
+			return xerror.Wrapf(err, "释放全局名字失败 (name=%s)", name)
+		}
+	}
 	return nil
 }
 
+// NameConflict 全局名字被其他节点占用时由集群调用，本节点放弃该名字
+// actor 实现了 iface.INameConflictHandler 时在其邮箱中回调
+func (s *System) NameConflict(name string, owner uint64) {
+	pid, ok := s.nameDict.Get(name)
+	if !ok {
+		return
+	}
+	glog.Warn("全局名字冲突，本节点放弃该名字", zap.String("name", name),
+		zap.Uint64("owner", owner), zap.Any("pid", pid))
+
+	s.nameDict.Delete(name)
+	if err := s.clusterUnname(name); err != nil {
+		glog.Error("全局名字冲突: 注销名字失败", zap.String("name", name), zap.Error(err))
+	}
+
+	err := s.SubmitTask(pid, iface.Task(func(ctx iface.IContext) error {
+		ctx.ID().Name = ""
+		if handler, ok := ctx.Actor().(iface.INameConflictHandler); ok {
+			return handler.OnNameConflict(ctx, name, owner)
+		}
+		return nil
+	}))
+	if err != nil {
+		glog.Error("全局名字冲突: 通知进程失败", zap.String("name", name), zap.Error(err))
+	}
+}
+
 // ==================== 消息发送 ====================
 
 func (s *System) isLocalMessage(message *iface.ActorMessage) bool {
-	return message.GetTo().GetNodeId() == s.node.GetID()
+	return s.isLocalPid(message.GetTo())
+}
+
+func (s *System) isLocalPid(pid *iface.Pid) bool {
+	return pid.GetNodeId() == s.node.GetID()
 }
 
 // Send 异步发送消息给 Actor
@@ -240,6 +300,10 @@ func (s *System) Send(message *iface.ActorMessage) error {
 
 // Call 同步调用 Actor，等待响应
 func (s *System) Call(message *iface.ActorMessage) ([]byte, error) {
+	// 未设置截止时间的调用使用默认超时
+	if !message.HasDeadline() {
+		message.SetTimeout(DefaultCallTimeout)
+	}
 	if s.isLocalMessage(message) {
 		return s.localCall(message)
 	}
@@ -256,7 +320,10 @@ func (s *System) Call(message *iface.ActorMessage) ([]byte, error) {
 
 // localCall 本地同步调用
 func (s *System) localCall(message *iface.ActorMessage) (data []byte, err error) {
-	timeout := lib.NowDelay(message.GetDeadline(), 0)
+	timeout := message.Remaining()
+	if timeout <= 0 {
+		return nil, iface.ErrDeadlineExceeded
+	}
 	waiter := lib.NewChanWaiter[[]byte](timeout)
 	message.SetResponse(func(bin []byte, e error) {
 		waiter.Done(bin, e)
@@ -276,21 +343,36 @@ func (s *System) localSend(message *iface.ActorMessage) error {
 
 // ==================== 任务提交 ====================
 
-// SubmitTask 提交异步任务到指定进程
-func (s *System) SubmitTask(to *iface.Pid, task iface.Task) error {
+// SubmitTask 提交异步任务到指定进程，远程进程只接受具名任务
+func (s *System) SubmitTask(to *iface.Pid, task iface.ITask) error {
+	if !s.isLocalPid(to) {
+		named, err := s.remoteTask(task)
+		if err != nil {
+			return err
+		}
+		return s.node.Cluster().PushTask(to, named)
+	}
 	msg := iface.NewTaskMessage(task)
 	return s.sendToProcess(to, msg)
 }
 
-// SubmitTaskAndWait 提交同步任务到指定进程，等待执行完成
-func (s *System) SubmitTaskAndWait(to *iface.Pid, task iface.Task, timeout time.Duration) (err error) {
+// SubmitTaskAndWait 提交同步任务到指定进程，等待执行完成，远程进程只接受具名任务
+func (s *System) SubmitTaskAndWait(to *iface.Pid, task iface.ITask, timeout time.Duration) (err error) {
+	if !s.isLocalPid(to) {
+		named, nErr := s.remoteTask(task)
+		if nErr != nil {
+			return nErr
+		}
+		return s.node.Cluster().PushTaskAndWait(to, timeout, named)
+	}
+
 	waiter := lib.NewChanWaiter[[]byte](timeout)
 
-	syncTask := func(ctx iface.IContext) error {
-		taskErr := task(ctx)
+	syncTask := iface.Task(func(ctx iface.IContext) error {
+		taskErr := task.Run(ctx)
 		waiter.Done(nil, taskErr)
 		return taskErr
-	}
+	})
 
 	msg := iface.NewTaskMessage(syncTask)
 	if err = s.sendToProcess(to, msg); err != nil {
@@ -302,6 +384,18 @@ func (s *System) SubmitTaskAndWait(to *iface.Pid, task iface.Task, timeout time.
 	return
 }
 
+// remoteTask 检查任务能否提交到远程进程
+func (s *System) remoteTask(task iface.ITask) (*iface.NamedTask, error) {
+	named, ok := task.(*iface.NamedTask)
+	if !ok {
+		return nil, xerror.Wrapf(iface.ErrTaskNotSerializable, "task=%T", task)
+	}
+	if s.node.Cluster() == nil {
+		return nil, ErrClusterIsNil
+	}
+	return named, nil
+}
+
 // ==================== 辅助方法 ====================
 
 // sendToProcess 发送消息到指定进程
@@ -320,9 +414,9 @@ func (s *System) sendToProcess(to *iface.Pid, msg iface.IMessage) error {
 }
 
 func (s *System) Select(name string, strategy discovery.RouteStrategy) *iface.Pid {
-	process := s.GetProcessByName(name)
-	if process != nil {
-		return process.Context().ID()
+	// 自定义进程不一定有 actor 上下文，直接使用名字表中的 pid
+	if pid, ok := s.nameDict.Get(name); ok && pid != nil {
+		return pid
 	}
 	cluster := s.node.Cluster()
 	nodeId := cluster.Select(name, strategy)
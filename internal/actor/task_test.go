@@ -0,0 +1,104 @@
+package actor_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+)
+
+type counterActor struct {
+	iface.Actor
+	total int
+}
+
+type addArgs struct {
+	Delta int `json:"delta"`
+}
+
+var errNegativeDelta = errors.New("delta 不能为负数")
+
+func init() {
+	// 任务在 actor 邮箱中执行，可以直接访问 actor 状态
+	_ = iface.RegisterTypedTask("test.add", func(ctx iface.IContext, args *addArgs) error {
+		if args.Delta < 0 {
+			return errNegativeDelta
+		}
+		ctx.Actor().(*counterActor).total += args.Delta
+		return nil
+	})
+}
+
+// total 在本地邮箱中读取计数，保证与任务串行
+func total(t *testing.T, system iface.ISystem, pid *iface.Pid) int {
+	t.Helper()
+	var value int
+	err := system.SubmitTaskAndWait(pid, iface.Task(func(ctx iface.IContext) error {
+		value = ctx.Actor().(*counterActor).total
+		return nil
+	}), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// TestRemoteNamedTask 测试具名任务提交到其它节点的进程并在其邮箱中执行
+func TestRemoteNamedTask(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+	counter := &counterActor{}
+	pid := n2.System().Spawn(counter)
+
+	if err := n1.System().SubmitTaskAndWait(pid, iface.NewNamedTask("test.add", &addArgs{Delta: 3}), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(t, n2.System(), pid); got != 3 {
+		t.Fatalf("want 3, got %d", got)
+	}
+
+	// 异步提交
+	if err := n1.System().SubmitTask(pid, iface.NewNamedTask("test.add", &addArgs{Delta: 4})); err != nil {
+		t.Fatal(err)
+	}
+	nodetest.Eventually(t, time.Second, func() bool {
+		return total(t, n2.System(), pid) == 7
+	}, "异步任务未执行")
+
+	// 本地执行同样经过序列化
+	if err := n2.System().SubmitTaskAndWait(pid, iface.NewNamedTask("test.add", &addArgs{Delta: 1}), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(t, n2.System(), pid); got != 8 {
+		t.Fatalf("want 8, got %d", got)
+	}
+}
+
+// TestRemoteTaskErrors 测试远程任务的错误返回
+func TestRemoteTaskErrors(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+	pid := n2.System().Spawn(&counterActor{})
+
+	err := n1.System().SubmitTaskAndWait(pid, iface.NewNamedTask("test.add", &addArgs{Delta: -1}), time.Second)
+	if err == nil || !strings.Contains(err.Error(), errNegativeDelta.Error()) {
+		t.Fatalf("want %v, got %v", errNegativeDelta, err)
+	}
+	err = n1.System().SubmitTaskAndWait(pid, iface.NewNamedTask("test.unknown", nil), time.Second)
+	if err == nil || !strings.Contains(err.Error(), iface.ErrTaskNotRegistered.Error()) {
+		t.Fatalf("want %v, got %v", iface.ErrTaskNotRegistered, err)
+	}
+	closure := iface.Task(func(ctx iface.IContext) error { return nil })
+	if err = n1.System().SubmitTask(pid, closure); !errors.Is(err, iface.ErrTaskNotSerializable) {
+		t.Fatalf("want ErrTaskNotSerializable, got %v", err)
+	}
+	if err = n1.Cluster().PushTask(pid, iface.NewNamedTask("", nil)); !errors.Is(err, iface.ErrTaskNameIsEmpty) {
+		t.Fatalf("want ErrTaskNameIsEmpty, got %v", err)
+	}
+	if got := total(t, n2.System(), pid); got != 0 {
+		t.Fatalf("want 0, got %d", got)
+	}
+}
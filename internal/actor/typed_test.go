@@ -0,0 +1,105 @@
+package actor_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/actor"
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+)
+
+type moveMsg struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// migratedGot 迁移后新实例收到的消息，新实例由工厂创建，无法沿用原实例的 channel
+var migratedGot = make(chan interface{}, 4)
+
+// typedActor 没有路由，OnMessage 记录收到的消息
+type typedActor struct {
+	iface.Actor
+	got chan interface{}
+}
+
+func (a *typedActor) OnMessage(ctx iface.IContext, msg interface{}) error {
+	a.got <- msg
+	return nil
+}
+
+func (a *typedActor) MarshalState() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+func (a *typedActor) UnmarshalState(data []byte) error {
+	return json.Unmarshal(data, a)
+}
+
+func init() {
+	_ = iface.RegisterType("test.move", (*moveMsg)(nil))
+	actor.RegisterMigratable(func() iface.IMigratable { return &typedActor{got: migratedGot} })
+}
+
+// sendTyped 在 from 的邮箱中向 to 发送已注册类型的消息
+func sendTyped(t *testing.T, system iface.ISystem, from, to *iface.Pid, request interface{}) {
+	t.Helper()
+	err := system.SubmitTaskAndWait(from, iface.Task(func(ctx iface.IContext) error {
+		return ctx.Send(to, "Move", request)
+	}), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receiveMove(t *testing.T, got chan interface{}) *moveMsg {
+	t.Helper()
+	select {
+	case msg := <-got:
+		move, ok := msg.(*moveMsg)
+		if !ok {
+			t.Fatalf("want *moveMsg, got %T", msg)
+		}
+		return move
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到消息")
+	}
+	return nil
+}
+
+// TestTypedDispatch 测试已注册类型的消息在本地直接投递原值，发往其它节点时按类型名解析
+func TestTypedDispatch(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, n2 := c.Start(1, "game"), c.Start(2, "game")
+	sender := n1.System().Spawn(&counterActor{})
+
+	local := &typedActor{got: make(chan interface{}, 1)}
+	request := &moveMsg{X: 1, Y: 2}
+	sendTyped(t, n1.System(), sender, n1.System().Spawn(local), request)
+	if move := receiveMove(t, local.got); move != request {
+		t.Fatalf("本地投递未使用原值: %+v", move)
+	}
+
+	remote := &typedActor{got: make(chan interface{}, 1)}
+	sendTyped(t, n1.System(), sender, n2.System().Spawn(remote), &moveMsg{X: 3, Y: 4})
+	if move := receiveMove(t, remote.got); *move != (moveMsg{X: 3, Y: 4}) {
+		t.Fatalf("want {3 4}, got %+v", move)
+	}
+}
+
+// TestTypedDispatchForwarded 测试迁移后发往原 pid 的本地原值经转发进程到达新节点时仍能解析
+func TestTypedDispatchForwarded(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1, _ := c.Start(1, "game"), c.Start(2, "game")
+	sender := n1.System().Spawn(&counterActor{})
+	oldPid := n1.System().Spawn(&typedActor{got: make(chan interface{}, 1)})
+
+	if _, err := n1.System().Migrate(oldPid, 2, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	sendTyped(t, n1.System(), sender, oldPid, &moveMsg{X: 5, Y: 6})
+	if move := receiveMove(t, migratedGot); *move != (moveMsg{X: 5, Y: 6}) {
+		t.Fatalf("want {5 6}, got %+v", move)
+	}
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrNoMemberWithTag = errors.New("没有节点带有该标签")
+	ErrNoSuccessReply  = errors.New("所有节点调用均失败")
+)
+
+// BroadcastCall 并发调用所有带有 tag 的节点，返回每个节点的结果
+// 超时未响应的节点结果为 iface.ErrDeadlineExceeded，其余节点的结果仍然可用
+// 开启 iface.WithFirstSuccess 时，只返回截至第一个成功结果时已收到的结果，全部失败时返回 ErrNoSuccessReply
+func (r *Cluster) BroadcastCall(tag, method string, request interface{}, timeout time.Duration, opts ...iface.BroadcastOption) ([]*iface.BroadcastReply, error) {
+	options := &iface.BroadcastOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	members := r.selectMembers(tag)
+	if len(members) == 0 {
+		return nil, ErrNoMemberWithTag
+	}
+
+	data, err := r.node.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// 缓冲大小等于节点数，提前返回时调用协程不会阻塞
+	ch := make(chan *iface.BroadcastReply, len(members))
+	for _, member := range members {
+		nodeId := member.GetID()
+		msg := iface.NewActorMessage(nil, &iface.Pid{NodeId: nodeId, Name: tag}, method, data)
+		msg.SetTimeout(timeout)
+		grs.Go(func(ctx context.Context) {
+			bin, callErr := r.node.System().Call(msg)
+			// 消息队列的超时错误与未响应的节点统一为 ErrDeadlineExceeded
+			if callErr != nil && msg.IsExpired() {
+				callErr = iface.ErrDeadlineExceeded
+			}
+			ch <- &iface.BroadcastReply{NodeId: nodeId, Data: bin, Err: callErr}
+		})
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	replies := make([]*iface.BroadcastReply, 0, len(members))
+	pending := make(map[uint64]struct{}, len(members))
+	for _, member := range members {
+		pending[member.GetID()] = struct{}{}
+	}
+	for len(pending) > 0 {
+		select {
+		case reply := <-ch:
+			delete(pending, reply.NodeId)
+			replies = append(replies, reply)
+			if options.FirstSuccess && reply.Err == nil {
+				return replies, nil
+			}
+		case <-timer.C:
+			for nodeId := range pending {
+				replies = append(replies, &iface.BroadcastReply{NodeId: nodeId, Err: iface.ErrDeadlineExceeded})
+			}
+			pending = nil
+		}
+	}
+
+	if options.FirstSuccess {
+		return replies, ErrNoSuccessReply
+	}
+	return replies, nil
+}
+
+// selectMembers 获取带有 tag 且版本兼容的所有节点，正在排空的节点不再接收新的路由和广播
+func (r *Cluster) selectMembers(tag string) []*discovery.Member {
+	var selected []*discovery.Member
+	for _, member := range r.dis.GetAll() {
+		if !slices.Contains(member.Tags, tag) || member.IsDraining() || !r.version.compatible(member) {
+			continue
+		}
+		selected = append(selected, member)
+	}
+	return selected
+}
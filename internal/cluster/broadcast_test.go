@@ -0,0 +1,134 @@
+package cluster_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/cluster"
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+
+	"golang.org/x/exp/slices"
+)
+
+type nodeReply struct {
+	NodeId uint64 `json:"nodeId"`
+}
+
+type queryActor struct {
+	iface.Actor
+	delay   time.Duration
+	handled atomic.Int32
+}
+
+func (a *queryActor) Query(ctx iface.IContext, request *nodeReply, response *nodeReply) error {
+	time.Sleep(a.delay)
+	response.NodeId = ctx.Node().GetID()
+	a.handled.Add(1)
+	return nil
+}
+
+// startQueryNode 启动带有 query 标签的节点，并注册同名的本地进程
+func startQueryNode(t *testing.T, c *nodetest.Cluster, id uint64, actor *queryActor) *node.Node {
+	t.Helper()
+	n := c.Start(id, "game")
+	if err := n.System().Named("query", n.System().Spawn(actor)); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Cluster().AddTag("query"); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func waitMembers(t *testing.T, n *node.Node, tag string, count int) {
+	t.Helper()
+	nodetest.Eventually(t, time.Second, func() bool {
+		found := 0
+		for _, member := range n.Cluster().Discovery().GetAll() {
+			if slices.Contains(member.GetTags(), tag) && !member.IsDraining() {
+				found++
+			}
+		}
+		return found == count
+	}, "带有标签 %s 的节点数不是 %d", tag, count)
+}
+
+// TestBroadcastCall 测试向所有节点并发调用、超时节点的结果和第一个成功结果
+func TestBroadcastCall(t *testing.T) {
+	c := nodetest.New(t, "")
+	slow := &queryActor{delay: time.Second}
+	n1 := startQueryNode(t, c, 1, &queryActor{})
+	startQueryNode(t, c, 2, &queryActor{})
+	startQueryNode(t, c, 3, slow)
+	waitMembers(t, n1, "query", 3)
+
+	start := time.Now()
+	replies, err := n1.Cluster().BroadcastCall("query", "Query", &nodeReply{}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("等待了 %v", elapsed)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("want 3 replies, got %d", len(replies))
+	}
+	for _, reply := range replies {
+		if reply.NodeId == 3 {
+			if !errors.Is(reply.Err, iface.ErrDeadlineExceeded) {
+				t.Fatalf("want ErrDeadlineExceeded, got %v", reply.Err)
+			}
+			continue
+		}
+		result := &nodeReply{}
+		if reply.Err != nil {
+			t.Fatal(reply.Err)
+		}
+		if err = n1.Unmarshal(reply.Data, result); err != nil || result.NodeId != reply.NodeId {
+			t.Fatalf("node %d replied %+v (err=%v)", reply.NodeId, result, err)
+		}
+	}
+
+	replies, err = n1.Cluster().BroadcastCall("query", "Query", &nodeReply{}, time.Second, iface.WithFirstSuccess())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := replies[len(replies)-1]; last.Err != nil || last.NodeId == 3 {
+		t.Fatalf("want first success, got %+v", last)
+	}
+
+	if _, err = n1.Cluster().BroadcastCall("nobody", "Query", nil, time.Second); !errors.Is(err, cluster.ErrNoMemberWithTag) {
+		t.Fatalf("want ErrNoMemberWithTag, got %v", err)
+	}
+	// 等待慢节点处理完已放弃的调用再停止节点
+	nodetest.Eventually(t, 3*time.Second, func() bool { return slow.handled.Load() == 2 }, "慢节点未处理完调用")
+}
+
+// TestBroadcastCallSkipDraining 测试正在排空的节点不参与广播调用
+func TestBroadcastCallSkipDraining(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1 := startQueryNode(t, c, 1, &queryActor{})
+	n2 := startQueryNode(t, c, 2, &queryActor{})
+	waitMembers(t, n1, "query", 2)
+
+	if err := n2.Cluster().SetMeta(map[string]string{discovery.MetaDraining: "true"}); err != nil {
+		t.Fatal(err)
+	}
+	waitMembers(t, n1, "query", 1)
+
+	replies, err := n1.Cluster().BroadcastCall("query", "Query", &nodeReply{}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0].NodeId != 1 {
+		t.Fatalf("want only node 1, got %+v", replies)
+	}
+	if nodeId := n1.Cluster().Select("query", nil); nodeId != 1 {
+		t.Fatalf("want node 1, got %d", nodeId)
+	}
+}
@@ -8,8 +8,12 @@ import (
 	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
 	"github.com/dzm2020/gas/pkg/glog"
 	"github.com/dzm2020/gas/pkg/lib"
+	"github.com/dzm2020/gas/pkg/lib/event"
+	"github.com/dzm2020/gas/pkg/lib/secure"
 	"github.com/dzm2020/gas/pkg/lib/xerror"
 	messageQue "github.com/dzm2020/gas/pkg/messageQue/iface"
+	"github.com/dzm2020/gas/pkg/trace"
+	"sync"
 	"time"
 
 	"github.com/duke-git/lancet/v2/convertor"
@@ -18,10 +22,13 @@ import (
 )
 
 var (
-	ErrMethodNotImplemented = errors.New("集群推送任务尚未实现")
-	ErrNotFoundMember       = errors.New("未找到成员节点")
+	ErrNotFoundMember = errors.New("未找到成员节点")
 )
 
+// envelopeSerializer 集群消息信封固定使用 protobuf，与节点的默认序列化器无关，
+// 信封中的 data 按 contentType 指定的格式解析
+var envelopeSerializer = lib.PB
+
 var _ iface.ICluster = (*Cluster)(nil)
 
 type Cluster struct {
@@ -29,14 +36,59 @@ type Cluster struct {
 	node iface.INode
 	dis  discovery.IDiscovery
 	mq   messageQue.IMessageQue
+
+	nameCheckInterval time.Duration
+	loadConf          *LoadConfig
+	version           *versionRouter
+	compressor        *compressor
+	sealer            *secure.Sealer
+	raw               discovery.IDiscovery // 未按命名空间过滤的服务发现
+	namespace         string
+	allowNamespaces   []string
+	metaMu            sync.Mutex // 保护本节点信息中的标签和 Meta
+	cancel            context.CancelFunc
+
+	topology  event.Listener[*discovery.Topology]
+	watchId   uint64   // 监听服务发现的句柄
+	watchers  sync.Map // serviceId -> *iface.Pid，订阅拓扑变化的本地进程
+	pendingMu sync.Mutex
+	pending   map[uint64]map[*pendingCall]struct{} // nodeId -> 等待回复的调用
 }
 
-func (r *Cluster) PushTask(pid *iface.Pid, f iface.Task) error {
-	return ErrMethodNotImplemented
+// PushTask 将具名任务推送到远程进程的邮箱中异步执行
+func (r *Cluster) PushTask(pid *iface.Pid, task *iface.NamedTask) error {
+	msg, err := r.newTaskMessage(pid, task)
+	if err != nil {
+		return err
+	}
+	msg.Async = true
+	return r.Send(msg)
 }
 
-func (r *Cluster) PushTaskAndWait(pid *iface.Pid, timeout time.Duration, task iface.Task) error {
-	return ErrMethodNotImplemented
+// PushTaskAndWait 将具名任务推送到远程进程的邮箱中执行，并等待执行结果
+func (r *Cluster) PushTaskAndWait(pid *iface.Pid, timeout time.Duration, task *iface.NamedTask) error {
+	msg, err := r.newTaskMessage(pid, task)
+	if err != nil {
+		return err
+	}
+	msg.SetTimeout(timeout)
+	_, err = r.Call(msg)
+	return err
+}
+
+func (r *Cluster) newTaskMessage(pid *iface.Pid, task *iface.NamedTask) (*iface.ActorMessage, error) {
+	if task == nil || task.Name == "" {
+		return nil, iface.ErrTaskNameIsEmpty
+	}
+	args, err := task.MarshalArgs(r.node)
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.node.Marshal(&iface.TaskRequest{Name: task.Name, Args: args})
+	if err != nil {
+		return nil, err
+	}
+	return iface.NewActorMessage(nil, pid, iface.TaskMethod, data), nil
 }
 
 func (r *Cluster) Start(ctx context.Context) error {
@@ -48,24 +100,34 @@ func (r *Cluster) Start(ctx context.Context) error {
 	if err := r.dis.Run(ctx); err != nil {
 		return err
 	}
+	// 监听所有类型节点的变化
+	r.watchId = r.dis.Watch(discovery.AllKinds, r.onTopology)
 	// 注册节点到服务发现
-	if err := r.dis.Register(r.node.Info()); err != nil {
+	if err := r.dis.Register(r.member()); err != nil {
 		return err
 	}
 	// 订阅消息队列
 	if err := r.subscribe(); err != nil {
 		return err
 	}
+	var bgCtx context.Context
+	bgCtx, r.cancel = context.WithCancel(context.Background())
+	r.startNameChecker(bgCtx)
+	r.startLoadReporter(bgCtx)
 	return nil
 }
 
-func (r *Cluster) makeSubject(nodeId uint64) string {
-	return fmt.Sprintf("%s.%d", r.name, nodeId)
+// makeSubject 节点的消息队列主题，非默认命名空间的主题以命名空间为前缀
+func (r *Cluster) makeSubject(namespace string, nodeId uint64) string {
+	if namespace == "" {
+		return fmt.Sprintf("%s.%d", r.name, nodeId)
+	}
+	return fmt.Sprintf("%s.%s.%d", namespace, r.name, nodeId)
 }
 
 func (r *Cluster) localSubject() string {
 	nodeId := r.node.GetID()
-	return r.makeSubject(nodeId)
+	return r.makeSubject(r.namespace, nodeId)
 }
 
 func (r *Cluster) subscribe() (err error) {
@@ -73,19 +135,47 @@ func (r *Cluster) subscribe() (err error) {
 	return
 }
 
-func (r *Cluster) OnMessage(data []byte, response func(data []byte) error) {
+// onConnEvent 将消息队列的连接事件转为节点事件
+func (r *Cluster) onConnEvent(event *messageQue.ConnEvent) {
+	var typ iface.NodeEventType
+	switch event.Type {
+	case messageQue.ConnDisconnected:
+		typ = iface.NodeEventMQDisconnected
+	case messageQue.ConnReconnected:
+		typ = iface.NodeEventMQReconnected
+	case messageQue.ConnClosed:
+		typ = iface.NodeEventMQClosed
+	default:
+		return
+	}
+	r.node.Events().Notify(&iface.NodeEvent{Type: typ, Data: event})
+}
+
+// OnMessage 处理消息队列收到的消息，返回处理失败的原因，持久化投递的消息队列据此重新投递
+func (r *Cluster) OnMessage(data []byte, response func(data []byte) error) (err error) {
 	message := &iface.Message{}
-	var err error
+	var span *trace.Span
 	defer func() {
+		span.End(err)
 		if err != nil {
 			glog.Error("集群：处理消息失败", zap.Error(err), zap.Any("message", message))
 		}
 	}()
-	if err = r.node.Unmarshal(data, message); err != nil {
+	if data, err = r.open(data); err != nil {
+		return
+	}
+	if err = envelopeSerializer.Unmarshal(data, message); err != nil {
 		return
 	}
 	msg := &iface.ActorMessage{Message: message}
 
+	if span = trace.Start("cluster.receive", message.GetTraceParent()); span != nil {
+		if span.IsRecording() {
+			span.SetAttr("method", message.GetMethod()).SetAttr("from", message.GetFrom().String())
+		}
+		message.TraceParent = span.TraceParent()
+	}
+
 	glog.Debug("集群：处理消息", zap.Any("message", message))
 
 	system := r.node.System()
@@ -96,13 +186,17 @@ func (r *Cluster) OnMessage(data []byte, response func(data []byte) error) {
 		responseData, responseErr := system.Call(msg)
 		//  打包结果
 		responseMessage := iface.NewResponse(responseData, responseErr)
-		responseData, err = r.node.Marshal(responseMessage)
+		responseData, err = envelopeSerializer.Marshal(responseMessage)
 		if err != nil {
 			return
 		}
+		if responseData, err = r.seal(responseData); err != nil {
+			return
+		}
 		//  写入到消息队列
 		err = response(responseData)
 	}
+	return
 }
 
 // Send 发送消息到集群节点
@@ -113,16 +207,28 @@ func (r *Cluster) Send(msg *iface.ActorMessage) (err error) {
 
 	toNodeId := msg.To.GetNodeId()
 
-	if m := r.dis.GetById(toNodeId); m == nil {
+	m := r.lookupMember(toNodeId)
+	if m == nil {
 		return xerror.Wrapf(ErrNotFoundMember, "nodeId=%d", toNodeId)
 	}
+	if err = r.checkCompatible(m); err != nil {
+		return err
+	}
 
-	bytes, mErr := r.node.Marshal(msg)
+	r.stampContentType(msg)
+	if err = msg.EncodeValue(r.node); err != nil {
+		return err
+	}
+	bytes, mErr := envelopeSerializer.Marshal(msg.Message)
 	if mErr != nil {
 		return mErr
 	}
 
-	subject := r.makeSubject(toNodeId)
+	if bytes, err = r.seal(bytes); err != nil {
+		return err
+	}
+
+	subject := r.makeSubject(m.Namespace(), toNodeId)
 	if err = r.mq.Publish(subject, bytes); err != nil {
 		return xerror.Wrapf(err, "发布消息到队列失败 (subject=%s)", subject)
 	}
@@ -136,26 +242,51 @@ func (r *Cluster) Call(msg *iface.ActorMessage) (bin []byte, err error) {
 
 	toNodeId := msg.To.GetNodeId()
 
-	if m := r.dis.GetById(toNodeId); m == nil {
+	m := r.lookupMember(toNodeId)
+	if m == nil {
 		err = xerror.Wrapf(ErrNotFoundMember, "nodeId=%d", toNodeId)
 		return
 	}
+	if err = r.checkCompatible(m); err != nil {
+		return
+	}
+
+	timeout := msg.Remaining()
+	if timeout <= 0 {
+		err = iface.ErrDeadlineExceeded
+		return
+	}
 
-	data, marshalErr := r.node.Marshal(msg.Message)
+	r.stampContentType(msg)
+	if err = msg.EncodeValue(r.node); err != nil {
+		return
+	}
+	data, marshalErr := envelopeSerializer.Marshal(msg.Message)
 	if marshalErr != nil {
 		return nil, marshalErr
 	}
+	if data, err = r.seal(data); err != nil {
+		return
+	}
 
-	subject := r.makeSubject(toNodeId)
-	timeout := lib.NowDelay(msg.GetDeadline(), 0)
-	bytes, requestErr := r.mq.Request(subject, data, timeout)
+	subject := r.makeSubject(m.Namespace(), toNodeId)
+	ctx, cancel := r.trackCall(toNodeId, timeout)
+	defer cancel()
+	bytes, requestErr := r.mq.RequestWithContext(ctx, subject, data)
 	if requestErr != nil {
+		if errors.Is(context.Cause(ctx), ErrMemberLeft) {
+			err = xerror.Wrapf(ErrMemberLeft, "nodeId=%d", toNodeId)
+			return
+		}
 		err = xerror.Wrapf(requestErr, "请求消息队列失败 (subject=%s, timeout=%v)", subject, timeout)
 		return
 	}
 
+	if bytes, err = r.open(bytes); err != nil {
+		return nil, err
+	}
 	response := &iface.Response{}
-	if err = r.node.Unmarshal(bytes, response); err != nil {
+	if err = envelopeSerializer.Unmarshal(bytes, response); err != nil {
 		return nil, err
 	}
 
@@ -165,30 +296,78 @@ func (r *Cluster) Call(msg *iface.ActorMessage) (bin []byte, err error) {
 	return bin, err
 }
 
+// stampContentType 未指定格式的消息由本节点的默认序列化器生成，发往其它节点前写明格式
+func (r *Cluster) stampContentType(msg *iface.ActorMessage) {
+	if msg.ContentType == 0 {
+		msg.ContentType = r.node.ContentType()
+	}
+}
+
+// Discovery 获取服务发现实例
+func (r *Cluster) Discovery() discovery.IDiscovery {
+	return r.dis
+}
+
+// UpdateMember 将本节点信息（标签、Meta）的变化同步到服务发现
 func (r *Cluster) UpdateMember() error {
-	return r.dis.Register(r.node.Info())
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	return r.dis.Update(r.node.Info().Clone())
 }
 
-func (r *Cluster) Select(tag string, strategy discovery.RouteStrategy) uint64 {
-	if strategy == nil {
-		strategy = discovery.RouteRandom
+// member 发布到服务发现的本节点信息副本，服务发现可能在其它协程中读取，不能与本节点的修改共享 Tags 和 Meta
+func (r *Cluster) member() *discovery.Member {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	return r.node.Info().Clone()
+}
+
+// AddTag 给本节点添加标签并同步到服务发现，同步失败时撤销
+// 标签和 Meta 修改时替换为新的切片和 map，不修改其它协程可能正在读取的旧值
+func (r *Cluster) AddTag(tag string) error {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	info := r.node.Info()
+	if slices.Contains(info.Tags, tag) {
+		return nil
 	}
-	// 通过服务发现获取节点列表
-	members := r.dis.GetAll()
-	if len(members) == 0 {
-		return 0
+	tags := info.Tags
+	info.Tags = append(slices.Clone(tags), tag)
+	if err := r.dis.Update(info.Clone()); err != nil {
+		info.Tags = tags
+		return err
 	}
+	return nil
+}
 
-	var selected []*discovery.Member
-	for _, node := range members {
-		if !slices.Contains(node.Tags, tag) {
-			continue
-		}
-		selected = append(selected, node)
-	}
+// RemoveTag 移除本节点的标签并同步到服务发现
+func (r *Cluster) RemoveTag(tag string) error {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	info := r.node.Info()
+	info.Tags = slices.DeleteFunc(slices.Clone(info.Tags), func(s string) bool { return s == tag })
+	return r.dis.Update(info.Clone())
+}
+
+// tags 本节点标签的副本
+func (r *Cluster) tags() []string {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	return slices.Clone(r.node.Info().Tags)
+}
+
+// Select 按路由策略从带有 tag 的节点中选择一个，跳过排空中和版本不兼容的节点，并按灰度比例分流
+func (r *Cluster) Select(tag string, strategy discovery.RouteStrategy) uint64 {
+	return r.SelectByKey(tag, "", strategy)
+}
 
+// SelectByKey 与 Select 相同，key 用于灰度分流，配置的 key 以及按哈希落入灰度比例的 key 固定路由到灰度节点
+func (r *Cluster) SelectByKey(tag, key string, strategy discovery.RouteStrategy) uint64 {
+	if strategy == nil {
+		strategy = discovery.RouteRandom
+	}
 	// 使用路由策略选择节点
-	selectedNode := strategy(selected)
+	selectedNode := strategy(r.version.split(r.selectMembers(tag), key))
 	if selectedNode == nil {
 		return 0
 	}
@@ -198,14 +377,7 @@ func (r *Cluster) Select(tag string, strategy discovery.RouteStrategy) uint64 {
 
 // Broadcast 向服务的所有节点广播消息
 func (r *Cluster) Broadcast(tag string, message *iface.ActorMessage) {
-	members := r.dis.GetAll()
-	if len(members) == 0 {
-		return
-	}
-	for _, member := range members {
-		if !slices.Contains(member.Tags, tag) {
-			continue
-		}
+	for _, member := range r.selectMembers(tag) {
 		msg := convertor.DeepClone(message)
 		msg.To = &iface.Pid{
 			NodeId: member.GetID(),
@@ -223,6 +395,10 @@ func (r *Cluster) Broadcast(tag string, message *iface.ActorMessage) {
 
 // Shutdown 关闭所有订阅
 func (r *Cluster) Shutdown(ctx context.Context) error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.dis.Unwatch(discovery.AllKinds, r.watchId)
 	if err := r.dis.Shutdown(ctx); err != nil {
 		return err
 	}
@@ -2,11 +2,15 @@ package cluster
 
 import (
 	"context"
+	"time"
+
 	"github.com/dzm2020/gas/internal/iface"
 	"github.com/dzm2020/gas/internal/profile"
 	dis "github.com/dzm2020/gas/pkg/discovery"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
 	"github.com/dzm2020/gas/pkg/lib/component"
 	mq "github.com/dzm2020/gas/pkg/messageQue"
+	mqIface "github.com/dzm2020/gas/pkg/messageQue/iface"
 )
 
 const (
@@ -17,6 +21,21 @@ type Config struct {
 	Name         string      `json:"name" yaml:"name"`
 	Discovery    *dis.Config `json:"discovery" yaml:"discovery"`
 	MessageQueue *mq.Config  `json:"messageQueue" yaml:"messageQueue"`
+	// Namespace 命名空间，多个集群共用 Consul 和 NATS 时相互隔离，
+	// 作用于服务发现（Meta 过滤）、消息队列主题前缀和全局名字
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// AllowNamespaces 允许通过 pid 直接访问的其它命名空间，路由和选举不会跨命名空间
+	AllowNamespaces []string `json:"allowNamespaces" yaml:"allowNamespaces"`
+	// NameCheckInterval 检查全局名字冲突的间隔
+	NameCheckInterval time.Duration `json:"nameCheckInterval" yaml:"nameCheckInterval"`
+	// Load 节点负载发布配置
+	Load *LoadConfig `json:"load" yaml:"load"`
+	// Version 版本感知路由配置
+	Version *VersionConfig `json:"version" yaml:"version"`
+	// Compression 传输压缩配置
+	Compression *CompressionConfig `json:"compression" yaml:"compression"`
+	// Security 消息签名与加密配置
+	Security *SecurityConfig `json:"security" yaml:"security"`
 }
 
 func defaultConfig() *Config {
@@ -31,6 +50,11 @@ func defaultConfig() *Config {
 			Type:   "nats",
 			Config: nil,
 		},
+		NameCheckInterval: 5 * time.Second,
+		Load:              defaultLoadConfig(),
+		Version:           defaultVersionConfig(),
+		Compression:       defaultCompressionConfig(),
+		Security:          defaultSecurityConfig(),
 	}
 }
 
@@ -59,16 +83,55 @@ func (r *Component) Start(ctx context.Context, node iface.INode) (err error) {
 	}
 
 	r.name = conf.Name
+	r.namespace = conf.Namespace
+	r.allowNamespaces = conf.AllowNamespaces
+	r.nameCheckInterval = conf.NameCheckInterval
+	r.loadConf = conf.Load
+	if r.version, err = newVersionRouter(conf.Version); err != nil {
+		return
+	}
+	if r.compressor, err = newCompressor(conf.Compression); err != nil {
+		return
+	}
+	if r.sealer, err = newSealer(conf.Security); err != nil {
+		return
+	}
+	info := node.Info()
+	if info.Meta == nil {
+		info.Meta = make(map[string]string)
+	}
+	if conf.Version.Version != "" {
+		info.Meta[discovery.MetaVersion] = conf.Version.Version
+	}
+	if r.namespace != "" {
+		info.Meta[discovery.MetaNamespace] = r.namespace
+	}
 	// 创建服务发现实例
-	r.dis, err = dis.NewFromConfig(*conf.Discovery)
+	r.raw, err = dis.NewFromConfig(*conf.Discovery)
 	if err != nil {
 		return
 	}
+	if namespaced, ok := r.raw.(discovery.INamespaced); ok {
+		namespaced.SetNamespace(r.namespace)
+	}
+	r.dis = newNamespaceDiscovery(r.raw, r.namespace)
 	// 创建集群通信管理器
 	r.mq, err = mq.NewFromConfig(*conf.MessageQueue)
 	if err != nil {
 		return
 	}
+	// 持久化投递的消息可能在时间窗口之后到达或重复投递，无法通过签名的重放检测
+	if durable, ok := r.mq.(mqIface.IDurable); ok && durable.Durable() && r.sealer != nil {
+		return ErrDurableWithSecurity
+	}
+	// 节点直连的消息队列按服务发现中的地址发送
+	if transport, ok := r.mq.(mqIface.IPeerTransport); ok {
+		transport.SetResolver(r.Cluster)
+	}
+	// 消息队列的连接状态变化作为节点事件发出
+	if source, ok := r.mq.(mqIface.IConnEventSource); ok {
+		source.SetConnEventHandler(r.onConnEvent)
+	}
 
 	//  建立引用
 	node.SetCluster(r.Cluster)
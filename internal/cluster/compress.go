@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/compress"
+
+	"go.uber.org/zap"
+)
+
+// CompressionConfig 集群传输压缩配置
+// 接收方根据压缩帧中的算法标记解压，与发送方的配置无关；默认不压缩，
+// 旧版本节点无法解压压缩帧，滚动升级时需要所有节点都升级后再开启
+type CompressionConfig struct {
+	Algorithm string `json:"algorithm" yaml:"algorithm"` // 压缩算法：zstd、snappy，为空或 none 时不压缩
+	Threshold int    `json:"threshold" yaml:"threshold"` // 超过该字节数的消息才压缩
+	MaxSize   int    `json:"maxSize" yaml:"maxSize"`     // 解压后的最大字节数，超过时丢弃消息，默认 64MB（compress.DefaultMaxSize）
+}
+
+func defaultCompressionConfig() *CompressionConfig {
+	return &CompressionConfig{
+		Algorithm: "none",
+		Threshold: 4096,
+		MaxSize:   compress.DefaultMaxSize,
+	}
+}
+
+// compressor 按阈值压缩发往集群的消息
+type compressor struct {
+	algorithm compress.Algorithm
+	threshold int
+	maxSize   int
+}
+
+func newCompressor(conf *CompressionConfig) (*compressor, error) {
+	algorithm, err := compress.Parse(conf.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &compressor{algorithm: algorithm, threshold: conf.Threshold, maxSize: conf.MaxSize}, nil
+}
+
+// encode 超过阈值时压缩，压缩失败或没有收益时发送原始数据
+func (c *compressor) encode(data []byte) []byte {
+	if c == nil || c.algorithm == compress.None || len(data) <= c.threshold {
+		return data
+	}
+	frame, err := compress.Encode(c.algorithm, data)
+	if err != nil {
+		glog.Warn("集群: 压缩消息失败，发送原始数据", zap.Int("size", len(data)), zap.Error(err))
+		return data
+	}
+	if len(frame) >= len(data) {
+		return data
+	}
+	return frame
+}
+
+// decode 解压收到的数据，未压缩的数据原样返回
+func (c *compressor) decode(data []byte) ([]byte, error) {
+	if c == nil {
+		return compress.Decode(data, 0)
+	}
+	return compress.Decode(data, c.maxSize)
+}
@@ -0,0 +1,23 @@
+package cluster
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dzm2020/gas/pkg/lib/compress"
+)
+
+// TestDefaultCompression 测试默认配置不压缩，旧版本节点可以解析发出的消息
+func TestDefaultCompression(t *testing.T) {
+	c, err := newCompressor(defaultCompressionConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("gas"), 10000)
+	if out := c.encode(data); !bytes.Equal(out, data) {
+		t.Fatal("默认配置压缩了消息")
+	}
+	if c.maxSize != compress.DefaultMaxSize {
+		t.Fatalf("want max size %d, got %d", compress.DefaultMaxSize, c.maxSize)
+	}
+}
@@ -0,0 +1,167 @@
+package cluster
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"time"
+
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/network"
+
+	"go.uber.org/zap"
+)
+
+// LoadConfig 节点负载发布配置
+type LoadConfig struct {
+	Interval   time.Duration `json:"interval" yaml:"interval"`     // 采集间隔，0 表示不发布负载
+	Threshold  float64       `json:"threshold" yaml:"threshold"`   // 任一指标变化比例超过该值时才发布
+	MaxSilence time.Duration `json:"maxSilence" yaml:"maxSilence"` // 超过该时长未发布时强制发布一次
+	Weight     float64       `json:"weight" yaml:"weight"`         // 静态权重，大于 0 时写入 Meta
+}
+
+func defaultLoadConfig() *LoadConfig {
+	return &LoadConfig{
+		Interval:   5 * time.Second,
+		Threshold:  0.1,
+		MaxSilence: time.Minute,
+	}
+}
+
+// loadReporter 定期采集节点负载并写入 Member.Meta
+// 只有指标变化超过阈值或长时间未发布时才更新服务发现，避免频繁写入 Consul
+type loadReporter struct {
+	cluster     *Cluster
+	conf        *LoadConfig
+	cpu         *cpuSampler
+	last        map[string]float64
+	lastPublish time.Time
+}
+
+func (r *Cluster) startLoadReporter(ctx context.Context) {
+	conf := r.loadConf
+	if conf == nil || conf.Interval <= 0 {
+		return
+	}
+	if conf.Weight > 0 {
+		r.SetMeta(map[string]string{discovery.MetaWeight: strconv.FormatFloat(conf.Weight, 'f', -1, 64)})
+	}
+	reporter := &loadReporter{
+		cluster: r,
+		conf:    conf,
+		cpu:     newCPUSampler(),
+	}
+	grs.Go(func(context.Context) {
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reporter.report()
+			}
+		}
+	})
+}
+
+func (l *loadReporter) sample() map[string]float64 {
+	values := map[string]float64{
+		discovery.MetaConns:      float64(network.ConnectionCount()),
+		discovery.MetaGoroutines: float64(runtime.NumGoroutine()),
+		discovery.MetaCPU:        math.Round(l.cpu.Percent()),
+	}
+	if system := l.cluster.node.System(); system != nil {
+		values[discovery.MetaActors] = float64(system.ProcessCount())
+	}
+	return values
+}
+
+func (l *loadReporter) report() {
+	values := l.sample()
+	if !l.changed(values) && time.Since(l.lastPublish) < l.conf.MaxSilence {
+		return
+	}
+	meta := make(map[string]string, len(values))
+	for key, value := range values {
+		meta[key] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	if err := l.cluster.SetMeta(meta); err != nil {
+		glog.Warn("集群: 发布节点负载失败", zap.Error(err))
+		return
+	}
+	l.last = values
+	l.lastPublish = time.Now()
+}
+
+// changed 任一指标相对上次发布的变化比例是否超过阈值
+func (l *loadReporter) changed(values map[string]float64) bool {
+	if l.last == nil {
+		return true
+	}
+	for key, value := range values {
+		last := l.last[key]
+		if math.Abs(value-last)/math.Max(math.Abs(last), 1) > l.conf.Threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMeta 更新本节点的 Meta 并同步到服务发现
+func (r *Cluster) SetMeta(meta map[string]string) error {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	info := r.node.Info()
+	next := make(map[string]string, len(info.Meta)+len(meta))
+	for key, value := range info.Meta {
+		next[key] = value
+	}
+	for key, value := range meta {
+		next[key] = value
+	}
+	info.Meta = next
+	return r.dis.Update(info.Clone())
+}
+
+// cpuSampler 基于 runtime/metrics 估算进程 CPU 使用率
+// 统计的是 Go 运行时可用 CPU 时间（GOMAXPROCS）中非空闲的比例
+type cpuSampler struct {
+	samples   []metrics.Sample
+	lastTotal float64
+	lastIdle  float64
+}
+
+func newCPUSampler() *cpuSampler {
+	c := &cpuSampler{
+		samples: []metrics.Sample{
+			{Name: "/cpu/classes/total:cpu-seconds"},
+			{Name: "/cpu/classes/idle:cpu-seconds"},
+		},
+	}
+	c.lastTotal, c.lastIdle = c.read()
+	return c
+}
+
+func (c *cpuSampler) read() (total, idle float64) {
+	metrics.Read(c.samples)
+	if c.samples[0].Value.Kind() != metrics.KindFloat64 || c.samples[1].Value.Kind() != metrics.KindFloat64 {
+		return 0, 0
+	}
+	return c.samples[0].Value.Float64(), c.samples[1].Value.Float64()
+}
+
+// Percent 自上次调用以来的 CPU 使用率 [0,100]
+func (c *cpuSampler) Percent() float64 {
+	total, idle := c.read()
+	deltaTotal, deltaIdle := total-c.lastTotal, idle-c.lastIdle
+	c.lastTotal, c.lastIdle = total, idle
+	if deltaTotal <= 0 {
+		return 0
+	}
+	return math.Min(math.Max((1-deltaIdle/deltaTotal)*100, 0), 100)
+}
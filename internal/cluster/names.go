@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+
+	"golang.org/x/exp/slices"
+)
+
+// startNameChecker 定期检查本节点持有的全局名字是否与其他节点冲突
+func (r *Cluster) startNameChecker(ctx context.Context) {
+	if r.nameCheckInterval <= 0 {
+		return
+	}
+	grs.Go(func(context.Context) {
+		ticker := time.NewTicker(r.nameCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.checkNames()
+			}
+		}
+	})
+}
+
+func (r *Cluster) checkNames() {
+	system := r.node.System()
+	if system == nil {
+		return
+	}
+	self := r.node.GetID()
+	for _, name := range r.tags() {
+		if !iface.IsGlobalName(name) || !system.HasName(name) {
+			continue
+		}
+		if owner := r.nameOwner(name); owner != 0 && owner != self {
+			system.NameConflict(name, owner)
+		}
+	}
+}
+
+// nameOwner 名字在集群中的占用者
+// 服务发现支持名字注册表时以注册表为准，占用已失效（例如健康检查曾经失败）时尝试重新占用；
+// 否则通过节点标签判断，多个节点持有同一名字时 ID 最小的节点获胜
+func (r *Cluster) nameOwner(name string) uint64 {
+	self := r.node.GetID()
+	if registry, ok := r.dis.(discovery.INameRegistry); ok {
+		if owner, found := registry.Lookup(name); found {
+			return owner
+		}
+		owner, _ := registry.Claim(name, self)
+		return owner
+	}
+	owner := self
+	for id, member := range r.dis.GetAll() {
+		if id < owner && slices.Contains(member.Tags, name) {
+			owner = id
+		}
+	}
+	return owner
+}
@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"sync"
+
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/event"
+
+	"golang.org/x/exp/slices"
+)
+
+// namespaceDiscovery 命名空间隔离的服务发现视图
+// 多个集群共用同一个 Consul 时，只暴露 Meta 中命名空间与本节点相同的节点，
+// 单例、分片、路由、拓扑事件等基于服务发现的功能因此天然限定在本命名空间内
+type namespaceDiscovery struct {
+	discovery.IDiscovery
+	namespace string
+
+	mu      sync.Mutex
+	watches map[string]*namespaceWatch
+}
+
+// namespaceRegistry 底层服务发现支持名字注册表时，全局名字按命名空间隔离
+type namespaceRegistry struct {
+	*namespaceDiscovery
+	registry discovery.INameRegistry
+}
+
+// namespaceWatch 每个 kind 只向底层注册一次，过滤后再分发给本视图的监听者
+type namespaceWatch struct {
+	view     *namespaceDiscovery
+	listener *event.Listener[*discovery.Topology]
+}
+
+func newNamespaceDiscovery(inner discovery.IDiscovery, namespace string) discovery.IDiscovery {
+	view := &namespaceDiscovery{
+		IDiscovery: inner,
+		namespace:  namespace,
+		watches:    make(map[string]*namespaceWatch),
+	}
+	if registry, ok := inner.(discovery.INameRegistry); ok {
+		return &namespaceRegistry{namespaceDiscovery: view, registry: registry}
+	}
+	return view
+}
+
+func (n *namespaceDiscovery) visible(member *discovery.Member) bool {
+	return member != nil && member.Namespace() == n.namespace
+}
+
+func (n *namespaceDiscovery) filter(members map[uint64]*discovery.Member) map[uint64]*discovery.Member {
+	if members == nil {
+		return nil
+	}
+	result := make(map[uint64]*discovery.Member, len(members))
+	for id, member := range members {
+		if n.visible(member) {
+			result[id] = member
+		}
+	}
+	return result
+}
+
+func (n *namespaceDiscovery) filterList(members []*discovery.Member) []*discovery.Member {
+	var result []*discovery.Member
+	for _, member := range members {
+		if n.visible(member) {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+func (n *namespaceDiscovery) GetById(memberId uint64) *discovery.Member {
+	member := n.IDiscovery.GetById(memberId)
+	if !n.visible(member) {
+		return nil
+	}
+	return member
+}
+
+func (n *namespaceDiscovery) GetByKind(kind string) map[uint64]*discovery.Member {
+	return n.filter(n.IDiscovery.GetByKind(kind))
+}
+
+func (n *namespaceDiscovery) GetAll() map[uint64]*discovery.Member {
+	return n.filter(n.IDiscovery.GetAll())
+}
+
+func (n *namespaceDiscovery) Watch(kind string, handler discovery.ServiceChangeHandler) uint64 {
+	n.mu.Lock()
+	w, ok := n.watches[kind]
+	if !ok {
+		w = &namespaceWatch{view: n, listener: event.NewListener[*discovery.Topology]()}
+		n.watches[kind] = w
+	}
+	n.mu.Unlock()
+
+	id := w.listener.Add(handler)
+	if !ok {
+		n.IDiscovery.Watch(kind, w.notify)
+	}
+	return id
+}
+
+func (n *namespaceDiscovery) Unwatch(kind string, id uint64) {
+	n.mu.Lock()
+	w, ok := n.watches[kind]
+	n.mu.Unlock()
+	if ok {
+		w.listener.Remove(id)
+	}
+}
+
+func (w *namespaceWatch) notify(topology *discovery.Topology) {
+	view := w.view
+	filtered := &discovery.Topology{
+		All:    view.filterList(topology.All),
+		Update: view.filterList(topology.Update),
+		Joined: view.filterList(topology.Joined),
+		Left:   view.filterList(topology.Left),
+	}
+	if filtered.IsChange() {
+		w.listener.Notify(filtered)
+	}
+}
+
+// scoped 名字在注册表中的键，默认命名空间保持原名
+func (n *namespaceRegistry) scoped(name string) string {
+	if n.namespace == "" {
+		return name
+	}
+	return n.namespace + "/" + name
+}
+
+func (n *namespaceRegistry) Claim(name string, memberId uint64) (uint64, error) {
+	return n.registry.Claim(n.scoped(name), memberId)
+}
+
+func (n *namespaceRegistry) Release(name string, memberId uint64) error {
+	return n.registry.Release(n.scoped(name), memberId)
+}
+
+func (n *namespaceRegistry) Lookup(name string) (uint64, bool) {
+	return n.registry.Lookup(n.scoped(name))
+}
+
+// lookupMember 查找消息的目标节点，其它命名空间的节点只有在 AllowNamespaces 中显式允许时才可访问
+func (r *Cluster) lookupMember(nodeId uint64) *discovery.Member {
+	if member := r.dis.GetById(nodeId); member != nil {
+		return member
+	}
+	if len(r.allowNamespaces) == 0 {
+		return nil
+	}
+	member := r.raw.GetById(nodeId)
+	if member == nil || !slices.Contains(r.allowNamespaces, member.Namespace()) {
+		return nil
+	}
+	return member
+}
@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// LocalAddress 本节点在服务发现中登记的地址，节点直连的消息队列在该地址上监听
+func (r *Cluster) LocalAddress() string {
+	info := r.node.Info()
+	if info.GetPort() <= 0 {
+		return ""
+	}
+	return net.JoinHostPort(info.GetAddress(), strconv.Itoa(info.GetPort()))
+}
+
+// Resolve 解析主题对应节点的地址，主题由 makeSubject 生成，最后一段为节点 ID
+func (r *Cluster) Resolve(subject string) (string, bool) {
+	index := strings.LastIndexByte(subject, '.')
+	if index < 0 {
+		return "", false
+	}
+	nodeId, err := strconv.ParseUint(subject[index+1:], 10, 64)
+	if err != nil {
+		return "", false
+	}
+	member := r.raw.GetById(nodeId)
+	if member == nil || member.GetPort() <= 0 || r.makeSubject(member.Namespace(), nodeId) != subject {
+		return "", false
+	}
+	return net.JoinHostPort(member.GetAddress(), strconv.Itoa(member.GetPort())), true
+}
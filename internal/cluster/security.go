@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/lib/secure"
+)
+
+// ErrDurableWithSecurity 持久化投递的消息可能超出时间窗口或重复投递，会被重放检测拒绝
+var ErrDurableWithSecurity = errors.New("消息签名不能与持久化投递的消息队列同时使用")
+
+// SecurityConfig 节点间消息签名与加密配置，未配置密钥时不签名
+// 启用后拒绝未签名、签名错误、超出时间窗口或重放的消息
+type SecurityConfig struct {
+	// Keys 集群共享密钥，第一个用于签名，其余只用于验证；
+	// 轮换密钥时先在所有节点追加新密钥，再把新密钥移到第一位，最后删除旧密钥
+	Keys []SecurityKey `json:"keys" yaml:"keys"`
+	// Encrypt 是否使用 AES-GCM 加密消息内容，启用后拒绝未加密的消息
+	Encrypt bool `json:"encrypt" yaml:"encrypt"`
+	// Window 允许的时钟偏差，同时决定重放检测保留 nonce 的时长
+	Window time.Duration `json:"window" yaml:"window"`
+}
+
+// SecurityKey 集群共享密钥，Secret 至少 16 字节
+type SecurityKey struct {
+	Id     string `json:"id" yaml:"id"`
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+func defaultSecurityConfig() *SecurityConfig {
+	return &SecurityConfig{
+		Window: 30 * time.Second,
+	}
+}
+
+// newSealer 未配置密钥时返回 nil，消息原样传输
+func newSealer(conf *SecurityConfig) (*secure.Sealer, error) {
+	if conf == nil || len(conf.Keys) == 0 {
+		return nil, nil
+	}
+	keys := make([]secure.Key, 0, len(conf.Keys))
+	for _, key := range conf.Keys {
+		keys = append(keys, secure.Key{Id: key.Id, Secret: []byte(key.Secret)})
+	}
+	return secure.New(keys, conf.Encrypt, conf.Window)
+}
+
+// seal 压缩后签名，发往消息队列的数据都经过这里
+func (r *Cluster) seal(data []byte) ([]byte, error) {
+	data = r.compressor.encode(data)
+	if r.sealer == nil {
+		return data, nil
+	}
+	return r.sealer.Seal(data)
+}
+
+// open 验证签名后解压，从消息队列收到的数据都经过这里
+func (r *Cluster) open(data []byte) ([]byte, error) {
+	if r.sealer != nil {
+		var err error
+		if data, err = r.sealer.Open(data); err != nil {
+			return nil, err
+		}
+	}
+	return r.compressor.decode(data)
+}
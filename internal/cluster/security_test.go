@@ -0,0 +1,61 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dzm2020/gas/internal/cluster"
+	"github.com/dzm2020/gas/internal/node"
+	"github.com/dzm2020/gas/internal/profile"
+	"github.com/dzm2020/gas/pkg/messageQue"
+	mqIface "github.com/dzm2020/gas/pkg/messageQue/iface"
+)
+
+// durableQue 持久化投递的消息队列，只用于检查启动配置
+type durableQue struct {
+	mqIface.IMessageQue
+}
+
+func (durableQue) Durable() bool {
+	return true
+}
+
+func init() {
+	_ = messageQue.GetFactoryMgr().Register("test.durable", func(args ...any) (mqIface.IMessageQue, error) {
+		return durableQue{}, nil
+	})
+}
+
+const durableConfig = `
+cluster:
+  name: test
+  discovery:
+    type: inmem
+    config:
+      registry: durable
+  messageQueue:
+    type: test.durable
+  security:
+    keys:
+      - id: k1
+        secret: 0123456789abcdef
+`
+
+// TestDurableWithSecurity 测试持久化投递的消息队列与消息签名同时配置时拒绝启动
+func TestDurableWithSecurity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(durableConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profile.Init(path)
+
+	n := node.New("")
+	n.Id = 1
+	err := cluster.NewComponent().Start(context.Background(), n)
+	if !errors.Is(err, cluster.ErrDurableWithSecurity) {
+		t.Fatalf("want ErrDurableWithSecurity, got %v", err)
+	}
+}
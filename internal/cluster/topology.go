@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMemberLeft      = errors.New("目标节点已离开集群")
+	ErrWatcherNotLocal = errors.New("只能订阅本节点的进程")
+	ErrWatcherNotFound = errors.New("订阅拓扑变化的进程不存在")
+)
+
+// pendingCall 等待回复的远程调用，目标节点离开时立即取消
+type pendingCall struct {
+	cancel context.CancelCauseFunc
+}
+
+// OnTopologyChange 注册集群成员变化的回调，返回用于注销的句柄，回调在服务发现的协程中执行，不应阻塞
+// 按句柄注册，同一方法不同实例的方法值可以分别注册
+func (r *Cluster) OnTopologyChange(handler discovery.ServiceChangeHandler) uint64 {
+	return r.topology.Add(handler)
+}
+
+// OffTopologyChange 注销 OnTopologyChange 返回的句柄对应的回调
+func (r *Cluster) OffTopologyChange(id uint64) {
+	r.topology.Remove(id)
+}
+
+// WatchTopology 订阅集群成员变化，事件以任务的形式投递到进程邮箱，
+// actor 实现 iface.ITopologyHandler 即可在单线程中处理，进程退出后自动取消订阅
+func (r *Cluster) WatchTopology(pid *iface.Pid) error {
+	if pid.GetNodeId() != r.node.GetID() {
+		return xerror.Wrapf(ErrWatcherNotLocal, "pid=%v", pid)
+	}
+	if r.node.System().GetProcess(pid) == nil {
+		return xerror.Wrapf(ErrWatcherNotFound, "pid=%v", pid)
+	}
+	r.watchers.Store(pid.GetServiceId(), pid)
+	return nil
+}
+
+// UnwatchTopology 取消订阅集群成员变化
+func (r *Cluster) UnwatchTopology(pid *iface.Pid) {
+	r.watchers.Delete(pid.GetServiceId())
+}
+
+func (r *Cluster) onTopology(topology *discovery.Topology) {
+	for _, member := range topology.Left {
+		r.failPending(member.GetID())
+	}
+	r.topology.Notify(topology)
+
+	events := make([]*iface.MemberEvent, 0, len(topology.Left)+len(topology.Joined)+len(topology.Update))
+	for _, member := range topology.Left {
+		events = append(events, &iface.MemberEvent{Type: iface.MemberLeft, Member: member})
+	}
+	for _, member := range topology.Joined {
+		events = append(events, &iface.MemberEvent{Type: iface.MemberJoined, Member: member})
+	}
+	for _, member := range topology.Update {
+		events = append(events, &iface.MemberEvent{Type: iface.MemberUpdated, Member: member})
+	}
+	r.watchers.Range(func(key, value any) bool {
+		r.deliver(value.(*iface.Pid), events)
+		return true
+	})
+}
+
+// deliver 按顺序把事件投递到订阅者邮箱，进程已退出时取消订阅
+func (r *Cluster) deliver(pid *iface.Pid, events []*iface.MemberEvent) {
+	system := r.node.System()
+	for _, event := range events {
+		err := system.SubmitTask(pid, iface.Task(func(ctx iface.IContext) error {
+			if handler, ok := ctx.Actor().(iface.ITopologyHandler); ok {
+				return handler.OnTopologyChange(ctx, event)
+			}
+			return nil
+		}))
+		if err == nil {
+			continue
+		}
+		if system.GetProcess(pid) == nil {
+			r.watchers.Delete(pid.GetServiceId())
+			return
+		}
+		glog.Warn("集群: 投递拓扑事件失败", zap.Any("pid", pid), zap.Error(err))
+	}
+}
+
+// trackCall 登记发往 nodeId 的远程调用，返回的 ctx 在超时或目标节点离开时取消
+func (r *Cluster) trackCall(nodeId uint64, timeout time.Duration) (context.Context, context.CancelFunc) {
+	leftCtx, cancel := context.WithCancelCause(context.Background())
+	ctx, cancelTimeout := context.WithTimeout(leftCtx, timeout)
+	call := &pendingCall{cancel: cancel}
+
+	r.pendingMu.Lock()
+	if r.pending == nil {
+		r.pending = make(map[uint64]map[*pendingCall]struct{})
+	}
+	calls, ok := r.pending[nodeId]
+	if !ok {
+		calls = make(map[*pendingCall]struct{})
+		r.pending[nodeId] = calls
+	}
+	calls[call] = struct{}{}
+	r.pendingMu.Unlock()
+
+	return ctx, func() {
+		cancelTimeout()
+		cancel(nil)
+		r.pendingMu.Lock()
+		delete(calls, call)
+		if len(r.pending[nodeId]) == 0 {
+			delete(r.pending, nodeId)
+		}
+		r.pendingMu.Unlock()
+	}
+}
+
+// failPending 目标节点离开集群，立即结束所有等待其回复的调用
+func (r *Cluster) failPending(nodeId uint64) {
+	r.pendingMu.Lock()
+	calls := make([]*pendingCall, 0, len(r.pending[nodeId]))
+	for call := range r.pending[nodeId] {
+		calls = append(calls, call)
+	}
+	delete(r.pending, nodeId)
+	r.pendingMu.Unlock()
+
+	if len(calls) > 0 {
+		glog.Warn("集群: 节点离开，取消等待中的调用", zap.Uint64("nodeId", nodeId), zap.Int("calls", len(calls)))
+	}
+	for _, call := range calls {
+		call.cancel(ErrMemberLeft)
+	}
+}
@@ -0,0 +1,53 @@
+package cluster_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/node/nodetest"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+)
+
+// joinRecorder 记录收到的加入事件中的节点
+type joinRecorder struct {
+	mu     sync.Mutex
+	joined map[uint64]bool
+}
+
+func (c *joinRecorder) onTopology(topology *discovery.Topology) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, member := range topology.Joined {
+		c.joined[member.GetID()] = true
+	}
+}
+
+func (c *joinRecorder) has(id uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.joined[id]
+}
+
+// TestOnTopologyChange 测试同一方法不同实例的回调分别注册，并按句柄注销
+func TestOnTopologyChange(t *testing.T) {
+	c := nodetest.New(t, "")
+	n1 := c.Start(1, "game")
+	a, b := &joinRecorder{joined: make(map[uint64]bool)}, &joinRecorder{joined: make(map[uint64]bool)}
+	idA := n1.Cluster().OnTopologyChange(a.onTopology)
+	n1.Cluster().OnTopologyChange(b.onTopology)
+
+	c.Start(2, "game")
+	nodetest.Eventually(t, time.Second, func() bool {
+		return a.has(2) && b.has(2)
+	}, "回调未全部执行")
+
+	n1.Cluster().OffTopologyChange(idA)
+	c.Start(3, "game")
+	nodetest.Eventually(t, time.Second, func() bool {
+		return b.has(3)
+	}, "回调未执行")
+	if a.has(3) {
+		t.Fatal("注销的回调仍被执行")
+	}
+}
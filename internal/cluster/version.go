@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrIncompatibleMember = errors.New("目标节点版本不兼容")
+)
+
+// VersionConfig 版本感知路由配置，用于滚动升级期间新旧节点混合部署
+type VersionConfig struct {
+	// Version 本节点的协议/应用版本，写入 Member.Meta
+	Version string `json:"version" yaml:"version"`
+	// Constraint 目标节点需要满足的版本约束，见 discovery.Constraint；
+	// 为空时要求主版本号与本节点相同，本节点也未配置版本时不限制
+	Constraint string `json:"constraint" yaml:"constraint"`
+	// Canary 灰度路由配置
+	Canary *CanaryConfig `json:"canary" yaml:"canary"`
+}
+
+// CanaryConfig 灰度路由配置，灰度节点为带有 canary 标签的节点
+type CanaryConfig struct {
+	// Percent 路由到灰度节点的流量百分比 [0,100]，带 key 的路由按 key 哈希，同一个 key 稳定落在同一侧
+	Percent float64 `json:"percent" yaml:"percent"`
+	// Keys 始终路由到灰度节点的 key
+	Keys []string `json:"keys" yaml:"keys"`
+}
+
+func defaultVersionConfig() *VersionConfig {
+	return &VersionConfig{
+		Canary: &CanaryConfig{},
+	}
+}
+
+// versionRouter 版本兼容性检查与灰度分流
+type versionRouter struct {
+	constraint *discovery.Constraint
+	canary     *CanaryConfig
+}
+
+func newVersionRouter(conf *VersionConfig) (*versionRouter, error) {
+	router := &versionRouter{canary: conf.Canary}
+	if router.canary == nil {
+		router.canary = &CanaryConfig{}
+	}
+	rule := conf.Constraint
+	if rule == "" && conf.Version != "" {
+		self, err := discovery.ParseVersion(conf.Version)
+		if err != nil {
+			return nil, err
+		}
+		rule = "^" + strconv.Itoa(self.Major)
+	}
+	if rule == "" {
+		return router, nil
+	}
+	constraint, err := discovery.ParseConstraint(rule)
+	if err != nil {
+		return nil, err
+	}
+	router.constraint = constraint
+	return router, nil
+}
+
+// compatible 节点版本是否兼容，未发布版本的节点视为兼容
+func (v *versionRouter) compatible(member *discovery.Member) bool {
+	if v == nil || v.constraint == nil {
+		return true
+	}
+	version, ok := member.Version()
+	if !ok {
+		return true
+	}
+	return v.constraint.Check(version)
+}
+
+// split 按灰度规则选择灰度节点或普通节点，只有一侧可用时使用该侧
+func (v *versionRouter) split(members []*discovery.Member, key string) []*discovery.Member {
+	var canary, stable []*discovery.Member
+	for _, member := range members {
+		if member.IsCanary() {
+			canary = append(canary, member)
+		} else {
+			stable = append(stable, member)
+		}
+	}
+	if len(canary) == 0 || len(stable) == 0 {
+		return members
+	}
+	if v.toCanary(key) {
+		return canary
+	}
+	return stable
+}
+
+func (v *versionRouter) toCanary(key string) bool {
+	if v == nil {
+		return false
+	}
+	conf := v.canary
+	if key != "" && slices.Contains(conf.Keys, key) {
+		return true
+	}
+	if conf.Percent <= 0 {
+		return false
+	}
+	if key == "" {
+		return rand.Float64()*100 < conf.Percent
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return float64(h.Sum32()%10000) < conf.Percent*100
+}
+
+// checkCompatible 发送前检查目标节点版本
+func (r *Cluster) checkCompatible(member *discovery.Member) error {
+	if !r.version.compatible(member) {
+		return xerror.Wrapf(ErrIncompatibleMember, "nodeId=%d version=%s constraint=%s",
+			member.GetID(), member.GetMeta()[discovery.MetaVersion], r.version.constraint)
+	}
+	return nil
+}
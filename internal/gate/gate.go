@@ -10,24 +10,34 @@ import (
 	"github.com/dzm2020/gas/internal/session"
 	"github.com/dzm2020/gas/pkg/glog"
 	"github.com/dzm2020/gas/pkg/network"
+	"github.com/dzm2020/gas/pkg/trace"
 
 	"github.com/duke-git/lancet/v2/convertor"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
 
+var (
+	ErrGateDraining = errors.New("节点正在排空，不再接受新连接")
+)
+
 type Gate struct {
 	network.EmptyHandler
-	node    iface.INode
-	Address string
-	Options []network.Option
-	Factory Factory
-	server  network.IServer
-	maxConn int64
-	count   atomic.Int64
+	node     iface.INode
+	Address  string
+	Options  []network.Option
+	Factory  Factory
+	server   network.IServer
+	maxConn  int64
+	count    atomic.Int64
+	draining atomic.Bool
+	eventId  uint64 // 节点事件处理函数的句柄
 }
 
 func (g *Gate) Start(ctx context.Context) (err error) {
+	g.draining.Store(g.node.IsDraining())
+	// 同一节点可能有多个网关，方法值的函数指针相同，需要按句柄注册
+	g.eventId = g.node.Events().Add(g.onNodeEvent)
 	options := append(g.Options, network.WithCodec(codec.New()))
 	g.server, err = network.NewServer(g, g.Address, options...)
 	if err != nil {
@@ -54,7 +64,17 @@ func (g *Gate) getSession(entity network.IConnection) *session.Session {
 	return convertor.DeepClone(s)
 }
 
+// onNodeEvent 节点排空后停止接受新连接，已有连接继续服务
+func (g *Gate) onNodeEvent(event *iface.NodeEvent) {
+	if event.Type == iface.NodeEventDraining {
+		g.draining.Store(true)
+	}
+}
+
 func (g *Gate) OnConnect(entity network.IConnection) error {
+	if g.draining.Load() {
+		return ErrGateDraining
+	}
 	if g.count.Load() > g.maxConn {
 		return errors.New("too many connections")
 	}
@@ -68,7 +88,7 @@ func (g *Gate) OnConnect(entity network.IConnection) error {
 	return system.Send(message)
 }
 
-func (g *Gate) OnMessage(entity network.IConnection, clientMsg interface{}) error {
+func (g *Gate) OnMessage(entity network.IConnection, clientMsg interface{}) (err error) {
 	system := g.node.System()
 	s := g.getSession(entity)
 
@@ -78,10 +98,26 @@ func (g *Gate) OnMessage(entity network.IConnection, clientMsg interface{}) erro
 
 	message := g.makeActorMessage(s, "OnConnectionMessage", msg.Data)
 
+	// 客户端请求是链路的起点
+	span := trace.Start("gate.receive", "")
+	if span.IsRecording() {
+		span.SetAttr("entityId", convertor.ToString(entity.ID())).
+			SetAttr("cmd", convertor.ToString(msg.Cmd)).
+			SetAttr("act", convertor.ToString(msg.Act))
+	}
+	message.TraceParent = span.TraceParent()
+	defer func() {
+		span.End(err)
+	}()
+
 	return system.Send(message)
 }
 
 func (g *Gate) OnClose(entity network.IConnection, wrong error) {
+	// 被拒绝的连接没有创建 session，无需通知 agent
+	if _, ok := entity.Context().(*session.Session); !ok {
+		return
+	}
 	g.count.Add(-1)
 
 	s := g.getSession(entity)
@@ -107,6 +143,9 @@ func (g *Gate) formatSession(s *session.Session, msg *protocol.Message) {
 }
 
 func (g *Gate) Stop(ctx context.Context) error {
+	if g.node != nil {
+		g.node.Events().Remove(g.eventId)
+	}
 	if g.server == nil {
 		return nil
 	}
@@ -1,6 +1,7 @@
 package iface
 
 import (
+	"context"
 	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
 	"github.com/dzm2020/gas/pkg/lib"
 	"time"
@@ -21,6 +22,7 @@ type (
 
 	ISystem interface {
 		Spawn(actor IActor, args ...interface{}) *Pid
+		NewPid() *Pid // 分配本地进程 ID，用于通过 Add 注册自定义 IProcess
 		Add(pid *Pid, process IProcess)
 		Remove(pid *Pid) error
 		Named(name string, pid *Pid) error
@@ -30,12 +32,15 @@ type (
 		GetProcessById(id uint64) IProcess
 		GetProcessByName(name string) IProcess
 		GetAllProcesses() []IProcess
-		SubmitTask(pid *Pid, task Task) (err error)
-		SubmitTaskAndWait(pid *Pid, task Task, timeout time.Duration) (err error)
+		ProcessCount() int
+		SubmitTask(pid *Pid, task ITask) (err error)
+		SubmitTaskAndWait(pid *Pid, task ITask, timeout time.Duration) (err error)
 		Send(message *ActorMessage) (err error)
 		Call(message *ActorMessage) (data []byte, err error)
 		Shutdown() error
 		Select(name string, strategy discovery.RouteStrategy) *Pid
+		Migrate(pid *Pid, nodeId uint64, timeout time.Duration) (*Pid, error)
+		NameConflict(name string, owner uint64)
 	}
 
 	IContext interface {
@@ -50,6 +55,8 @@ type (
 		Forward(to *Pid, method string) error
 		AfterFunc(duration time.Duration, task Task) *lib.Timer
 		Message() *ActorMessage
+		Context() context.Context // 当前消息的 context，携带截止时间
+		ContentType() uint32      // 当前消息 data 的序列化格式，0 表示节点默认格式
 		Process() IProcess
 		System() ISystem
 		Shutdown() error
@@ -57,10 +64,36 @@ type (
 	}
 	IActor interface {
 		OnInit(ctx IContext, params []interface{}) error
+		// OnMessage 处理没有路由的消息，携带已注册类型（RegisterType、RegisterProto）的消息传入解析后的值，
+		// 其余消息传入 *Message
 		OnMessage(ctx IContext, msg interface{}) error
 		OnStop(ctx IContext) error
 	}
 
+	// IMigratable 支持跨节点迁移的 actor，迁移时在原节点序列化状态，在目标节点创建新实例后恢复
+	// 目标节点上的实例先调用 UnmarshalState，再调用 OnInit（params 为空）；迁移成功后原节点上的实例调用 OnStop
+	IMigratable interface {
+		IActor
+		MarshalState() ([]byte, error)
+		UnmarshalState(data []byte) error
+	}
+
+	// INameConflictHandler 可选接口，全局名字被其他节点占用时在 actor 邮箱中回调，此时本地名字已被注销
+	INameConflictHandler interface {
+		OnNameConflict(ctx IContext, name string, owner uint64) error
+	}
+
+	// ISerializerSelector 可选接口，为 actor 的方法指定序列化格式，返回 0 时使用节点默认格式
+	// 作用于该 actor 发出的 Send/Call，以及收到的未携带内容类型的消息（例如来自网关的消息）
+	ISerializerSelector interface {
+		ContentType(method string) uint32
+	}
+
+	// ITopologyHandler 可选接口，通过 ICluster.WatchTopology 订阅后，集群成员变化时在 actor 邮箱中回调
+	ITopologyHandler interface {
+		OnTopologyChange(ctx IContext, event *MemberEvent) error
+	}
+
 	IRouter interface {
 		Handle(ctx IContext, methodName string, session ISession, data []byte) ([]byte, error)
 		HasRoute(methodName string) bool
@@ -90,6 +90,9 @@ type Message struct {
 	Async         bool                   `protobuf:"varint,5,opt,name=async,proto3" json:"async,omitempty"`
 	Session       *Session               `protobuf:"bytes,6,opt,name=session,proto3" json:"session,omitempty"`
 	Deadline      int64                  `protobuf:"varint,7,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	TraceParent   string                 `protobuf:"bytes,8,opt,name=traceParent,proto3" json:"traceParent,omitempty"`
+	ContentType   uint32                 `protobuf:"varint,9,opt,name=contentType,proto3" json:"contentType,omitempty"`
+	TypeName      string                 `protobuf:"bytes,10,opt,name=typeName,proto3" json:"typeName,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -173,6 +176,27 @@ func (x *Message) GetDeadline() int64 {
 	return 0
 }
 
+func (x *Message) GetTraceParent() string {
+	if x != nil {
+		return x.TraceParent
+	}
+	return ""
+}
+
+func (x *Message) GetContentType() uint32 {
+	if x != nil {
+		return x.ContentType
+	}
+	return 0
+}
+
+func (x *Message) GetTypeName() string {
+	if x != nil {
+		return x.TypeName
+	}
+	return ""
+}
+
 type Response struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
@@ -317,6 +341,162 @@ func (x *Session) GetCode() int64 {
 	return 0
 }
 
+type TaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Args          []byte                 `protobuf:"bytes,2,opt,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskRequest) Reset() {
+	*x = TaskRequest{}
+	mi := &file_actor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskRequest) ProtoMessage() {}
+
+func (x *TaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_actor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskRequest.ProtoReflect.Descriptor instead.
+func (*TaskRequest) Descriptor() ([]byte, []int) {
+	return file_actor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TaskRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetArgs() []byte {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type MigrateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	State         []byte                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrateRequest) Reset() {
+	*x = MigrateRequest{}
+	mi := &file_actor_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrateRequest) ProtoMessage() {}
+
+func (x *MigrateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_actor_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrateRequest.ProtoReflect.Descriptor instead.
+func (*MigrateRequest) Descriptor() ([]byte, []int) {
+	return file_actor_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MigrateRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MigrateRequest) GetState() []byte {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *MigrateRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type MigrateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pid           *Pid                   `protobuf:"bytes,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrateResponse) Reset() {
+	*x = MigrateResponse{}
+	mi := &file_actor_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrateResponse) ProtoMessage() {}
+
+func (x *MigrateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_actor_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrateResponse.ProtoReflect.Descriptor instead.
+func (*MigrateResponse) Descriptor() ([]byte, []int) {
+	return file_actor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *MigrateResponse) GetPid() *Pid {
+	if x != nil {
+		return x.Pid
+	}
+	return nil
+}
+
 var File_actor_proto protoreflect.FileDescriptor
 
 const file_actor_proto_rawDesc = "" +
@@ -325,7 +505,7 @@ const file_actor_proto_rawDesc = "" +
 	"\x03Pid\x12\x16\n" +
 	"\x06nodeId\x18\x01 \x01(\x04R\x06nodeId\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
-	"\tserviceId\x18\x03 \x01(\x04R\tserviceId\"\xcd\x01\n" +
+	"\tserviceId\x18\x03 \x01(\x04R\tserviceId\"\xad\x02\n" +
 	"\aMessage\x12\x1a\n" +
 	"\x02to\x18\x01 \x01(\v2\n" +
 	".actor.PidR\x02to\x12\x1e\n" +
@@ -335,7 +515,11 @@ const file_actor_proto_rawDesc = "" +
 	"\x04data\x18\x04 \x01(\fR\x04data\x12\x14\n" +
 	"\x05async\x18\x05 \x01(\bR\x05async\x12(\n" +
 	"\asession\x18\x06 \x01(\v2\x0e.actor.SessionR\asession\x12\x1a\n" +
-	"\bdeadline\x18\a \x01(\x03R\bdeadline\"6\n" +
+	"\bdeadline\x18\a \x01(\x03R\bdeadline\x12 \n" +
+	"\vtraceParent\x18\b \x01(\tR\vtraceParent\x12 \n" +
+	"\vcontentType\x18\t \x01(\rR\vcontentType\x12\x1a\n" +
+	"\btypeName\x18\n" +
+	" \x01(\tR\btypeName\"6\n" +
 	"\bResponse\x12\x12\n" +
 	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
 	"\x06errMsg\x18\x02 \x01(\tR\x06errMsg\"\xad\x01\n" +
@@ -347,7 +531,17 @@ const file_actor_proto_rawDesc = "" +
 	"\x05index\x18\x04 \x01(\rR\x05index\x12\x10\n" +
 	"\x03cmd\x18\x05 \x01(\rR\x03cmd\x12\x10\n" +
 	"\x03act\x18\x06 \x01(\rR\x03act\x12\x12\n" +
-	"\x04code\x18\a \x01(\x03R\x04codeB\n" +
+	"\x04code\x18\a \x01(\x03R\x04code\"5\n" +
+	"\vTaskRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04args\x18\x02 \x01(\fR\x04args\"N\n" +
+	"\x0eMigrateRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\fR\x05state\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"/\n" +
+	"\x0fMigrateResponse\x12\x1c\n" +
+	"\x03pid\x18\x01 \x01(\v2\n" +
+	".actor.PidR\x03pidB\n" +
 	"Z\b./;ifaceb\x06proto3"
 
 var (
@@ -362,23 +556,27 @@ func file_actor_proto_rawDescGZIP() []byte {
 	return file_actor_proto_rawDescData
 }
 
-var file_actor_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_actor_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_actor_proto_goTypes = []any{
-	(*Pid)(nil),      // 0: actor.Pid
-	(*Message)(nil),  // 1: actor.Message
-	(*Response)(nil), // 2: actor.Response
-	(*Session)(nil),  // 3: actor.Session
+	(*Pid)(nil),             // 0: actor.Pid
+	(*Message)(nil),         // 1: actor.Message
+	(*Response)(nil),        // 2: actor.Response
+	(*Session)(nil),         // 3: actor.Session
+	(*TaskRequest)(nil),     // 4: actor.TaskRequest
+	(*MigrateRequest)(nil),  // 5: actor.MigrateRequest
+	(*MigrateResponse)(nil), // 6: actor.MigrateResponse
 }
 var file_actor_proto_depIdxs = []int32{
 	0, // 0: actor.Message.to:type_name -> actor.Pid
 	0, // 1: actor.Message.from:type_name -> actor.Pid
 	3, // 2: actor.Message.session:type_name -> actor.Session
 	0, // 3: actor.Session.agent:type_name -> actor.Pid
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	0, // 4: actor.MigrateResponse.pid:type_name -> actor.Pid
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_actor_proto_init() }
@@ -392,7 +590,7 @@ func file_actor_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_actor_proto_rawDesc), len(file_actor_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
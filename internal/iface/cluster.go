@@ -2,14 +2,50 @@ package iface
 
 import (
 	"context"
+	"time"
+
 	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
 )
 
 type ICluster interface {
 	Send(message *ActorMessage) (err error)
 	Call(message *ActorMessage) (data []byte, err error)
+	PushTask(pid *Pid, task *NamedTask) error
+	PushTaskAndWait(pid *Pid, timeout time.Duration, task *NamedTask) error
+	BroadcastCall(tag, method string, request interface{}, timeout time.Duration, opts ...BroadcastOption) ([]*BroadcastReply, error)
 	Start(ctx context.Context) error
 	Select(name string, strategy discovery.RouteStrategy) uint64
+	SelectByKey(name, key string, strategy discovery.RouteStrategy) uint64
 	UpdateMember() error
+	SetMeta(meta map[string]string) error
+	AddTag(tag string) error
+	RemoveTag(tag string) error
 	Shutdown(ctx context.Context) error
+	Discovery() discovery.IDiscovery
+	OnTopologyChange(handler discovery.ServiceChangeHandler) uint64
+	OffTopologyChange(id uint64)
+	WatchTopology(pid *Pid) error
+	UnwatchTopology(pid *Pid)
+}
+
+// BroadcastReply ICluster.BroadcastCall 中单个节点的调用结果
+type BroadcastReply struct {
+	NodeId uint64
+	Data   []byte
+	Err    error
+}
+
+// BroadcastOptions ICluster.BroadcastCall 的选项
+type BroadcastOptions struct {
+	FirstSuccess bool // 收到第一个成功结果后立即返回
+}
+
+// BroadcastOption ICluster.BroadcastCall 可选项
+type BroadcastOption func(o *BroadcastOptions)
+
+// WithFirstSuccess 收到第一个成功结果后立即返回，不再等待其他节点
+func WithFirstSuccess() BroadcastOption {
+	return func(o *BroadcastOptions) {
+		o.FirstSuccess = true
+	}
 }
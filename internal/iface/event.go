@@ -0,0 +1,45 @@
+package iface
+
+import (
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+)
+
+// NodeEventType 节点事件类型
+type NodeEventType int
+
+const (
+	// NodeEventDraining 节点进入排空状态，不再接收新的连接和路由
+	NodeEventDraining NodeEventType = iota + 1
+	// NodeEventDrained 节点排空完成，actor 和连接已全部退出或已到达截止时间
+	NodeEventDrained
+	// NodeEventMQDisconnected 消息队列与服务器的连接断开，Data 为消息队列的 *ConnEvent
+	NodeEventMQDisconnected
+	// NodeEventMQReconnected 消息队列重新连接成功，Data 为消息队列的 *ConnEvent
+	NodeEventMQReconnected
+	// NodeEventMQClosed 消息队列连接关闭，Data 为消息队列的 *ConnEvent
+	NodeEventMQClosed
+)
+
+// NodeEvent 节点事件，通过 INode.Events 订阅
+type NodeEvent struct {
+	Type NodeEventType
+	Data interface{}
+}
+
+// MemberEventType 集群成员事件类型
+type MemberEventType int
+
+const (
+	// MemberJoined 节点加入集群
+	MemberJoined MemberEventType = iota + 1
+	// MemberLeft 节点离开集群
+	MemberLeft
+	// MemberUpdated 节点信息（标签、Meta）变化
+	MemberUpdated
+)
+
+// MemberEvent 集群成员事件，通过 ICluster.WatchTopology 订阅后在 actor 邮箱中收到
+type MemberEvent struct {
+	Type   MemberEventType
+	Member *discovery.Member
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dzm2020/gas/pkg/lib"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 )
 
 var (
@@ -13,6 +16,7 @@ var (
 	ErrMessageTargetIsNil   = errors.New("message target (To) is nil")
 	ErrMessageTargetInvalid = errors.New("message target (To) is invalid: both serviceId and name are empty")
 	ErrSyncMessageIsNil     = errors.New("sync message is nil")
+	ErrDeadlineExceeded     = errors.New("message deadline exceeded")
 )
 
 // 编译时检查，确保所有消息类型都实现了 IMessageValidator 接口
@@ -26,18 +30,20 @@ type (
 		Validate() error
 	}
 	TaskMessage struct {
-		Task Task
+		Task ITask
 	}
 
 	ActorMessage struct {
 		*Message
+		// Value 投递给本地 actor 时未序列化的请求值，此时 Data 为空，发往其它节点前由 EncodeValue 序列化
+		Value    interface{}
 		response ResponseFunc
 	}
 
 	ResponseFunc func(data []byte, err error)
 )
 
-func NewTaskMessage(task Task) *TaskMessage {
+func NewTaskMessage(task ITask) *TaskMessage {
 	return &TaskMessage{
 		Task: task,
 	}
@@ -89,6 +95,34 @@ func (m *ActorMessage) Validate() error {
 	return nil
 }
 
+// SetTimeout 以毫秒精度设置消息截止时间
+func (x *Message) SetTimeout(timeout time.Duration) {
+	x.Deadline = time.Now().Add(timeout).UnixMilli()
+}
+
+// HasDeadline 是否设置了截止时间
+func (x *Message) HasDeadline() bool {
+	return x.GetDeadline() > 0
+}
+
+// DeadlineTime 返回截止时间，Deadline 为毫秒时间戳
+func (x *Message) DeadlineTime() time.Time {
+	return time.UnixMilli(x.GetDeadline())
+}
+
+// Remaining 距离截止时间的剩余时长，未设置截止时间时返回 0
+func (x *Message) Remaining() time.Duration {
+	if !x.HasDeadline() {
+		return 0
+	}
+	return time.Until(x.DeadlineTime())
+}
+
+// IsExpired 截止时间是否已过
+func (x *Message) IsExpired() bool {
+	return x.HasDeadline() && x.Remaining() <= 0
+}
+
 func (m *ActorMessage) Response(data []byte, err error) {
 	if m.response == nil {
 		return
@@ -100,6 +134,14 @@ func (m *ActorMessage) SetResponse(f ResponseFunc) {
 	m.response = f
 }
 
+// Redirect 复制消息并改为发往 to，用于代理转发；复制的消息不共享调用方的 Message 和响应回调，
+// 本地投递的原值随消息保留，转发到远程时由集群序列化
+func (m *ActorMessage) Redirect(to *Pid) *ActorMessage {
+	out := &ActorMessage{Message: proto.Clone(m.Message).(*Message), Value: m.Value}
+	out.To = to
+	return out
+}
+
 func NewPid(nodeId uint64, serviceId uint64) *Pid {
 	return &Pid{
 		NodeId:    nodeId,
@@ -114,7 +156,12 @@ func NewPidWithName(name string, nodeId uint64) *Pid {
 }
 
 func (p *Pid) IsGlobalName() bool {
-	return lib.IsFirstLetterUppercase(p.GetName())
+	return IsGlobalName(p.GetName())
+}
+
+// IsGlobalName 首字母大写的名字为全局名字，会在集群中注册
+func IsGlobalName(name string) bool {
+	return lib.IsFirstLetterUppercase(name)
 }
 
 func NewResponse(data []byte, err error) *Response {
@@ -133,3 +180,24 @@ func (r *Response) GetError() error {
 	}
 	return errors.New(r.GetErrMsg())
 }
+
+// GetValue 本地投递的请求值
+func (m *ActorMessage) GetValue() interface{} {
+	if m == nil {
+		return nil
+	}
+	return m.Value
+}
+
+// EncodeValue 将本地投递的请求值按 ContentType 序列化到 Data
+func (m *ActorMessage) EncodeValue(node INode) error {
+	if m.Value == nil || len(m.Data) > 0 {
+		return nil
+	}
+	data, err := node.MarshalWith(m.ContentType, m.Value)
+	if err != nil {
+		return err
+	}
+	m.Data = data
+	return nil
+}
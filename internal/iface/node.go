@@ -4,6 +4,7 @@ import (
 	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
 	"github.com/dzm2020/gas/pkg/lib"
 	"github.com/dzm2020/gas/pkg/lib/component"
+	"github.com/dzm2020/gas/pkg/lib/event"
 )
 
 type (
@@ -24,10 +25,19 @@ type (
 		component.IManager[INode]
 		Info() *Member
 		SetSerializer(ser lib.ISerializer)
+		// ContentType 默认序列化器的内容类型
+		ContentType() uint32
+		// SerializerOf 根据内容类型获取序列化器，0 使用默认序列化器，未注册的类型返回 lib.ErrContentTypeUnknown
+		SerializerOf(contentType uint32) (lib.ISerializer, error)
+		MarshalWith(contentType uint32, request interface{}) ([]byte, error)
+		UnmarshalWith(contentType uint32, data []byte, reply interface{}) error
 		System() ISystem
 		SetSystem(system ISystem)
 		Cluster() ICluster
 		SetCluster(ICluster)
 		Startup(comps ...component.IComponent[INode]) error
+		Events() *event.Listener[*NodeEvent]
+		Drain() error
+		IsDraining() bool
 	}
 )
@@ -0,0 +1,124 @@
+package iface
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+)
+
+var (
+	ErrTaskNameIsEmpty       = errors.New("task name is empty")
+	ErrTaskAlreadyRegistered = errors.New("task already registered")
+	ErrTaskNotRegistered     = errors.New("task not registered")
+	ErrTaskNotSerializable   = errors.New("only named task can be submitted to remote process")
+)
+
+// TaskMethod 远程任务使用的保留方法名
+const TaskMethod = "$task"
+
+var (
+	_ ITask = Task(nil)
+	_ ITask = (*NamedTask)(nil)
+)
+
+type (
+	// ITask 在 actor 邮箱中执行的任务
+	ITask interface {
+		Run(ctx IContext) error
+	}
+
+	// TaskHandler 具名任务处理函数，args 为序列化后的任务参数
+	TaskHandler func(ctx IContext, args []byte) error
+)
+
+// Run 执行闭包任务，闭包任务只能提交给本地进程
+func (t Task) Run(ctx IContext) error {
+	return t(ctx)
+}
+
+var (
+	taskMu       sync.RWMutex
+	taskHandlers = make(map[string]TaskHandler)
+)
+
+// RegisterTask 注册具名任务，需要在所有可能执行该任务的节点上注册
+func RegisterTask(name string, handler TaskHandler) error {
+	if name == "" {
+		return ErrTaskNameIsEmpty
+	}
+	if handler == nil {
+		return ErrTaskIsNilInMsg
+	}
+	taskMu.Lock()
+	defer taskMu.Unlock()
+	if _, ok := taskHandlers[name]; ok {
+		return xerror.Wrapf(ErrTaskAlreadyRegistered, "name=%s", name)
+	}
+	taskHandlers[name] = handler
+	return nil
+}
+
+// RegisterTypedTask 注册参数类型为 T 的具名任务，参数使用节点序列化器解码
+func RegisterTypedTask[T any](name string, fn func(ctx IContext, args *T) error) error {
+	return RegisterTask(name, func(ctx IContext, data []byte) error {
+		args := new(T)
+		if len(data) > 0 {
+			if err := ctx.Node().UnmarshalWith(ctx.ContentType(), data, args); err != nil {
+				return xerror.Wrapf(err, "解析任务参数失败 (name=%s)", name)
+			}
+		}
+		return fn(ctx, args)
+	})
+}
+
+// GetTask 获取已注册的具名任务
+func GetTask(name string) (TaskHandler, bool) {
+	taskMu.RLock()
+	defer taskMu.RUnlock()
+	handler, ok := taskHandlers[name]
+	return handler, ok
+}
+
+// RunTask 按名字执行具名任务
+func RunTask(ctx IContext, name string, args []byte) error {
+	handler, ok := GetTask(name)
+	if !ok {
+		return xerror.Wrapf(ErrTaskNotRegistered, "name=%s", name)
+	}
+	return handler(ctx, args)
+}
+
+// NamedTask 具名任务，参数可序列化，因此可以提交到其他节点的进程中执行
+type NamedTask struct {
+	Name string
+	Args interface{}
+}
+
+func NewNamedTask(name string, args interface{}) *NamedTask {
+	return &NamedTask{
+		Name: name,
+		Args: args,
+	}
+}
+
+// Run 在本地执行具名任务，参数同样经过序列化，保证本地和远程行为一致
+func (t *NamedTask) Run(ctx IContext) error {
+	data, err := t.MarshalArgs(ctx.Node())
+	if err != nil {
+		return err
+	}
+	return RunTask(ctx, t.Name, data)
+}
+
+// MarshalArgs 序列化任务参数
+func (t *NamedTask) MarshalArgs(node INode) ([]byte, error) {
+	if t.Args == nil {
+		return nil, nil
+	}
+	data, err := node.Marshal(t.Args)
+	if err != nil {
+		return nil, xerror.Wrapf(err, "序列化任务参数失败 (name=%s)", t.Name)
+	}
+	return data, nil
+}
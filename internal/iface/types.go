@@ -0,0 +1,81 @@
+package iface
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrTypeNameIsEmpty       = errors.New("类型名为空")
+	ErrTypeMustBePointer     = errors.New("注册的类型必须是指针")
+	ErrTypeAlreadyRegistered = errors.New("类型已注册")
+	ErrTypeNotRegistered     = errors.New("类型未注册")
+)
+
+var (
+	typeMu    sync.RWMutex
+	typeNames = make(map[string]reflect.Type)
+	typeTypes = make(map[reflect.Type]string)
+)
+
+// RegisterType 以稳定的名字注册消息类型，prototype 为该类型的指针，例如 (*Move)(nil)
+// 发送已注册类型的消息时会携带类型名，接收方解析为同一类型后交给 IActor.OnMessage，
+// 需要在所有收发该类型的节点上以相同的名字注册
+func RegisterType(name string, prototype interface{}) error {
+	if name == "" {
+		return ErrTypeNameIsEmpty
+	}
+	typ := reflect.TypeOf(prototype)
+	if typ == nil || typ.Kind() != reflect.Pointer {
+		return xerror.Wrapf(ErrTypeMustBePointer, "name=%s type=%v", name, typ)
+	}
+	typeMu.Lock()
+	defer typeMu.Unlock()
+	if old, ok := typeNames[name]; ok && old != typ {
+		return xerror.Wrapf(ErrTypeAlreadyRegistered, "name=%s type=%v", name, old)
+	}
+	if old, ok := typeTypes[typ]; ok && old != name {
+		return xerror.Wrapf(ErrTypeAlreadyRegistered, "type=%v name=%s", typ, old)
+	}
+	typeNames[name] = typ
+	typeTypes[typ] = name
+	return nil
+}
+
+// RegisterProto 注册 protobuf 消息，类型名为消息的全名
+func RegisterProto(messages ...proto.Message) error {
+	for _, message := range messages {
+		name := string(message.ProtoReflect().Descriptor().FullName())
+		if err := RegisterType(name, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TypeNameOf 获取值的注册类型名
+func TypeNameOf(value interface{}) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	typeMu.RLock()
+	defer typeMu.RUnlock()
+	name, ok := typeTypes[reflect.TypeOf(value)]
+	return name, ok
+}
+
+// NewTypedValue 创建类型名对应的新值，返回值为指针
+func NewTypedValue(name string) (interface{}, error) {
+	typeMu.RLock()
+	typ, ok := typeNames[name]
+	typeMu.RUnlock()
+	if !ok {
+		return nil, xerror.Wrapf(ErrTypeNotRegistered, "name=%s", name)
+	}
+	return reflect.New(typ.Elem()).Interface(), nil
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/event"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/network"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNodeDraining = errors.New("节点正在排空")
+)
+
+// DrainConfig 节点排空配置
+type DrainConfig struct {
+	Shutdown      bool          `json:"shutdown" yaml:"shutdown"`           // 排空完成后是否自动停止节点
+	Timeout       time.Duration `json:"timeout" yaml:"timeout"`             // 排空截止时间，到达后视为排空完成
+	CheckInterval time.Duration `json:"checkInterval" yaml:"checkInterval"` // 检查 actor 和连接数量的间隔
+}
+
+func defaultDrainConfig() *DrainConfig {
+	return &DrainConfig{
+		Shutdown:      true,
+		Timeout:       5 * time.Minute,
+		CheckInterval: time.Second,
+	}
+}
+
+// Events 节点事件监听器
+func (n *Node) Events() *event.Listener[*iface.NodeEvent] {
+	return n.events
+}
+
+// IsDraining 节点是否正在排空
+func (n *Node) IsDraining() bool {
+	return n.draining.Load()
+}
+
+// Drain 排空节点，用于滚动部署
+// 在服务发现中把本节点标记为排空，集群路由不再选择本节点，网关停止接受新连接但继续服务已有连接；
+// 之后等待 actor 和连接全部退出或到达截止时间，发出 NodeEventDrained 事件，并按配置停止节点
+func (n *Node) Drain() error {
+	if !n.draining.CompareAndSwap(false, true) {
+		return ErrNodeDraining
+	}
+	glog.Info("节点开始排空", zap.Uint64("nodeId", n.GetID()), zap.Duration("timeout", n.drainConf.Timeout))
+
+	if cluster := n.Cluster(); cluster != nil {
+		if err := cluster.SetMeta(map[string]string{discovery.MetaDraining: "true"}); err != nil {
+			glog.Error("节点排空: 发布排空状态失败", zap.Error(err))
+		}
+	}
+	n.events.Notify(&iface.NodeEvent{Type: iface.NodeEventDraining})
+
+	grs.Go(func(ctx context.Context) {
+		n.waitDrained(ctx)
+	})
+	return nil
+}
+
+func (n *Node) waitDrained(ctx context.Context) {
+	conf := n.drainConf
+	deadline := time.NewTimer(conf.Timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(conf.CheckInterval)
+	defer ticker.Stop()
+
+wait:
+	for {
+		actors, conns := n.actorCount(), network.ConnectionCount()
+		if actors == 0 && conns == 0 {
+			glog.Info("节点排空完成")
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			glog.Warn("节点排空到达截止时间", zap.Int("actors", actors), zap.Int64("conns", conns))
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	n.events.Notify(&iface.NodeEvent{Type: iface.NodeEventDrained})
+	if conf.Shutdown {
+		n.stopOnce.Do(func() {
+			close(n.stopChan)
+		})
+	}
+}
+
+// actorCount 业务 actor 数量，不包括区域、代理等没有 actor 上下文的系统进程
+func (n *Node) actorCount() int {
+	system := n.System()
+	if system == nil {
+		return 0
+	}
+	count := 0
+	for _, process := range system.GetAllProcesses() {
+		if process.Context() != nil {
+			count++
+		}
+	}
+	return count
+}
@@ -7,19 +7,30 @@ import (
 	"github.com/dzm2020/gas/internal/iface"
 	"github.com/dzm2020/gas/internal/logger"
 	"github.com/dzm2020/gas/internal/profile"
+	"github.com/dzm2020/gas/internal/tracing"
 	"github.com/dzm2020/gas/pkg/glog"
 	"github.com/dzm2020/gas/pkg/lib"
 	"github.com/dzm2020/gas/pkg/lib/component"
+	"github.com/dzm2020/gas/pkg/lib/event"
 	"github.com/dzm2020/gas/pkg/lib/grs"
 	"github.com/dzm2020/gas/pkg/lib/xerror"
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	_ "github.com/dzm2020/gas/pkg/discovery/provider/consul"
+	_ "github.com/dzm2020/gas/pkg/discovery/provider/inmem"
+	_ "github.com/dzm2020/gas/pkg/discovery/provider/redis"
+	_ "github.com/dzm2020/gas/pkg/discovery/provider/static"
+	_ "github.com/dzm2020/gas/pkg/discovery/provider/swim"
+	_ "github.com/dzm2020/gas/pkg/messageQue/provider/inproc"
 	_ "github.com/dzm2020/gas/pkg/messageQue/provider/nats"
+	_ "github.com/dzm2020/gas/pkg/messageQue/provider/redis"
+	_ "github.com/dzm2020/gas/pkg/messageQue/provider/tcp"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -34,6 +45,9 @@ func New(path string) *Node {
 		IManager:   component.NewComponentsMgr[iface.INode](),
 		path:       path,
 		viper:      viper.New(),
+		events:     event.NewListener[*iface.NodeEvent](),
+		drainConf:  defaultDrainConfig(),
+		stopChan:   make(chan struct{}),
 	}
 	return node
 }
@@ -42,27 +56,41 @@ type Node struct {
 	*iface.Member
 	component.IManager[iface.INode]
 	path       string
+	refMu      sync.RWMutex // 组件停止时会清空 system 和 cluster，actor 可能仍在并发访问
 	system     iface.ISystem
 	cluster    iface.ICluster
 	serializer lib.ISerializer
 	panicHook  func(entry zapcore.Entry)
 	viper      *viper.Viper
+	events     *event.Listener[*iface.NodeEvent]
+	drainConf  *DrainConfig
+	draining   atomic.Bool
+	stopOnce   sync.Once
+	stopChan   chan struct{}
 }
 
 func (n *Node) Info() *iface.Member {
 	return n.Member
 }
 func (n *Node) SetSystem(system iface.ISystem) {
+	n.refMu.Lock()
+	defer n.refMu.Unlock()
 	n.system = system
 }
 func (n *Node) System() iface.ISystem {
+	n.refMu.RLock()
+	defer n.refMu.RUnlock()
 	return n.system
 }
 
 func (n *Node) SetCluster(cluster iface.ICluster) {
+	n.refMu.Lock()
+	defer n.refMu.Unlock()
 	n.cluster = cluster
 }
 func (n *Node) Cluster() iface.ICluster {
+	n.refMu.RLock()
+	defer n.refMu.RUnlock()
 	return n.cluster
 }
 
@@ -77,7 +105,34 @@ func (n *Node) Serializer() lib.ISerializer {
 	return n.serializer
 }
 
+// ContentType 默认序列化器的内容类型
+func (n *Node) ContentType() uint32 {
+	return lib.ContentTypeOf(n.serializer)
+}
+
+// SerializerOf 根据内容类型获取序列化器，0 使用默认序列化器
+// 未注册的类型返回错误，不能按默认格式解析，否则会把其它格式的数据误解析为零值
+func (n *Node) SerializerOf(contentType uint32) (lib.ISerializer, error) {
+	if contentType == 0 {
+		return n.Serializer(), nil
+	}
+	serializer := lib.GetSerializer(contentType)
+	if serializer == nil {
+		return nil, xerror.Wrapf(lib.ErrContentTypeUnknown, "contentType=%d", contentType)
+	}
+	return serializer, nil
+}
+
 func (n *Node) Marshal(request interface{}) ([]byte, error) {
+	return n.MarshalWith(0, request)
+}
+
+func (n *Node) Unmarshal(data []byte, reply interface{}) error {
+	return n.UnmarshalWith(0, data, reply)
+}
+
+// MarshalWith 使用指定内容类型的序列化器序列化
+func (n *Node) MarshalWith(contentType uint32, request interface{}) ([]byte, error) {
 	if request == nil {
 		return []byte{}, nil
 	}
@@ -86,10 +141,15 @@ func (n *Node) Marshal(request interface{}) ([]byte, error) {
 		return data, nil
 	}
 
-	return n.Serializer().Marshal(request)
+	serializer, err := n.SerializerOf(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return serializer.Marshal(request)
 }
 
-func (n *Node) Unmarshal(data []byte, reply interface{}) error {
+// UnmarshalWith 使用指定内容类型的序列化器反序列化
+func (n *Node) UnmarshalWith(contentType uint32, data []byte, reply interface{}) error {
 	if len(data) == 0 {
 		return nil
 	}
@@ -102,7 +162,11 @@ func (n *Node) Unmarshal(data []byte, reply interface{}) error {
 		*ptr = data
 		return nil
 	}
-	return n.Serializer().Unmarshal(data, reply)
+	serializer, err := n.SerializerOf(contentType)
+	if err != nil {
+		return err
+	}
+	return serializer.Unmarshal(data, reply)
 }
 
 func (n *Node) Startup(comps ...component.IComponent[iface.INode]) (err error) {
@@ -117,10 +181,14 @@ func (n *Node) Startup(comps ...component.IComponent[iface.INode]) (err error) {
 	if err = profile.Get("node", n.Member); err != nil {
 		return
 	}
+	if err = profile.Get("drain", n.drainConf); err != nil {
+		return
+	}
 
 	// 注册组件
 	components := []component.IComponent[iface.INode]{
 		logger.NewComponent(n.panicHook),
+		tracing.NewComponent(),
 		actor.NewComponent(),
 		cluster.NewComponent(),
 	}
@@ -140,12 +208,25 @@ func (n *Node) Startup(comps ...component.IComponent[iface.INode]) (err error) {
 
 	glog.Info("节点启动完成", zap.String("path", n.path), zap.Strings("component", n.IManager.GetComponentNames()))
 
-	// 阻塞等待进程终止信号
+	// 阻塞等待进程终止信号，排空信号触发排空，排空完成后按配置自动停止
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL, syscall.SIGTERM)
-	<-sigChan
-
-	return n.shutdown()
+	drainChan := make(chan os.Signal, 1)
+	if len(drainSignals) > 0 {
+		signal.Notify(drainChan, drainSignals...)
+	}
+	for {
+		select {
+		case <-sigChan:
+			return n.shutdown()
+		case <-n.stopChan:
+			return n.shutdown()
+		case <-drainChan:
+			if err = n.Drain(); err != nil {
+				glog.Error("节点排空失败", zap.Error(err))
+			}
+		}
+	}
 }
 
 // Shutdown 优雅关闭节点，关闭所有组件
@@ -0,0 +1,41 @@
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dzm2020/gas/pkg/lib"
+)
+
+type point struct {
+	X int `json:"x" msgpack:"x"`
+}
+
+// TestSerializerOf 测试按内容类型选择序列化器，未注册的类型返回错误而不是使用默认格式
+func TestSerializerOf(t *testing.T) {
+	n := New("")
+	if serializer, err := n.SerializerOf(0); err != nil || serializer != lib.Json {
+		t.Fatalf("0 应使用默认序列化器: %v %v", serializer, err)
+	}
+	if serializer, err := n.SerializerOf(lib.ContentTypeMsgPack); err != nil || serializer != lib.MsgPack {
+		t.Fatalf("want msgpack, got %v %v", serializer, err)
+	}
+	if _, err := n.SerializerOf(99); !errors.Is(err, lib.ErrContentTypeUnknown) {
+		t.Fatalf("want ErrContentTypeUnknown, got %v", err)
+	}
+
+	data, err := n.MarshalWith(lib.ContentTypeMsgPack, &point{X: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := &point{}
+	if err = n.UnmarshalWith(lib.ContentTypeMsgPack, data, reply); err != nil || reply.X != 1 {
+		t.Fatalf("want x=1, got %+v %v", reply, err)
+	}
+	if _, err = n.MarshalWith(99, &point{X: 1}); !errors.Is(err, lib.ErrContentTypeUnknown) {
+		t.Fatalf("want ErrContentTypeUnknown, got %v", err)
+	}
+	if err = n.UnmarshalWith(99, data, reply); !errors.Is(err, lib.ErrContentTypeUnknown) {
+		t.Fatalf("want ErrContentTypeUnknown, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package nodetest 在同一进程内启动多个节点组成测试集群，节点之间通过 inproc 消息队列通信、通过 inmem 服务发现互相可见
+package nodetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/actor"
+	"github.com/dzm2020/gas/internal/cluster"
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node"
+	"github.com/dzm2020/gas/internal/profile"
+	"github.com/dzm2020/gas/pkg/lib/component"
+)
+
+// clusterConfig 测试集群的配置，每个测试使用独立的注册表和消息代理
+const clusterConfig = `
+cluster:
+  name: test
+  discovery:
+    type: inmem
+    config:
+      registry: %[1]s
+      ttl: 0s
+  messageQueue:
+    type: inproc
+    config:
+      broker: %[1]s
+`
+
+// Cluster 测试集群，测试结束时停止所有节点
+type Cluster struct {
+	t testing.TB
+}
+
+// New 写入测试集群的配置，extra 为追加的 yaml 配置（例如其它组件的配置）
+// 配置是进程级的，同一时间只能有一个测试集群
+func New(t testing.TB, extra string) *Cluster {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()) + fmt.Sprintf("_%d", time.Now().UnixNano())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(clusterConfig, name)+extra), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profile.Init(path)
+	return &Cluster{t: t}
+}
+
+// Start 启动节点，依次启动 actor、cluster 组件和 comps
+func (c *Cluster) Start(id uint64, kind string, comps ...component.IComponent[iface.INode]) *node.Node {
+	c.t.Helper()
+	n := node.New("")
+	n.Id = id
+	n.Kind = kind
+	n.Address = "127.0.0.1"
+	components := append([]component.IComponent[iface.INode]{actor.NewComponent(), cluster.NewComponent()}, comps...)
+	for _, comp := range components {
+		if err := n.Register(comp); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	if err := n.IManager.Start(context.Background(), n); err != nil {
+		c.t.Fatal(err)
+	}
+	c.t.Cleanup(func() {
+		_ = n.IManager.Stop(context.Background())
+	})
+	return n
+}
+
+// Eventually 在 timeout 内反复检查 cond，超时未满足时测试失败
+func Eventually(t testing.TB, timeout time.Duration, cond func() bool, format string, args ...interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
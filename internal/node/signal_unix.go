@@ -0,0 +1,11 @@
+//go:build !windows
+
+package node
+
+import (
+	"os"
+	"syscall"
+)
+
+// drainSignals 触发节点排空的信号
+var drainSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package node
+
+import (
+	"os"
+)
+
+// drainSignals windows 不支持 SIGUSR1，只能通过 Node.Drain 触发排空
+var drainSignals []os.Signal
@@ -0,0 +1,62 @@
+// Package sharding 提供集群分片，将大量实体按固定数量的分片分布到同类节点上，并在节点变化时重新平衡
+package sharding
+
+import (
+	"context"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/profile"
+	"github.com/dzm2020/gas/pkg/lib/component"
+)
+
+const (
+	ComponentName = "sharding"
+)
+
+// Config 集群分片配置
+type Config struct {
+	ShardCount     uint32        `json:"shardCount" yaml:"shardCount"`         // 分片数量，集群内所有节点必须一致
+	CheckInterval  time.Duration `json:"checkInterval" yaml:"checkInterval"`   // 协调者定期检查节点变化的间隔
+	HandoffTimeout time.Duration `json:"handoffTimeout" yaml:"handoffTimeout"` // 等待分片交接完成的超时时间
+	CallTimeout    time.Duration `json:"callTimeout" yaml:"callTimeout"`       // Call 的默认超时时间
+	BufferSize     int           `json:"bufferSize" yaml:"bufferSize"`         // 迁移期间每个分片类型最多缓存的消息数
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		ShardCount:     1000,
+		CheckInterval:  time.Second,
+		HandoffTimeout: 5 * time.Second,
+		CallTimeout:    3 * time.Second,
+		BufferSize:     10000,
+	}
+}
+
+// Component 集群分片组件，需要在 cluster 组件之后启动
+type Component struct {
+	component.BaseComponent[iface.INode]
+	*Manager
+}
+
+func NewComponent() *Component {
+	return &Component{
+		Manager: newManager(),
+	}
+}
+
+func (c *Component) Name() string {
+	return ComponentName
+}
+
+func (c *Component) Start(ctx context.Context, node iface.INode) error {
+	conf := defaultConfig()
+	if err := profile.Get(c.Name(), conf); err != nil {
+		return err
+	}
+	return c.Manager.start(node, conf)
+}
+
+func (c *Component) Stop(ctx context.Context) error {
+	return c.Manager.stop()
+}
@@ -0,0 +1,274 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+)
+
+var (
+	ErrTypeRegistered        = errors.New("分片类型已注册")
+	ErrTypeNotFound          = errors.New("分片类型未注册")
+	ErrProducerIsNil         = errors.New("实体构造函数为空")
+	ErrEntityIdIsEmpty       = errors.New("实体ID为空")
+	ErrClusterNotInitialized = errors.New("集群组件未初始化")
+)
+
+// EntityProducer 创建实体 actor，实体 ID 同时作为 OnInit 的第一个参数传入
+type EntityProducer func(entityId string) iface.IActor
+
+// Option 分片类型可选项
+type Option func(r *region)
+
+// WithStrategy 自定义分片分配策略，默认使用 LeastShardStrategy
+func WithStrategy(strategy AllocationStrategy) Option {
+	return func(r *region) {
+		r.strategy = strategy
+	}
+}
+
+// Manager 集群分片管理器
+//
+// 每种分片类型在每个节点上有一个本地区域进程（名字为 shard.<type>），负责把消息路由到实体所在节点。
+// 同类节点中 ID 最小的节点作为协调者，计算分片分配表并广播给所有同类节点；
+// 分片迁移时协调者先通知原节点停止分片内的实体，再广播新的分配表，期间发往这些分片的消息被缓存。
+// 非 kind 类型的节点不持有分配表，消息交给协调者转发。
+type Manager struct {
+	mu      sync.Mutex
+	node    iface.INode
+	conf    *Config
+	regions map[string]*region
+	kinds   map[string]uint64 // 已监听的节点类型 -> 监听句柄
+	notify  chan struct{}
+	cancel  context.CancelFunc
+}
+
+func newManager() *Manager {
+	return &Manager{
+		regions: make(map[string]*region),
+		kinds:   make(map[string]uint64),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Register 注册分片类型，kind 为承载实体的节点类型
+// 所有需要访问该类型实体的节点都应注册，非 kind 类型的节点不会创建实体
+func (m *Manager) Register(typeName, kind string, producer EntityProducer, opts ...Option) error {
+	if producer == nil {
+		return ErrProducerIsNil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.regions[typeName]; ok {
+		return xerror.Wrapf(ErrTypeRegistered, "type=%s", typeName)
+	}
+	r := newRegion(m, typeName, kind, producer)
+	for _, opt := range opts {
+		opt(r)
+	}
+	m.regions[typeName] = r
+	if m.node != nil {
+		if err := m.setup(r); err != nil {
+			delete(m.regions, typeName)
+			return err
+		}
+		m.trigger()
+	}
+	return nil
+}
+
+// Send 异步发送消息给实体，实体不存在时在所属节点上自动创建
+func (m *Manager) Send(typeName, entityId, method string, request interface{}) error {
+	msg, err := m.newMessage(typeName, entityId, method, request)
+	if err != nil {
+		return err
+	}
+	msg.Async = true
+	return m.node.System().Send(msg)
+}
+
+// Call 同步调用实体，实体不存在时在所属节点上自动创建
+func (m *Manager) Call(typeName, entityId, method string, request, reply interface{}) error {
+	msg, err := m.newMessage(typeName, entityId, method, request)
+	if err != nil {
+		return err
+	}
+	msg.SetTimeout(m.conf.CallTimeout)
+	data, err := m.node.System().Call(msg)
+	if err != nil {
+		return err
+	}
+	return m.node.Unmarshal(data, reply)
+}
+
+// ShardOf 实体所属分片
+func (m *Manager) ShardOf(entityId string) uint32 {
+	return shardOf(entityId, m.conf.ShardCount)
+}
+
+func (m *Manager) newMessage(typeName, entityId, method string, request interface{}) (*iface.ActorMessage, error) {
+	if entityId == "" {
+		return nil, ErrEntityIdIsEmpty
+	}
+	m.mu.Lock()
+	r, ok := m.regions[typeName]
+	m.mu.Unlock()
+	if !ok || r.pid == nil {
+		return nil, xerror.Wrapf(ErrTypeNotFound, "type=%s", typeName)
+	}
+	data, err := m.node.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	envelope := &ShardEnvelope{EntityId: entityId, Method: method, Data: data}
+	bin, err := m.node.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+	msg := iface.NewActorMessage(nil, r.pid, methodEnvelope, bin)
+	msg.ContentType = m.node.ContentType()
+	// 实体按外层消息的类型名解析信封中的请求
+	msg.TypeName, _ = iface.TypeNameOf(request)
+	return msg, nil
+}
+
+func (m *Manager) start(node iface.INode, conf *Config) error {
+	if node.Cluster() == nil {
+		return ErrClusterNotInitialized
+	}
+	if conf.ShardCount == 0 {
+		return ErrShardCountIsZero
+	}
+	m.mu.Lock()
+	m.node = node
+	m.conf = conf
+	for _, r := range m.regions {
+		if err := m.setup(r); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+	}
+	m.mu.Unlock()
+
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+	grs.Go(func(context.Context) {
+		m.loop(ctx)
+	})
+	m.trigger()
+	return nil
+}
+
+// setup 注册区域进程并监听节点类型变化，需要持有锁
+func (m *Manager) setup(r *region) error {
+	system := m.node.System()
+	pid := system.NewPid()
+	system.Add(pid, r)
+	if err := system.Named(regionName(r.typeName), pid); err != nil {
+		_ = system.Remove(pid)
+		return err
+	}
+	r.pid = pid
+	if _, ok := m.kinds[r.kind]; !ok {
+		m.kinds[r.kind] = m.discovery().Watch(r.kind, m.onTopology)
+	}
+	return nil
+}
+
+func (m *Manager) stop() error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if dis := m.discovery(); dis != nil {
+		for kind, id := range m.kinds {
+			dis.Unwatch(kind, id)
+		}
+	}
+	for _, r := range m.regions {
+		_ = r.Shutdown()
+	}
+	return nil
+}
+
+func (m *Manager) discovery() discovery.IDiscovery {
+	cluster := m.node.Cluster()
+	if cluster == nil {
+		return nil
+	}
+	return cluster.Discovery()
+}
+
+func (m *Manager) onTopology(_ *discovery.Topology) {
+	m.trigger()
+}
+
+func (m *Manager) trigger() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) loop(ctx context.Context) {
+	ticker := time.NewTicker(m.conf.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.notify:
+		}
+		m.mu.Lock()
+		regions := make([]*region, 0, len(m.regions))
+		for _, r := range m.regions {
+			regions = append(regions, r)
+		}
+		m.mu.Unlock()
+
+		for _, r := range regions {
+			r.purgeExpired()
+			r.coordinate()
+		}
+	}
+}
+
+// members 获取 kind 类型可分配分片的存活节点，本节点可能尚未出现在服务发现中，需要补充
+// 正在排空的节点不再分配分片，见 draining
+func (m *Manager) members(kind string) []uint64 {
+	return m.filterMembers(kind, false)
+}
+
+// draining 获取 kind 类型正在排空的节点，其上的分片需要交接到其它节点
+func (m *Manager) draining(kind string) []uint64 {
+	return m.filterMembers(kind, true)
+}
+
+func (m *Manager) filterMembers(kind string, draining bool) []uint64 {
+	ids := make(map[uint64]struct{})
+	if dis := m.discovery(); dis != nil {
+		for id, member := range dis.GetByKind(kind) {
+			if member.IsDraining() == draining {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+	if self := m.node.GetID(); m.node.GetKind() == kind {
+		if m.node.IsDraining() == draining {
+			ids[self] = struct{}{}
+		} else {
+			delete(ids, self)
+		}
+	}
+	return sortedIds(ids)
+}
@@ -0,0 +1,506 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrRegionClosed       = errors.New("分片区域已关闭")
+	ErrRegionBufferFull   = errors.New("分片迁移中，区域缓存已满")
+	ErrRegionUnknownMsg   = errors.New("分片区域不支持的消息")
+	ErrShardRouteLoop     = errors.New("分片路由转发次数过多，分配表可能不一致")
+	ErrHandoffTimeout     = errors.New("等待分片交接超时")
+	ErrShardCountIsZero   = errors.New("分片数量不能为0")
+	ErrNoCoordinatorFound = errors.New("未找到分片协调者")
+)
+
+const (
+	methodEnvelope = "$shard"
+	methodTable    = "$shardTable"
+	methodHandoff  = "$shardHandoff"
+	methodQuery    = "$shardQuery"
+
+	// maxHops 消息在节点间转发的最大次数，防止分配表短暂不一致时消息循环转发
+	maxHops = 3
+)
+
+func regionName(typeName string) string {
+	return "shard." + typeName
+}
+
+func shardOf(entityId string, shardCount uint32) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(entityId))
+	return h.Sum32() % shardCount
+}
+
+type pending struct {
+	msg      *iface.ActorMessage
+	envelope *ShardEnvelope
+}
+
+var _ iface.IProcess = (*region)(nil)
+
+// region 分片区域进程，负责一种分片类型在本节点上的消息路由和实体管理
+type region struct {
+	mgr      *Manager
+	typeName string
+	kind     string
+	producer EntityProducer
+	strategy AllocationStrategy
+	pid      *iface.Pid
+
+	mu         sync.Mutex
+	table      *ShardTable
+	entities   map[string]*iface.Pid
+	handingOff map[uint32]struct{} // 正在交出的分片
+	buffer     map[uint32][]*pending
+	buffered   int
+	published  []uint64 // 协调者上次广播分配表时的节点列表
+	closed     bool
+}
+
+func newRegion(mgr *Manager, typeName, kind string, producer EntityProducer) *region {
+	return &region{
+		mgr:        mgr,
+		typeName:   typeName,
+		kind:       kind,
+		producer:   producer,
+		strategy:   &LeastShardStrategy{},
+		entities:   make(map[string]*iface.Pid),
+		handingOff: make(map[uint32]struct{}),
+		buffer:     make(map[uint32][]*pending),
+	}
+}
+
+// Context 区域进程没有 actor 上下文
+func (r *region) Context() iface.IContext {
+	return nil
+}
+
+func (r *region) PostMessage(message iface.IMessage) error {
+	msg, ok := message.(*iface.ActorMessage)
+	if !ok {
+		return ErrRegionUnknownMsg
+	}
+	node := r.mgr.node
+	switch msg.GetMethod() {
+	case methodEnvelope:
+		envelope := &ShardEnvelope{}
+		if err := node.UnmarshalWith(msg.GetContentType(), msg.GetData(), envelope); err != nil {
+			return xerror.Wrap(err, "解析分片消息失败")
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.closed {
+			return ErrRegionClosed
+		}
+		r.route(msg, envelope)
+	case methodTable:
+		table := &ShardTable{}
+		if err := node.UnmarshalWith(msg.GetContentType(), msg.GetData(), table); err != nil {
+			return xerror.Wrap(err, "解析分片分配表失败")
+		}
+		r.applyTable(table)
+		msg.Response(nil, nil)
+	case methodHandoff:
+		request := &HandoffRequest{}
+		if err := node.UnmarshalWith(msg.GetContentType(), msg.GetData(), request); err != nil {
+			return xerror.Wrap(err, "解析分片交接请求失败")
+		}
+		// 等待实体退出需要时间，不阻塞投递方
+		grs.Go(func(ctx context.Context) {
+			msg.Response(nil, r.handoff(request))
+		})
+	case methodQuery:
+		r.mu.Lock()
+		table := r.table
+		r.mu.Unlock()
+		if table == nil {
+			table = &ShardTable{}
+		}
+		msg.Response(node.MarshalWith(msg.GetContentType(), table))
+	default:
+		return xerror.Wrapf(ErrRegionUnknownMsg, "method=%s", msg.GetMethod())
+	}
+	return nil
+}
+
+// route 路由实体消息，需要持有锁
+func (r *region) route(msg *iface.ActorMessage, envelope *ShardEnvelope) {
+	if msg.IsExpired() {
+		msg.Response(nil, iface.ErrDeadlineExceeded)
+		return
+	}
+	shard := shardOf(envelope.GetEntityId(), r.mgr.conf.ShardCount)
+	self := r.mgr.node.GetID()
+
+	if r.table == nil {
+		// 非承载节点不持有分配表，交给协调者转发
+		if r.mgr.node.GetKind() != r.kind {
+			coordinator := r.coordinator()
+			if coordinator == 0 {
+				msg.Response(nil, ErrNoCoordinatorFound)
+				return
+			}
+			r.forward(coordinator, msg, envelope)
+			return
+		}
+		r.hold(shard, msg, envelope)
+		return
+	}
+	if _, ok := r.handingOff[shard]; ok {
+		r.hold(shard, msg, envelope)
+		return
+	}
+	owner := r.table.GetOwners()[shard]
+	switch owner {
+	case 0:
+		r.hold(shard, msg, envelope)
+	case self:
+		r.deliver(msg, envelope)
+	default:
+		r.forward(owner, msg, envelope)
+	}
+}
+
+func (r *region) coordinator() uint64 {
+	members := r.mgr.members(r.kind)
+	if len(members) == 0 {
+		return 0
+	}
+	return members[0]
+}
+
+// hold 缓存迁移中或尚未分配的分片消息
+func (r *region) hold(shard uint32, msg *iface.ActorMessage, envelope *ShardEnvelope) {
+	if r.buffered >= r.mgr.conf.BufferSize {
+		msg.Response(nil, ErrRegionBufferFull)
+		return
+	}
+	r.buffer[shard] = append(r.buffer[shard], &pending{msg: msg, envelope: envelope})
+	r.buffered++
+}
+
+// deliver 投递给本地实体，实体不存在时创建
+func (r *region) deliver(msg *iface.ActorMessage, envelope *ShardEnvelope) {
+	system := r.mgr.node.System()
+	entityId := envelope.GetEntityId()
+
+	var process iface.IProcess
+	if pid, ok := r.entities[entityId]; ok {
+		process = system.GetProcess(pid)
+	}
+	if process == nil {
+		pid := system.Spawn(r.producer(entityId), entityId)
+		r.entities[entityId] = pid
+		process = system.GetProcess(pid)
+	}
+
+	out := &iface.ActorMessage{
+		Message: &iface.Message{
+			To:          r.entities[entityId],
+			From:        msg.GetFrom(),
+			Method:      envelope.GetMethod(),
+			Data:        envelope.GetData(),
+			TypeName:    msg.GetTypeName(),
+			Async:       msg.GetAsync(),
+			Session:     msg.GetSession(),
+			Deadline:    msg.GetDeadline(),
+			TraceParent: msg.GetTraceParent(),
+			ContentType: msg.GetContentType(),
+		},
+	}
+	out.SetResponse(msg.Response)
+	if process == nil {
+		msg.Response(nil, xerror.Wrapf(ErrRegionClosed, "entity=%s", entityId))
+		return
+	}
+	if err := process.PostMessage(out); err != nil {
+		msg.Response(nil, err)
+	}
+}
+
+// forward 转发到其他节点的区域进程
+func (r *region) forward(nodeId uint64, msg *iface.ActorMessage, envelope *ShardEnvelope) {
+	if envelope.GetHops() >= maxHops {
+		glog.Error("分片: 消息转发次数过多", zap.String("type", r.typeName),
+			zap.String("entity", envelope.GetEntityId()), zap.Uint64("nodeId", nodeId))
+		msg.Response(nil, ErrShardRouteLoop)
+		return
+	}
+	envelope.Hops++
+	// 实体消息的 data 保持发送方的序列化格式，信封也使用相同的格式
+	data, err := r.mgr.node.MarshalWith(msg.GetContentType(), envelope)
+	if err != nil {
+		msg.Response(nil, err)
+		return
+	}
+
+	out := iface.NewActorMessage(msg.GetFrom(), iface.NewPidWithName(regionName(r.typeName), nodeId), methodEnvelope, data)
+	out.Async = msg.GetAsync()
+	out.Session = msg.GetSession()
+	out.Deadline = msg.GetDeadline()
+	out.TraceParent = msg.GetTraceParent()
+	out.ContentType = msg.GetContentType()
+	out.TypeName = msg.GetTypeName()
+
+	system := r.mgr.node.System()
+	if out.GetAsync() {
+		if err = system.Send(out); err != nil {
+			glog.Error("分片: 转发消息失败", zap.String("type", r.typeName),
+				zap.Uint64("nodeId", nodeId), zap.Error(err))
+		}
+		return
+	}
+	grs.Go(func(ctx context.Context) {
+		bin, callErr := system.Call(out)
+		msg.Response(bin, callErr)
+	})
+}
+
+// applyTable 应用协调者广播的分配表，并重新路由缓存的消息
+func (r *region) applyTable(table *ShardTable) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || (r.table != nil && table.GetVersion() <= r.table.GetVersion()) {
+		return
+	}
+	r.table = table
+	self := r.mgr.node.GetID()
+	owners := table.GetOwners()
+
+	// 新的分配表生效后交接完成
+	r.handingOff = make(map[uint32]struct{})
+	// 交接超时时协调者仍会重新分配，此时不再属于本节点的实体需要停止
+	system := r.mgr.node.System()
+	for entityId, pid := range r.entities {
+		if owners[shardOf(entityId, r.mgr.conf.ShardCount)] == self {
+			continue
+		}
+		delete(r.entities, entityId)
+		if process := system.GetProcess(pid); process != nil {
+			_ = process.Shutdown()
+		}
+	}
+
+	buffer := r.buffer
+	r.buffer = make(map[uint32][]*pending)
+	r.buffered = 0
+	for _, list := range buffer {
+		for _, p := range list {
+			r.route(p.msg, p.envelope)
+		}
+	}
+}
+
+// handoff 停止即将迁出的分片内的实体，等待实体退出后返回
+func (r *region) handoff(request *HandoffRequest) error {
+	system := r.mgr.node.System()
+	var pids []*iface.Pid
+
+	r.mu.Lock()
+	for _, shard := range request.GetShards() {
+		r.handingOff[shard] = struct{}{}
+	}
+	for entityId, pid := range r.entities {
+		if slices.Contains(request.GetShards(), shardOf(entityId, r.mgr.conf.ShardCount)) {
+			pids = append(pids, pid)
+			delete(r.entities, entityId)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, pid := range pids {
+		if process := system.GetProcess(pid); process != nil {
+			_ = process.Shutdown()
+		}
+	}
+
+	deadline := time.Now().Add(r.mgr.conf.HandoffTimeout)
+	for _, pid := range pids {
+		for system.GetProcess(pid) != nil {
+			if time.Now().After(deadline) {
+				return xerror.Wrapf(ErrHandoffTimeout, "type=%s", r.typeName)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// coordinate 协调者计算新的分配表，交接迁移的分片后广播
+func (r *region) coordinate() {
+	node := r.mgr.node
+	members := r.mgr.members(r.kind)
+	if len(members) == 0 || node.GetKind() != r.kind || members[0] != node.GetID() {
+		r.mu.Lock()
+		r.published = nil
+		r.mu.Unlock()
+		return
+	}
+
+	// 排空中的节点仍然存活，其上的分片需要交接，分配表也需要发给它以便转发消息
+	alive := append(slices.Clone(members), r.mgr.draining(r.kind)...)
+
+	r.mu.Lock()
+	seeded := r.table != nil
+	r.mu.Unlock()
+	if !seeded && !r.seedTable(alive) {
+		return
+	}
+
+	r.mu.Lock()
+	current := make(map[uint32]uint64)
+	for shard, owner := range r.table.GetOwners() {
+		current[shard] = owner
+	}
+	version := r.table.GetVersion()
+	samePublished := slices.Equal(r.published, members)
+	r.mu.Unlock()
+
+	next := r.strategy.Allocate(current, members, r.mgr.conf.ShardCount)
+
+	changed := len(current) != len(next)
+	moves := make(map[uint64][]uint32)
+	for shard, owner := range next {
+		old, ok := current[shard]
+		if !ok || old == owner {
+			continue
+		}
+		changed = true
+		// 已离开节点上的分片无需交接
+		if slices.Contains(alive, old) {
+			moves[old] = append(moves[old], shard)
+		}
+	}
+	if !changed && samePublished {
+		return
+	}
+
+	version++
+	for nodeId, shards := range moves {
+		if _, err := r.callRegion(nodeId, methodHandoff, &HandoffRequest{Version: version, Shards: shards}, true); err != nil {
+			glog.Warn("分片: 交接分片失败，直接重新分配", zap.String("type", r.typeName),
+				zap.Uint64("nodeId", nodeId), zap.Int("shards", len(shards)), zap.Error(err))
+		}
+	}
+
+	table := &ShardTable{Version: version, Owners: next}
+	for _, nodeId := range alive {
+		if _, err := r.callRegion(nodeId, methodTable, table, false); err != nil {
+			glog.Error("分片: 广播分配表失败", zap.String("type", r.typeName),
+				zap.Uint64("nodeId", nodeId), zap.Error(err))
+		}
+	}
+	r.mu.Lock()
+	r.published = members
+	r.mu.Unlock()
+
+	glog.Info("分片: 分配表已更新", zap.String("type", r.typeName), zap.Uint64("version", version),
+		zap.Uint64s("members", members), zap.Int("moves", len(moves)))
+}
+
+// seedTable 协调者没有分配表时（首次启动或重启），从其它节点取回当前生效的分配表
+// 否则新分配表的版本从 1 开始，低于其它节点持有的版本而被拒绝，各节点的路由将永久不一致
+// 有节点未能返回分配表时返回 false，本轮不重新分配，等待下次检查
+func (r *region) seedTable(alive []uint64) bool {
+	self := r.mgr.node.GetID()
+	var latest *ShardTable
+	for _, nodeId := range alive {
+		if nodeId == self {
+			continue
+		}
+		data, err := r.callRegion(nodeId, methodQuery, &ShardTable{}, true)
+		if err != nil {
+			glog.Warn("分片: 获取分配表失败", zap.String("type", r.typeName),
+				zap.Uint64("nodeId", nodeId), zap.Error(err))
+			return false
+		}
+		table := &ShardTable{}
+		if err = r.mgr.node.Unmarshal(data, table); err != nil {
+			glog.Warn("分片: 解析分配表失败", zap.String("type", r.typeName),
+				zap.Uint64("nodeId", nodeId), zap.Error(err))
+			return false
+		}
+		if table.GetVersion() > latest.GetVersion() {
+			latest = table
+		}
+	}
+	if latest != nil {
+		r.applyTable(latest)
+	}
+	return true
+}
+
+func (r *region) callRegion(nodeId uint64, method string, request interface{}, wait bool) ([]byte, error) {
+	data, err := r.mgr.node.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	msg := iface.NewActorMessage(r.pid, iface.NewPidWithName(regionName(r.typeName), nodeId), method, data)
+	system := r.mgr.node.System()
+	if !wait {
+		msg.Async = true
+		return nil, system.Send(msg)
+	}
+	msg.SetTimeout(r.mgr.conf.HandoffTimeout)
+	return system.Call(msg)
+}
+
+// purgeExpired 丢弃缓存中已超过截止时间的消息
+func (r *region) purgeExpired() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for shard, list := range r.buffer {
+		kept := list[:0]
+		for _, p := range list {
+			if p.msg.IsExpired() {
+				p.msg.Response(nil, iface.ErrDeadlineExceeded)
+				r.buffered--
+				continue
+			}
+			kept = append(kept, p)
+		}
+		if len(kept) == 0 {
+			delete(r.buffer, shard)
+			continue
+		}
+		r.buffer[shard] = kept
+	}
+}
+
+func (r *region) Shutdown() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	buffer := r.buffer
+	r.buffer = make(map[uint32][]*pending)
+	r.buffered = 0
+	r.mu.Unlock()
+
+	for _, list := range buffer {
+		for _, p := range list {
+			p.msg.Response(nil, ErrRegionClosed)
+		}
+	}
+	if system := r.mgr.node.System(); system != nil && r.pid != nil {
+		return system.Remove(r.pid)
+	}
+	return nil
+}
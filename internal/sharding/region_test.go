@@ -0,0 +1,252 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+)
+
+const testConfig = "sharding:\n  shardCount: 16\n  checkInterval: 50ms\n  handoffTimeout: 1s\n"
+
+type whereReply struct {
+	NodeId uint64 `json:"nodeId"`
+}
+
+// stopped 记录停止过的实体，键为 节点ID/实体ID
+type stopped struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func (s *stopped) add(nodeId uint64, entityId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[fmt.Sprintf("%d/%s", nodeId, entityId)] = true
+}
+
+func (s *stopped) has(nodeId uint64, entityId string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[fmt.Sprintf("%d/%s", nodeId, entityId)]
+}
+
+type entityActor struct {
+	iface.Actor
+	id      string
+	nodeId  uint64
+	stopped *stopped
+}
+
+func (a *entityActor) OnInit(ctx iface.IContext, params []interface{}) error {
+	a.id = params[0].(string)
+	a.nodeId = ctx.Node().GetID()
+	return nil
+}
+
+func (a *entityActor) OnStop(ctx iface.IContext) error {
+	a.stopped.add(a.nodeId, a.id)
+	return nil
+}
+
+func (a *entityActor) Where(ctx iface.IContext, request *whereReply, response *whereReply) error {
+	response.NodeId = ctx.Node().GetID()
+	return nil
+}
+
+func startShardNode(t *testing.T, c *nodetest.Cluster, id uint64, s *stopped) (*node.Node, *Component) {
+	t.Helper()
+	comp := NewComponent()
+	n := c.Start(id, "game", comp)
+	err := comp.Register("player", "game", func(entityId string) iface.IActor {
+		return &entityActor{stopped: s}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, comp
+}
+
+func tableOf(comp *Component) *ShardTable {
+	comp.mu.Lock()
+	r := comp.regions["player"]
+	comp.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.table
+}
+
+// waitConverged 等待所有节点持有同一版本的分配表，且分片只分配给 owners
+func waitConverged(t *testing.T, comps []*Component, owners ...uint64) *ShardTable {
+	t.Helper()
+	var table *ShardTable
+	nodetest.Eventually(t, 3*time.Second, func() bool {
+		table = tableOf(comps[0])
+		if table == nil {
+			return false
+		}
+		for _, comp := range comps[1:] {
+			if other := tableOf(comp); other == nil || other.GetVersion() != table.GetVersion() {
+				return false
+			}
+		}
+		counts := countByOwner(table.GetOwners())
+		if len(counts) != len(owners) {
+			return false
+		}
+		for _, id := range owners {
+			if counts[id] == 0 {
+				return false
+			}
+		}
+		return true
+	}, "分配表未收敛到节点 %v", owners)
+	return table
+}
+
+func where(t *testing.T, comp *Component, entityId string) uint64 {
+	t.Helper()
+	reply := &whereReply{}
+	if err := comp.Call("player", entityId, "Where", &whereReply{}, reply); err != nil {
+		t.Fatalf("call %s: %v", entityId, err)
+	}
+	return reply.NodeId
+}
+
+// TestHandoff 测试新节点加入后分片交接：原节点先停止实体，消息随后路由到新的所有者
+func TestHandoff(t *testing.T) {
+	c := nodetest.New(t, testConfig)
+	s := &stopped{keys: make(map[string]bool)}
+	_, a := startShardNode(t, c, 1, s)
+	waitConverged(t, []*Component{a}, 1)
+
+	entities := make([]string, 40)
+	for i := range entities {
+		entities[i] = fmt.Sprintf("p%d", i)
+		if nodeId := where(t, a, entities[i]); nodeId != 1 {
+			t.Fatalf("want node 1, got %d", nodeId)
+		}
+	}
+
+	_, b := startShardNode(t, c, 2, s)
+	table := waitConverged(t, []*Component{a, b}, 1, 2)
+
+	moved := 0
+	for _, entityId := range entities {
+		owner := table.GetOwners()[a.ShardOf(entityId)]
+		if owner != 2 {
+			continue
+		}
+		moved++
+		if !s.has(1, entityId) {
+			t.Fatalf("实体 %s 迁出前未在节点 1 停止", entityId)
+		}
+		for _, comp := range []*Component{a, b} {
+			if nodeId := where(t, comp, entityId); nodeId != 2 {
+				t.Fatalf("实体 %s 应在节点 2, got %d", entityId, nodeId)
+			}
+		}
+	}
+	if moved == 0 {
+		t.Fatal("没有分片迁移到节点 2")
+	}
+}
+
+// TestCoordinatorRestart 测试协调者重启后沿用其它节点的分配表版本，新的分配表能被所有节点接受
+func TestCoordinatorRestart(t *testing.T) {
+	c := nodetest.New(t, testConfig)
+	s := &stopped{keys: make(map[string]bool)}
+	n1, a := startShardNode(t, c, 1, s)
+	_, b := startShardNode(t, c, 2, s)
+	_, d := startShardNode(t, c, 3, s)
+	waitConverged(t, []*Component{a, b, d}, 1, 2, 3)
+
+	if err := n1.IManager.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := waitConverged(t, []*Component{b, d}, 2, 3)
+
+	_, a = startShardNode(t, c, 1, s)
+	after := waitConverged(t, []*Component{a, b, d}, 1, 2, 3)
+	if after.GetVersion() <= before.GetVersion() {
+		t.Fatalf("重启后的版本 %d 不大于之前的版本 %d", after.GetVersion(), before.GetVersion())
+	}
+	for i := 0; i < 20; i++ {
+		entityId := fmt.Sprintf("p%d", i)
+		owner := after.GetOwners()[a.ShardOf(entityId)]
+		for _, comp := range []*Component{a, b, d} {
+			if nodeId := where(t, comp, entityId); nodeId != owner {
+				t.Fatalf("实体 %s 应在节点 %d, got %d", entityId, owner, nodeId)
+			}
+		}
+	}
+}
+
+type moveMsg struct {
+	X int `json:"x"`
+}
+
+// typedEntity 没有路由，OnMessage 记录收到的消息
+type typedEntity struct {
+	iface.Actor
+	got chan interface{}
+}
+
+func (a *typedEntity) OnMessage(ctx iface.IContext, msg interface{}) error {
+	a.got <- msg
+	return nil
+}
+
+func init() {
+	_ = iface.RegisterType("sharding.test.move", (*moveMsg)(nil))
+}
+
+// TestTypedEntityMessage 测试已注册类型的请求经分片路由（包括转发到其它节点）后实体按类型解析
+func TestTypedEntityMessage(t *testing.T) {
+	c := nodetest.New(t, testConfig)
+	got := make(chan interface{}, 1)
+	comps := make([]*Component, 2)
+	for i := range comps {
+		comps[i] = NewComponent()
+		c.Start(uint64(i+1), "game", comps[i])
+		err := comps[i].Register("typed", "game", func(entityId string) iface.IActor {
+			return &typedEntity{got: got}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodetest.Eventually(t, 3*time.Second, func() bool {
+		for _, comp := range comps {
+			comp.mu.Lock()
+			r := comp.regions["typed"]
+			comp.mu.Unlock()
+			r.mu.Lock()
+			table := r.table
+			r.mu.Unlock()
+			if table == nil || len(countByOwner(table.GetOwners())) != 2 {
+				return false
+			}
+		}
+		return true
+	}, "分配表未收敛")
+
+	for i := 0; i < 10; i++ {
+		if err := comps[0].Send("typed", fmt.Sprintf("e%d", i), "Move", &moveMsg{X: i}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg := <-got:
+			if move, ok := msg.(*moveMsg); !ok || move.X != i {
+				t.Fatalf("want *moveMsg{%d}, got %#v", i, msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("实体未收到消息")
+		}
+	}
+}
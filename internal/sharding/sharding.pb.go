@@ -0,0 +1,267 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.30.2
+// source: sharding.proto
+
+package sharding
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ShardEnvelope struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EntityId      string                 `protobuf:"bytes,1,opt,name=entityId,proto3" json:"entityId,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Hops          uint32                 `protobuf:"varint,4,opt,name=hops,proto3" json:"hops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardEnvelope) Reset() {
+	*x = ShardEnvelope{}
+	mi := &file_sharding_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardEnvelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardEnvelope) ProtoMessage() {}
+
+func (x *ShardEnvelope) ProtoReflect() protoreflect.Message {
+	mi := &file_sharding_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardEnvelope.ProtoReflect.Descriptor instead.
+func (*ShardEnvelope) Descriptor() ([]byte, []int) {
+	return file_sharding_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShardEnvelope) GetEntityId() string {
+	if x != nil {
+		return x.EntityId
+	}
+	return ""
+}
+
+func (x *ShardEnvelope) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *ShardEnvelope) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ShardEnvelope) GetHops() uint32 {
+	if x != nil {
+		return x.Hops
+	}
+	return 0
+}
+
+type ShardTable struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Owners        map[uint32]uint64      `protobuf:"bytes,2,rep,name=owners,proto3" json:"owners,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardTable) Reset() {
+	*x = ShardTable{}
+	mi := &file_sharding_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardTable) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardTable) ProtoMessage() {}
+
+func (x *ShardTable) ProtoReflect() protoreflect.Message {
+	mi := &file_sharding_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardTable.ProtoReflect.Descriptor instead.
+func (*ShardTable) Descriptor() ([]byte, []int) {
+	return file_sharding_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ShardTable) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ShardTable) GetOwners() map[uint32]uint64 {
+	if x != nil {
+		return x.Owners
+	}
+	return nil
+}
+
+type HandoffRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Shards        []uint32               `protobuf:"varint,2,rep,packed,name=shards,proto3" json:"shards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandoffRequest) Reset() {
+	*x = HandoffRequest{}
+	mi := &file_sharding_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandoffRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffRequest) ProtoMessage() {}
+
+func (x *HandoffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sharding_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffRequest.ProtoReflect.Descriptor instead.
+func (*HandoffRequest) Descriptor() ([]byte, []int) {
+	return file_sharding_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HandoffRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *HandoffRequest) GetShards() []uint32 {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+var File_sharding_proto protoreflect.FileDescriptor
+
+const file_sharding_proto_rawDesc = "" +
+	"\n" +
+	"\x0esharding.proto\x12\bsharding\"k\n" +
+	"\rShardEnvelope\x12\x1a\n" +
+	"\bentityId\x18\x01 \x01(\tR\bentityId\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04hops\x18\x04 \x01(\rR\x04hops\"\x9b\x01\n" +
+	"\n" +
+	"ShardTable\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x128\n" +
+	"\x06owners\x18\x02 \x03(\v2 .sharding.ShardTable.OwnersEntryR\x06owners\x1a9\n" +
+	"\vOwnersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\rR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"B\n" +
+	"\x0eHandoffRequest\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12\x16\n" +
+	"\x06shards\x18\x02 \x03(\rR\x06shardsB\rZ\v./;shardingb\x06proto3"
+
+var (
+	file_sharding_proto_rawDescOnce sync.Once
+	file_sharding_proto_rawDescData []byte
+)
+
+func file_sharding_proto_rawDescGZIP() []byte {
+	file_sharding_proto_rawDescOnce.Do(func() {
+		file_sharding_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sharding_proto_rawDesc), len(file_sharding_proto_rawDesc)))
+	})
+	return file_sharding_proto_rawDescData
+}
+
+var file_sharding_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_sharding_proto_goTypes = []any{
+	(*ShardEnvelope)(nil),  // 0: sharding.ShardEnvelope
+	(*ShardTable)(nil),     // 1: sharding.ShardTable
+	(*HandoffRequest)(nil), // 2: sharding.HandoffRequest
+	nil,                    // 3: sharding.ShardTable.OwnersEntry
+}
+var file_sharding_proto_depIdxs = []int32{
+	3, // 0: sharding.ShardTable.owners:type_name -> sharding.ShardTable.OwnersEntry
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_sharding_proto_init() }
+func file_sharding_proto_init() {
+	if File_sharding_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sharding_proto_rawDesc), len(file_sharding_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_sharding_proto_goTypes,
+		DependencyIndexes: file_sharding_proto_depIdxs,
+		MessageInfos:      file_sharding_proto_msgTypes,
+	}.Build()
+	File_sharding_proto = out.File
+	file_sharding_proto_goTypes = nil
+	file_sharding_proto_depIdxs = nil
+}
@@ -0,0 +1,98 @@
+package sharding
+
+import (
+	"sort"
+)
+
+// AllocationStrategy 分片分配策略
+type AllocationStrategy interface {
+	// Allocate 根据当前分配表和存活节点计算新的分配表，返回 shardId -> nodeId
+	// current 中可能包含已离开的节点，members 按 ID 升序排列且不为空
+	Allocate(current map[uint32]uint64, members []uint64, shardCount uint32) map[uint32]uint64
+}
+
+var _ AllocationStrategy = (*LeastShardStrategy)(nil)
+
+// LeastShardStrategy 最少分片策略
+// 离开节点的分片分配给分片最少的节点，然后从分片最多的节点向最少的节点迁移，直到差值不超过 Threshold
+type LeastShardStrategy struct {
+	Threshold int // 允许的最大分片数差值，默认 1
+	MaxMoves  int // 每次重新平衡最多迁移的分片数，0 表示不限制
+}
+
+func (s *LeastShardStrategy) Allocate(current map[uint32]uint64, members []uint64, shardCount uint32) map[uint32]uint64 {
+	threshold := s.Threshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	alive := make(map[uint64][]uint32, len(members))
+	for _, id := range members {
+		alive[id] = nil
+	}
+
+	result := make(map[uint32]uint64, shardCount)
+	var unassigned []uint32
+	for shard := uint32(0); shard < shardCount; shard++ {
+		owner, ok := current[shard]
+		if _, isAlive := alive[owner]; ok && isAlive {
+			result[shard] = owner
+			alive[owner] = append(alive[owner], shard)
+			continue
+		}
+		unassigned = append(unassigned, shard)
+	}
+
+	// 未分配或所在节点已离开的分片，分配给分片最少的节点
+	for _, shard := range unassigned {
+		owner := leastLoaded(alive, members)
+		result[shard] = owner
+		alive[owner] = append(alive[owner], shard)
+	}
+
+	// 重新平衡，只迁移存活节点之间的分片
+	for moves := 0; s.MaxMoves <= 0 || moves < s.MaxMoves; moves++ {
+		from, to := mostLoaded(alive, members), leastLoaded(alive, members)
+		if len(alive[from])-len(alive[to]) <= threshold {
+			break
+		}
+		shards := alive[from]
+		shard := shards[len(shards)-1]
+		alive[from] = shards[:len(shards)-1]
+		alive[to] = append(alive[to], shard)
+		result[shard] = to
+	}
+	return result
+}
+
+// leastLoaded 分片最少的节点，相同时取 ID 最小的节点，保证所有节点计算结果一致
+func leastLoaded(alive map[uint64][]uint32, members []uint64) uint64 {
+	best := members[0]
+	for _, id := range members[1:] {
+		if len(alive[id]) < len(alive[best]) {
+			best = id
+		}
+	}
+	return best
+}
+
+func mostLoaded(alive map[uint64][]uint32, members []uint64) uint64 {
+	best := members[0]
+	for _, id := range members[1:] {
+		if len(alive[id]) > len(alive[best]) {
+			best = id
+		}
+	}
+	return best
+}
+
+func sortedIds(ids map[uint64]struct{}) []uint64 {
+	result := make([]uint64, 0, len(ids))
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
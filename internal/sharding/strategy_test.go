@@ -0,0 +1,100 @@
+package sharding
+
+import (
+	"testing"
+)
+
+func countByOwner(table map[uint32]uint64) map[uint64]int {
+	counts := make(map[uint64]int)
+	for _, owner := range table {
+		counts[owner]++
+	}
+	return counts
+}
+
+func checkBalanced(t *testing.T, table map[uint32]uint64, members []uint64, shardCount uint32, threshold int) {
+	t.Helper()
+	if len(table) != int(shardCount) {
+		t.Fatalf("want %d shards, got %d", shardCount, len(table))
+	}
+	counts := countByOwner(table)
+	minCount, maxCount := int(shardCount), 0
+	for _, id := range members {
+		minCount = min(minCount, counts[id])
+		maxCount = max(maxCount, counts[id])
+	}
+	if maxCount-minCount > threshold {
+		t.Fatalf("分配不均衡: %v", counts)
+	}
+	for owner := range counts {
+		found := false
+		for _, id := range members {
+			found = found || id == owner
+		}
+		if !found {
+			t.Fatalf("分片分配给了非存活节点 %d", owner)
+		}
+	}
+}
+
+func moved(before, after map[uint32]uint64) int {
+	count := 0
+	for shard, owner := range after {
+		if before[shard] != owner {
+			count++
+		}
+	}
+	return count
+}
+
+func TestLeastShardStrategyInitial(t *testing.T) {
+	s := &LeastShardStrategy{}
+	members := []uint64{1, 2, 3}
+	table := s.Allocate(nil, members, 10)
+	checkBalanced(t, table, members, 10, 1)
+
+	// 所有节点使用相同的输入得到相同的结果
+	again := s.Allocate(nil, members, 10)
+	if moved(table, again) != 0 {
+		t.Fatalf("分配结果不确定: %v %v", table, again)
+	}
+}
+
+func TestLeastShardStrategyMemberLeft(t *testing.T) {
+	s := &LeastShardStrategy{}
+	before := s.Allocate(nil, []uint64{1, 2, 3}, 12)
+	left := countByOwner(before)[2]
+
+	after := s.Allocate(before, []uint64{1, 3}, 12)
+	checkBalanced(t, after, []uint64{1, 3}, 12, 1)
+	// 只迁移离开节点上的分片
+	if n := moved(before, after); n != left {
+		t.Fatalf("want %d moves, got %d", left, n)
+	}
+	for shard, owner := range before {
+		if owner != 2 && after[shard] != owner {
+			t.Fatalf("存活节点的分片 %d 被迁移", shard)
+		}
+	}
+}
+
+func TestLeastShardStrategyMemberJoined(t *testing.T) {
+	before := (&LeastShardStrategy{}).Allocate(nil, []uint64{1, 2}, 12)
+
+	after := (&LeastShardStrategy{}).Allocate(before, []uint64{1, 2, 3}, 12)
+	checkBalanced(t, after, []uint64{1, 2, 3}, 12, 1)
+	// 新节点只从已有节点接收分片，已有节点之间不互相迁移
+	if n := moved(before, after); n != countByOwner(after)[3] {
+		t.Fatalf("want %d moves, got %d", countByOwner(after)[3], n)
+	}
+
+	limited := (&LeastShardStrategy{MaxMoves: 2}).Allocate(before, []uint64{1, 2, 3}, 12)
+	if n := moved(before, limited); n != 2 {
+		t.Fatalf("want 2 moves, got %d", n)
+	}
+
+	loose := (&LeastShardStrategy{Threshold: 6}).Allocate(before, []uint64{1, 2, 3}, 12)
+	if n := moved(before, loose); n != 0 {
+		t.Fatalf("差值未超过阈值时不迁移, got %d moves", n)
+	}
+}
@@ -0,0 +1,56 @@
+// Package singleton 提供集群单例 actor，保证同一类节点中只运行一个实例，并在节点离开后自动迁移
+package singleton
+
+import (
+	"context"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/profile"
+	"github.com/dzm2020/gas/pkg/lib/component"
+)
+
+const (
+	ComponentName = "singleton"
+)
+
+// Config 集群单例配置
+type Config struct {
+	CheckInterval time.Duration `json:"checkInterval" yaml:"checkInterval"` // 定期检查所有者的间隔
+	BufferSize    int           `json:"bufferSize" yaml:"bufferSize"`       // 所有者切换期间代理最多缓存的消息数
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		CheckInterval: time.Second,
+		BufferSize:    1024,
+	}
+}
+
+// Component 集群单例组件，需要在 cluster 组件之后启动
+type Component struct {
+	component.BaseComponent[iface.INode]
+	*Manager
+}
+
+func NewComponent() *Component {
+	return &Component{
+		Manager: newManager(),
+	}
+}
+
+func (c *Component) Name() string {
+	return ComponentName
+}
+
+func (c *Component) Start(ctx context.Context, node iface.INode) error {
+	conf := defaultConfig()
+	if err := profile.Get(c.Name(), conf); err != nil {
+		return err
+	}
+	return c.Manager.start(node, conf)
+}
+
+func (c *Component) Stop(ctx context.Context) error {
+	return c.Manager.stop()
+}
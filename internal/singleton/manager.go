@@ -0,0 +1,324 @@
+package singleton
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	discovery "github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrNameMustBeGlobal      = errors.New("单例名字必须是全局名字（首字母大写）")
+	ErrSingletonRegistered   = errors.New("单例已注册")
+	ErrSingletonNotFound     = errors.New("单例未注册")
+	ErrProducerIsNil         = errors.New("单例构造函数为空")
+	ErrClusterNotInitialized = errors.New("集群组件未初始化")
+)
+
+// proxyPrefix 代理进程名前缀，代理名字为本地名字，不会注册到集群
+const proxyPrefix = "singleton."
+
+// Producer 创建单例 actor
+type Producer func() iface.IActor
+
+type singleton struct {
+	name     string
+	kind     string
+	producer Producer
+	args     []interface{}
+	proxy    *proxyProcess
+}
+
+// Manager 集群单例管理器
+//
+// 选举规则：同类节点中已经持有单例名字标签的节点继续作为所有者（多个时取 ID 最小者），
+// 否则由 ID 最小的节点创建。节点离开后，其它节点在服务发现感知到变化或下一次定期检查时重新选举，
+// 因此迁移耗时约为 服务发现健康检查感知时间 + CheckInterval。
+// 服务发现实现了 INameRegistry（如 consul 会话锁）时，单例名字的占用是原子的，同一时刻只有一个节点能注册成功。
+type Manager struct {
+	mu         sync.Mutex
+	node       iface.INode
+	conf       *Config
+	singletons map[string]*singleton
+	kinds      map[string]uint64 // 已监听的节点类型 -> 监听句柄
+	notify     chan struct{}
+	cancel     context.CancelFunc
+}
+
+func newManager() *Manager {
+	return &Manager{
+		singletons: make(map[string]*singleton),
+		kinds:      make(map[string]uint64),
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+// Register 注册单例，kind 为可以运行该单例的节点类型
+// 所有需要访问该单例的节点都应注册，非 kind 类型的节点只创建代理，不参与选举
+func (m *Manager) Register(name, kind string, producer Producer, args ...interface{}) error {
+	if !iface.IsGlobalName(name) {
+		return xerror.Wrapf(ErrNameMustBeGlobal, "name=%s", name)
+	}
+	if producer == nil {
+		return ErrProducerIsNil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.singletons[name]; ok {
+		return xerror.Wrapf(ErrSingletonRegistered, "name=%s", name)
+	}
+	s := &singleton{
+		name:     name,
+		kind:     kind,
+		producer: producer,
+		args:     args,
+	}
+	m.singletons[name] = s
+	if m.node != nil {
+		if err := m.setup(s); err != nil {
+			delete(m.singletons, name)
+			return err
+		}
+		m.trigger()
+	}
+	return nil
+}
+
+// Proxy 获取单例的本地代理 pid，发往代理的消息会被转发到当前所有者，切换期间会被缓存
+func (m *Manager) Proxy(name string) (*iface.Pid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.singletons[name]
+	if !ok || s.proxy == nil {
+		return nil, xerror.Wrapf(ErrSingletonNotFound, "name=%s", name)
+	}
+	return s.proxy.pid, nil
+}
+
+// Owner 获取单例当前所在节点，切换期间返回 0
+func (m *Manager) Owner(name string) uint64 {
+	m.mu.Lock()
+	s, ok := m.singletons[name]
+	m.mu.Unlock()
+	if !ok || s.proxy == nil {
+		return 0
+	}
+	return s.proxy.getOwner()
+}
+
+func (m *Manager) start(node iface.INode, conf *Config) error {
+	if node.Cluster() == nil {
+		return ErrClusterNotInitialized
+	}
+	m.mu.Lock()
+	m.node = node
+	m.conf = conf
+	for _, s := range m.singletons {
+		if err := m.setup(s); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+	}
+	m.mu.Unlock()
+
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+	grs.Go(func(context.Context) {
+		m.loop(ctx)
+	})
+	m.trigger()
+	return nil
+}
+
+// setup 创建代理进程并监听节点类型变化，需要持有锁
+func (m *Manager) setup(s *singleton) error {
+	system := m.node.System()
+	s.proxy = newProxyProcess(m, s.name, system.NewPid())
+	system.Add(s.proxy.pid, s.proxy)
+	if err := system.Named(proxyPrefix+s.name, s.proxy.pid); err != nil {
+		_ = system.Remove(s.proxy.pid)
+		return err
+	}
+	if _, ok := m.kinds[s.kind]; !ok {
+		m.kinds[s.kind] = m.discovery().Watch(s.kind, m.onTopology)
+	}
+	return nil
+}
+
+func (m *Manager) stop() error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if dis := m.discovery(); dis != nil {
+		for kind, id := range m.kinds {
+			dis.Unwatch(kind, id)
+		}
+	}
+	// 主动停止本地实例，其它节点可以立即接管
+	for _, s := range m.singletons {
+		m.stopLocal(s)
+		if s.proxy != nil {
+			_ = s.proxy.Shutdown()
+		}
+	}
+	return nil
+}
+
+func (m *Manager) discovery() discovery.IDiscovery {
+	cluster := m.node.Cluster()
+	if cluster == nil {
+		return nil
+	}
+	return cluster.Discovery()
+}
+
+func (m *Manager) onTopology(_ *discovery.Topology) {
+	m.trigger()
+}
+
+// trigger 通知后台协程立即检查，不会阻塞服务发现回调
+func (m *Manager) trigger() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) loop(ctx context.Context) {
+	ticker := time.NewTicker(m.conf.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.notify:
+		}
+		m.reconcile()
+	}
+}
+
+func (m *Manager) reconcile() {
+	m.mu.Lock()
+	singletons := make([]*singleton, 0, len(m.singletons))
+	for _, s := range m.singletons {
+		singletons = append(singletons, s)
+	}
+	m.mu.Unlock()
+
+	for _, s := range singletons {
+		m.reconcileOne(s)
+	}
+}
+
+func (m *Manager) reconcileOne(s *singleton) {
+	if m.node.GetKind() == s.kind {
+		selfId := m.node.GetID()
+		running := m.node.System().HasName(s.name)
+		leader := m.elect(s)
+		switch {
+		case leader == selfId && !running:
+			m.spawnLocal(s)
+		case leader != selfId && running:
+			glog.Info("集群单例: 所有者已变更，停止本地实例",
+				zap.String("name", s.name), zap.Uint64("leader", leader))
+			m.stopLocal(s)
+		}
+	}
+	s.proxy.setOwner(m.owner(s))
+	s.proxy.purgeExpired()
+}
+
+// elect 选举单例所有者
+func (m *Manager) elect(s *singleton) uint64 {
+	var tagged, lowest uint64
+	for _, member := range m.candidates(s.kind) {
+		id := member.GetID()
+		if slices.Contains(member.GetTags(), s.name) && (tagged == 0 || id < tagged) {
+			tagged = id
+		}
+		if lowest == 0 || id < lowest {
+			lowest = id
+		}
+	}
+	if tagged != 0 {
+		return tagged
+	}
+	return lowest
+}
+
+// candidates 获取参与选举的节点，本节点可能尚未出现在服务发现中，需要补充
+// 正在排空的节点不参与选举，其持有的单例会迁移到其它节点
+func (m *Manager) candidates(kind string) map[uint64]*discovery.Member {
+	result := make(map[uint64]*discovery.Member)
+	if dis := m.discovery(); dis != nil {
+		for id, member := range dis.GetByKind(kind) {
+			if member.IsDraining() {
+				continue
+			}
+			result[id] = member
+		}
+	}
+	self := m.node.Info()
+	if m.node.IsDraining() {
+		delete(result, self.GetID())
+	} else {
+		result[self.GetID()] = self
+	}
+	return result
+}
+
+// owner 当前实际运行单例的节点
+func (m *Manager) owner(s *singleton) uint64 {
+	if m.node.System().HasName(s.name) {
+		return m.node.GetID()
+	}
+	var owner uint64
+	if dis := m.discovery(); dis != nil {
+		for id, member := range dis.GetByKind(s.kind) {
+			if id == m.node.GetID() || !slices.Contains(member.GetTags(), s.name) {
+				continue
+			}
+			if owner == 0 || id < owner {
+				owner = id
+			}
+		}
+	}
+	return owner
+}
+
+func (m *Manager) spawnLocal(s *singleton) {
+	system := m.node.System()
+	pid := system.Spawn(s.producer(), s.args...)
+	if err := system.Named(s.name, pid); err != nil {
+		glog.Error("集群单例: 注册名字失败", zap.String("name", s.name), zap.Error(err))
+		if process := system.GetProcess(pid); process != nil {
+			_ = process.Shutdown()
+		}
+		return
+	}
+	glog.Info("集群单例: 本节点成为所有者", zap.String("name", s.name), zap.Any("pid", pid))
+}
+
+func (m *Manager) stopLocal(s *singleton) {
+	process := m.node.System().GetProcessByName(s.name)
+	if process == nil {
+		return
+	}
+	if err := process.Shutdown(); err != nil {
+		glog.Error("集群单例: 停止本地实例失败", zap.String("name", s.name), zap.Error(err))
+	}
+}
@@ -0,0 +1,148 @@
+package singleton
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrProxyClosed        = errors.New("单例代理已关闭")
+	ErrProxyBufferFull    = errors.New("单例切换中，代理缓存已满")
+	ErrProxyUnsupportTask = errors.New("单例代理不支持任务消息")
+)
+
+var _ iface.IProcess = (*proxyProcess)(nil)
+
+// proxyProcess 单例代理进程，将消息转发到当前所有者，所有者未知时缓存消息
+type proxyProcess struct {
+	mgr    *Manager
+	name   string
+	pid    *iface.Pid
+	mu     sync.Mutex
+	owner  uint64
+	buffer []*iface.ActorMessage
+	closed bool
+}
+
+func newProxyProcess(mgr *Manager, name string, pid *iface.Pid) *proxyProcess {
+	return &proxyProcess{
+		mgr:  mgr,
+		name: name,
+		pid:  pid,
+	}
+}
+
+// Context 代理进程没有 actor 上下文
+func (p *proxyProcess) Context() iface.IContext {
+	return nil
+}
+
+func (p *proxyProcess) PostMessage(message iface.IMessage) error {
+	msg, ok := message.(*iface.ActorMessage)
+	if !ok {
+		return ErrProxyUnsupportTask
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrProxyClosed
+	}
+	if p.owner == 0 {
+		if len(p.buffer) >= p.mgr.conf.BufferSize {
+			return ErrProxyBufferFull
+		}
+		p.buffer = append(p.buffer, msg)
+		return nil
+	}
+	// 持有锁转发，保证缓存的消息先于新消息发出
+	p.forward(p.owner, msg)
+	return nil
+}
+
+func (p *proxyProcess) getOwner() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.owner
+}
+
+// setOwner 更新所有者，所有者确定后发出缓存的消息
+func (p *proxyProcess) setOwner(owner uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.owner != owner {
+		glog.Info("集群单例: 所有者变更", zap.String("name", p.name),
+			zap.Uint64("old", p.owner), zap.Uint64("new", owner))
+	}
+	p.owner = owner
+	if owner == 0 || len(p.buffer) == 0 {
+		return
+	}
+	buffer := p.buffer
+	p.buffer = nil
+	for _, msg := range buffer {
+		p.forward(owner, msg)
+	}
+}
+
+// purgeExpired 丢弃缓存中已超过截止时间的消息
+func (p *proxyProcess) purgeExpired() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	kept := p.buffer[:0]
+	for _, msg := range p.buffer {
+		if msg.IsExpired() {
+			msg.Response(nil, iface.ErrDeadlineExceeded)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	p.buffer = kept
+}
+
+func (p *proxyProcess) forward(owner uint64, msg *iface.ActorMessage) {
+	if msg.IsExpired() {
+		msg.Response(nil, iface.ErrDeadlineExceeded)
+		return
+	}
+	out := msg.Redirect(iface.NewPidWithName(p.name, owner))
+
+	system := p.mgr.node.System()
+	if out.GetAsync() {
+		if err := system.Send(out); err != nil {
+			glog.Error("集群单例: 转发消息失败", zap.String("name", p.name),
+				zap.Uint64("owner", owner), zap.Error(err))
+		}
+		return
+	}
+	grs.Go(func(ctx context.Context) {
+		data, err := system.Call(out)
+		msg.Response(data, err)
+	})
+}
+
+func (p *proxyProcess) Shutdown() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	buffer := p.buffer
+	p.buffer = nil
+	p.mu.Unlock()
+
+	for _, msg := range buffer {
+		msg.Response(nil, ErrProxyClosed)
+	}
+	if system := p.mgr.node.System(); system != nil {
+		return system.Remove(p.pid)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package singleton_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/node"
+	"github.com/dzm2020/gas/internal/node/nodetest"
+	"github.com/dzm2020/gas/internal/singleton"
+)
+
+type ownerReply struct {
+	NodeId uint64 `json:"nodeId"`
+}
+
+type ownerActor struct {
+	iface.Actor
+}
+
+func (a *ownerActor) Owner(ctx iface.IContext, request *ownerReply, response *ownerReply) error {
+	response.NodeId = ctx.Node().GetID()
+	return nil
+}
+
+func startSingleton(t *testing.T, c *nodetest.Cluster, id uint64) (*node.Node, *singleton.Component) {
+	t.Helper()
+	comp := singleton.NewComponent()
+	n := c.Start(id, "game", comp)
+	if err := comp.Register("Owner", "game", func() iface.IActor { return &ownerActor{} }); err != nil {
+		t.Fatal(err)
+	}
+	return n, comp
+}
+
+// TestProxyForward 测试代理把消息转发到所有者，且不修改调用方的消息
+func TestProxyForward(t *testing.T) {
+	c := nodetest.New(t, "singleton:\n  checkInterval: 50ms\n")
+	_, a := startSingleton(t, c, 1)
+	n2, b := startSingleton(t, c, 2)
+	nodetest.Eventually(t, 2*time.Second, func() bool {
+		return a.Owner("Owner") == 1 && b.Owner("Owner") == 1
+	}, "单例所有者未确定")
+
+	proxy, err := b.Proxy("Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := iface.NewActorMessage(nil, proxy, "Owner", []byte("{}"))
+	msg.SetTimeout(time.Second)
+	data, err := n2.System().Call(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"nodeId":1}` {
+		t.Fatalf("want reply from node 1, got %s", data)
+	}
+	if msg.GetTo() != proxy || proxy.GetNodeId() != 2 || proxy.GetName() == "Owner" {
+		t.Fatalf("调用方的消息被修改: to=%v proxy=%v", msg.GetTo(), proxy)
+	}
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"github.com/dzm2020/gas/internal/iface"
+	"github.com/dzm2020/gas/internal/profile"
+	"github.com/dzm2020/gas/pkg/lib/component"
+	"github.com/dzm2020/gas/pkg/trace"
+
+	"github.com/duke-git/lancet/v2/convertor"
+)
+
+const (
+	ComponentName = "trace"
+)
+
+// Component 链路追踪组件
+type Component struct {
+	component.BaseComponent[iface.INode]
+}
+
+// NewComponent 创建链路追踪组件
+func NewComponent() *Component {
+	return &Component{}
+}
+
+func (c *Component) Name() string {
+	return ComponentName
+}
+
+func (c *Component) Start(ctx context.Context, node iface.INode) error {
+	conf := trace.DefaultConfig()
+	if err := profile.Get(c.Name(), conf); err != nil {
+		return err
+	}
+	resource := map[string]string{
+		"nodeKind": node.GetKind(),
+		"nodeId":   convertor.ToString(node.GetID()),
+	}
+	return trace.Init(conf, resource)
+}
+
+func (c *Component) Stop(ctx context.Context) error {
+	return trace.Shutdown(ctx)
+}
@@ -2,8 +2,16 @@ package iface
 
 import (
 	"context"
+	"errors"
 )
 
+var (
+	ErrNameClaimed = errors.New("名字已被其他节点占用")
+)
+
+// AllKinds 监听所有类型节点的变化，用于 Watch/Unwatch
+const AllKinds = "*"
+
 type (
 	IDiscovery interface {
 		Run(ctx context.Context) error
@@ -13,11 +21,31 @@ type (
 		GetById(memberId uint64) *Member
 		GetByKind(kind string) map[uint64]*Member
 		GetAll() map[uint64]*Member
-		Watch(kind string, handler ServiceChangeHandler)
-		Unwatch(kind string, handler ServiceChangeHandler)
+		// Watch 监听 kind 类型节点的变化，kind 为 AllKinds 时监听所有类型；返回的句柄用于 Unwatch，
+		// 同一方法不同实例的方法值（例如 r.onTopology）分别注册
+		Watch(kind string, handler ServiceChangeHandler) uint64
+		// Unwatch 按 Watch 返回的句柄注销
+		Unwatch(kind string, id uint64)
 		Shutdown(ctx context.Context) error
 	}
 
+	// INameRegistry 服务发现的可选实现，提供集群一致的全局名字注册表
+	// 名字的占用与节点健康状态绑定，节点失效时自动释放
+	INameRegistry interface {
+		// Claim 原子地占用名字，已被其他节点占用时返回 ErrNameClaimed 和当前占用者
+		Claim(name string, memberId uint64) (owner uint64, err error)
+		// Release 释放名字，只有占用者可以释放，其他节点调用时不做任何处理
+		Release(name string, memberId uint64) error
+		// Lookup 查询名字的占用者
+		Lookup(name string) (owner uint64, ok bool)
+	}
+
+	// INamespaced 服务发现的可选实现，按命名空间隔离存储节点，集群在 Run 之前设置本节点的命名空间
+	// 设置后只监听和返回该命名空间内的节点，不同命名空间中 ID 相同的节点互不覆盖
+	INamespaced interface {
+		SetNamespace(namespace string)
+	}
+
 	ServiceChangeHandler func(_ *Topology)
 )
 
@@ -0,0 +1,50 @@
+package iface
+
+import (
+	"strconv"
+)
+
+// 节点负载相关的 Meta 键，由节点定期发布，供负载感知的路由策略读取
+const (
+	MetaActors     = "actors"     // actor 数量
+	MetaConns      = "conns"      // 网络连接数
+	MetaCPU        = "cpu"        // CPU 使用率 [0,100]
+	MetaGoroutines = "goroutines" // 协程数
+	MetaWeight     = "weight"     // 静态权重，表示节点容量，默认 1
+	MetaDraining   = "draining"   // 节点正在排空，值为 "true" 时不再接收新的路由
+)
+
+// MetaFloat 读取数值类型的 Meta，不存在或格式错误时返回 def
+func (b *Member) MetaFloat(key string, def float64) float64 {
+	value, ok := b.GetMeta()[key]
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+// Weight 节点静态权重，未配置或不合法时为 1
+func (b *Member) Weight() float64 {
+	weight := b.MetaFloat(MetaWeight, 1)
+	if weight <= 0 {
+		return 1
+	}
+	return weight
+}
+
+// IsDraining 节点是否正在排空
+func (b *Member) IsDraining() bool {
+	return b.GetMeta()[MetaDraining] == "true"
+}
+
+// Load 节点负载评分，越小越空闲
+// 以连接数和 actor 数衡量业务压力，按权重折算到单位容量，再按 CPU 使用率放大
+func (b *Member) Load() float64 {
+	pressure := b.MetaFloat(MetaConns, 0) + b.MetaFloat(MetaActors, 0)
+	cpu := b.MetaFloat(MetaCPU, 0)
+	return (pressure + 1) / b.Weight() * (1 + cpu/100)
+}
@@ -28,6 +28,19 @@ func (b *Member) GetMeta() map[string]string {
 	return b.Meta
 }
 
+// Clone 深拷贝 Member，副本与原值不共享 Tags 和 Meta
+func (b *Member) Clone() *Member {
+	c := *b
+	c.Tags = append([]string(nil), b.Tags...)
+	if b.Meta != nil {
+		c.Meta = make(map[string]string, len(b.Meta))
+		for k, v := range b.Meta {
+			c.Meta[k] = v
+		}
+	}
+	return &c
+}
+
 // Equal 比较两个 Member 是否相等
 func (b *Member) Equal(other *Member) bool {
 	if b == nil && other == nil {
@@ -68,3 +81,11 @@ func (b *Member) Equal(other *Member) bool {
 	}
 	return true
 }
+
+// MetaNamespace 节点所属命名空间，未设置时属于默认命名空间 ""
+const MetaNamespace = "namespace"
+
+// Namespace 节点所属命名空间
+func (b *Member) Namespace() string {
+	return b.GetMeta()[MetaNamespace]
+}
@@ -0,0 +1,22 @@
+package iface
+
+import (
+	"testing"
+)
+
+// TestMemberClone 测试副本与原值不共享 Tags 和 Meta
+func TestMemberClone(t *testing.T) {
+	member := &Member{Id: 1, Kind: "game", Tags: []string{"a"}, Meta: map[string]string{"k": "v"}}
+	c := member.Clone()
+	if !c.Equal(member) {
+		t.Fatalf("副本不相等: %+v", c)
+	}
+	member.Tags[0] = "b"
+	member.Meta["k"] = "w"
+	if c.Tags[0] != "a" || c.Meta["k"] != "v" {
+		t.Fatalf("副本被修改: %+v", c)
+	}
+	if (&Member{}).Clone().Meta != nil {
+		t.Fatal("空 Meta 的副本应为 nil")
+	}
+}
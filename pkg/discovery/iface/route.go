@@ -34,3 +34,43 @@ func RouteFirst(members []*Member) *Member {
 	}
 	return members[0]
 }
+
+// RouteLeastLoaded 选择负载评分最低的节点，评分见 Member.Load
+func RouteLeastLoaded(members []*Member) *Member {
+	var best *Member
+	var bestLoad float64
+	for _, member := range members {
+		load := member.Load()
+		if best == nil || load < bestLoad {
+			best, bestLoad = member, load
+		}
+	}
+	return best
+}
+
+// RouteWeightedRandom 按权重随机选择节点，权重为静态权重乘以 CPU 剩余比例
+// 与 RouteLeastLoaded 相比不会在负载信息更新前把流量集中到同一个节点
+func RouteWeightedRandom(members []*Member) *Member {
+	if len(members) == 0 {
+		return nil
+	}
+	weights := make([]float64, len(members))
+	var total float64
+	for i, member := range members {
+		idle := 1 - member.MetaFloat(MetaCPU, 0)/100
+		// 满载节点保留少量流量，避免所有节点满载时无节点可选
+		if idle < 0.05 {
+			idle = 0.05
+		}
+		weights[i] = member.Weight() * idle
+		total += weights[i]
+	}
+	r := rand.Float64() * total
+	for i, weight := range weights {
+		r -= weight
+		if r < 0 {
+			return members[i]
+		}
+	}
+	return members[len(members)-1]
+}
@@ -0,0 +1,77 @@
+package iface
+
+import (
+	"testing"
+)
+
+func newLoadMember(id uint64, meta map[string]string) *Member {
+	return &Member{Id: id, Meta: meta}
+}
+
+// TestRouteLeastLoaded 测试最小负载路由
+func TestRouteLeastLoaded(t *testing.T) {
+	if RouteLeastLoaded(nil) != nil {
+		t.Fatal("空列表应返回 nil")
+	}
+	members := []*Member{
+		newLoadMember(1, map[string]string{MetaConns: "100", MetaActors: "50", MetaCPU: "20"}),
+		newLoadMember(2, map[string]string{MetaConns: "10", MetaActors: "5", MetaCPU: "90"}),
+		newLoadMember(3, map[string]string{MetaConns: "100", MetaActors: "50", MetaCPU: "20", MetaWeight: "4"}),
+	}
+	if selected := RouteLeastLoaded(members); selected.GetID() != 2 {
+		t.Fatalf("应选择连接最少的节点 2，实际 %d", selected.GetID())
+	}
+
+	members[1].Meta[MetaConns] = "1000"
+	if selected := RouteLeastLoaded(members); selected.GetID() != 3 {
+		t.Fatalf("应选择权重更高的节点 3，实际 %d", selected.GetID())
+	}
+}
+
+// TestRouteWeightedRandom 测试加权随机路由
+func TestRouteWeightedRandom(t *testing.T) {
+	if RouteWeightedRandom(nil) != nil {
+		t.Fatal("空列表应返回 nil")
+	}
+	members := []*Member{
+		newLoadMember(1, map[string]string{MetaWeight: "9"}),
+		newLoadMember(2, map[string]string{MetaWeight: "1"}),
+		newLoadMember(3, map[string]string{MetaWeight: "9", MetaCPU: "100"}),
+	}
+	counts := make(map[uint64]int)
+	for i := 0; i < 10000; i++ {
+		counts[RouteWeightedRandom(members).GetID()]++
+	}
+	if counts[1] <= counts[2]*4 {
+		t.Fatalf("高权重节点被选中次数过少: %v", counts)
+	}
+	if counts[3] >= counts[2] {
+		t.Fatalf("满载节点被选中次数过多: %v", counts)
+	}
+}
+
+// TestMemberWeight 测试权重解析
+func TestMemberWeight(t *testing.T) {
+	cases := map[string]float64{"": 1, "abc": 1, "-2": 1, "0": 1, "3": 3, "0.5": 0.5}
+	for value, want := range cases {
+		member := newLoadMember(1, map[string]string{})
+		if value != "" {
+			member.Meta[MetaWeight] = value
+		}
+		if got := member.Weight(); got != want {
+			t.Errorf("weight=%q: want %v, got %v", value, want, got)
+		}
+	}
+}
+
+// TestMemberIsDraining 测试排空标记解析
+func TestMemberIsDraining(t *testing.T) {
+	member := newLoadMember(1, map[string]string{})
+	if member.IsDraining() {
+		t.Fatal("未设置排空标记时不应处于排空状态")
+	}
+	member.Meta[MetaDraining] = "true"
+	if !member.IsDraining() {
+		t.Fatal("设置排空标记后应处于排空状态")
+	}
+}
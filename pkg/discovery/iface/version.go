@@ -0,0 +1,174 @@
+package iface
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrInvalidVersion    = errors.New("版本号格式错误")
+	ErrInvalidConstraint = errors.New("版本约束格式错误")
+)
+
+const (
+	MetaVersion = "version" // 节点协议/应用版本，格式为 major.minor.patch
+	TagCanary   = "canary"  // 灰度节点标签
+)
+
+// Version 语义化版本号，只比较 major.minor.patch，忽略预发布和构建信息
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion 解析版本号，支持 v 前缀，缺省的部分视为 0，例如 "v1.2" 等价于 "1.2.0"
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(raw, "-+"); i >= 0 {
+		raw = raw[:i]
+	}
+	parts := strings.Split(raw, ".")
+	if raw == "" || len(parts) > 3 {
+		return v, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+// Compare 比较版本号，小于、等于、大于 o 时分别返回 -1、0、1
+func (v Version) Compare(o Version) int {
+	switch {
+	case v.Major != o.Major:
+		return compareInt(v.Major, o.Major)
+	case v.Minor != o.Minor:
+		return compareInt(v.Minor, o.Minor)
+	default:
+		return compareInt(v.Patch, o.Patch)
+	}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Constraint 版本约束，多个条件以逗号分隔，全部满足时才兼容，例如 ">=1.2, <2"
+//
+// 支持的条件：
+//   - ^1.2   主版本号相同且不低于 1.2.0
+//   - ~1.2   主、次版本号相同且不低于 1.2.0
+//   - >=1.2、>1.2、<=1.2、<2、=1.2.3
+//   - 1.2    等价于 ^1.2
+//   - * 或空 不限制
+type Constraint struct {
+	clauses []clause
+}
+
+type clause struct {
+	op      string
+	version Version
+}
+
+var constraintOps = []string{">=", "<=", ">", "<", "=", "^", "~"}
+
+// ParseConstraint 解析版本约束
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || item == "*" {
+			continue
+		}
+		op := "^"
+		for _, candidate := range constraintOps {
+			if strings.HasPrefix(item, candidate) {
+				op = candidate
+				item = strings.TrimSpace(item[len(candidate):])
+				break
+			}
+		}
+		version, err := ParseVersion(item)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+		}
+		c.clauses = append(c.clauses, clause{op: op, version: version})
+	}
+	return c, nil
+}
+
+// Check 版本是否满足约束
+func (c *Constraint) Check(v Version) bool {
+	for _, cl := range c.clauses {
+		if !cl.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Constraint) String() string {
+	items := make([]string, 0, len(c.clauses))
+	for _, cl := range c.clauses {
+		items = append(items, cl.op+cl.version.String())
+	}
+	if len(items) == 0 {
+		return "*"
+	}
+	return strings.Join(items, ", ")
+}
+
+func (cl clause) check(v Version) bool {
+	cmp := v.Compare(cl.version)
+	switch cl.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "=":
+		return cmp == 0
+	case "~":
+		return v.Major == cl.version.Major && v.Minor == cl.version.Minor && cmp >= 0
+	default:
+		return v.Major == cl.version.Major && cmp >= 0
+	}
+}
+
+// Version 节点发布的版本号，未发布或格式错误时 ok 为 false
+func (b *Member) Version() (v Version, ok bool) {
+	raw, found := b.GetMeta()[MetaVersion]
+	if !found {
+		return v, false
+	}
+	v, err := ParseVersion(raw)
+	return v, err == nil
+}
+
+// IsCanary 是否为灰度节点
+func (b *Member) IsCanary() bool {
+	return slices.Contains(b.GetTags(), TagCanary)
+}
@@ -0,0 +1,84 @@
+package iface
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseVersion 测试版本号解析
+func TestParseVersion(t *testing.T) {
+	cases := map[string]Version{
+		"1.2.3":        {1, 2, 3},
+		"v1.2":         {1, 2, 0},
+		"2":            {2, 0, 0},
+		"1.4.0-rc.1":   {1, 4, 0},
+		"1.0.0+build5": {1, 0, 0},
+	}
+	for raw, want := range cases {
+		got, err := ParseVersion(raw)
+		if err != nil || got != want {
+			t.Errorf("%q: want %v, got %v (err=%v)", raw, want, got, err)
+		}
+	}
+	for _, raw := range []string{"", "a.b", "1.2.3.4", "-1"} {
+		if _, err := ParseVersion(raw); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("%q: 应返回 ErrInvalidVersion，实际 %v", raw, err)
+		}
+	}
+}
+
+// TestConstraint 测试版本约束
+func TestConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"", "0.1.0", true},
+		{"*", "9.9.9", true},
+		{"^1.2", "1.2.0", true},
+		{"^1.2", "1.9.3", true},
+		{"^1.2", "1.1.9", false},
+		{"^1.2", "2.0.0", false},
+		{"1.2", "1.3.0", true},
+		{"~1.2", "1.2.7", true},
+		{"~1.2", "1.3.0", false},
+		{">=1.2, <2", "1.5.0", true},
+		{">=1.2, <2", "2.0.0", false},
+		{">1.2.3", "1.2.3", false},
+		{"<=1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+	}
+	for _, c := range cases {
+		constraint, err := ParseConstraint(c.constraint)
+		if err != nil {
+			t.Fatalf("%q: %v", c.constraint, err)
+		}
+		version, _ := ParseVersion(c.version)
+		if got := constraint.Check(version); got != c.want {
+			t.Errorf("%q check %q: want %v, got %v", c.constraint, c.version, c.want, got)
+		}
+	}
+	if _, err := ParseConstraint(">=x"); !errors.Is(err, ErrInvalidConstraint) {
+		t.Errorf("应返回 ErrInvalidConstraint，实际 %v", err)
+	}
+}
+
+// TestMemberVersion 测试节点版本与灰度标签
+func TestMemberVersion(t *testing.T) {
+	member := &Member{Id: 1, Meta: map[string]string{}}
+	if _, ok := member.Version(); ok {
+		t.Fatal("未发布版本时 ok 应为 false")
+	}
+	member.Meta[MetaVersion] = "v1.3.0"
+	if v, ok := member.Version(); !ok || v != (Version{1, 3, 0}) {
+		t.Fatalf("版本解析错误: %v %v", v, ok)
+	}
+	if member.IsCanary() {
+		t.Fatal("未打标签时不应为灰度节点")
+	}
+	member.Tags = append(member.Tags, TagCanary)
+	if !member.IsCanary() {
+		t.Fatal("带 canary 标签时应为灰度节点")
+	}
+}
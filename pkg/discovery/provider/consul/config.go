@@ -9,6 +9,9 @@ type Config struct {
 	WatchWaitTime      time.Duration `json:"watchWaitTime" mapstruct:"watchWaitTime"`
 	HealthTTL          time.Duration `json:"healthTTL" mapstruct:"healthTTL"`
 	DeregisterInterval time.Duration `json:"deregisterInterval" mapstruct:"deregisterInterval"`
+	NamePrefix         string        `json:"namePrefix" mapstruct:"namePrefix"`       // 全局名字在 KV 中的前缀
+	NameLockDelay      time.Duration `json:"nameLockDelay" mapstruct:"nameLockDelay"` // 节点失效后名字可被重新占用的延迟
+	Namespace          string        `json:"namespace" mapstruct:"namespace"`         // 只监听该命名空间的节点，由集群按其命名空间设置
 }
 
 func DefaultConfig() *Config {
@@ -17,5 +20,7 @@ func DefaultConfig() *Config {
 		WatchWaitTime:      1 * time.Second,
 		HealthTTL:          1 * time.Second,
 		DeregisterInterval: 3 * time.Second,
+		NamePrefix:         "gas/names/",
+		NameLockDelay:      time.Second,
 	}
 }
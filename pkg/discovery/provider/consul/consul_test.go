@@ -35,7 +35,7 @@ func TestRegistrar(t *testing.T) {
 		time.Sleep(1 * time.Second)
 	}()
 
-	provider.Watch("test", onNodeChangeHandler)
+	id := provider.Watch("test", onNodeChangeHandler)
 
 	member := &iface.Member{Id: 1, Kind: "test", Address: "127.0.0.1", Port: 8080}
 	//  注册服务
@@ -62,7 +62,7 @@ func TestRegistrar(t *testing.T) {
 		t.Fatal(err)
 	}
 	time.Sleep(time.Second)
-	provider.Unwatch("test", onNodeChangeHandler)
+	provider.Unwatch("test", id)
 
 	//  注销服务
 	if err := provider.Deregister(member.GetID()); err != nil {
@@ -78,6 +78,31 @@ func TestRegistrar(t *testing.T) {
 	time.Sleep(2 * time.Second)
 }
 
+// TestNamespaceClaim 测试命名空间中的节点创建会话并占用名字
+func TestNamespaceClaim(t *testing.T) {
+	provider := New(DefaultConfig())
+	provider.SetNamespace("ns")
+	if err := provider.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	member := &iface.Member{Id: 1, Kind: "test", Address: "127.0.0.1", Port: 8080,
+		Meta: map[string]string{iface.MetaNamespace: "ns"}}
+	if err := provider.Register(member); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+
+	owner, err := provider.Claim("Boss", 1)
+	if err != nil || owner != 1 {
+		t.Fatalf("want owner 1, got %d (err=%v)", owner, err)
+	}
+	if owner, ok := provider.Lookup("Boss"); !ok || owner != 1 {
+		t.Fatalf("want owner 1, got %d", owner)
+	}
+}
+
 func TestRegistrarConcurrency(t *testing.T) {
 	provider := New(DefaultConfig())
 	provider.Run(context.Background())
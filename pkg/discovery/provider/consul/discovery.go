@@ -3,11 +3,13 @@ package consul
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/dzm2020/gas/pkg/discovery/iface"
 	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/event"
 	"github.com/dzm2020/gas/pkg/lib/grs"
 	"github.com/dzm2020/gas/pkg/lib/stopper"
 
@@ -26,6 +28,7 @@ type discovery struct {
 	waitIndex uint64
 	mu        sync.RWMutex
 	watchers  map[string]*Watcher
+	all       *event.Listener[*iface.Topology] // 所有类型节点变化的监听者
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -40,6 +43,7 @@ func newDiscovery(ctx context.Context, wg *sync.WaitGroup, client *api.Client, c
 		wg:        wg,
 		waitIndex: 0,
 		watchers:  make(map[string]*Watcher),
+		all:       event.NewListener[*iface.Topology](),
 	}
 	d.ctx, d.cancel = context.WithCancel(ctx)
 	return d
@@ -58,7 +62,7 @@ func (d *discovery) watch() {
 	defer func() {
 		d.shutdown()
 	}()
-	for !d.Stop() {
+	for !d.IsStop() {
 		select {
 		case <-d.ctx.Done():
 			return
@@ -93,11 +97,13 @@ func (d *discovery) fetch() error {
 	d.waitIndex = meta.LastIndex
 
 	// 创建新的服务 watcher  只增不减 确保listener不会被删除
+	// watcher 按节点类型创建，其它命名空间的服务由 watcher 过滤
+	prefix := scopedName(d.config.Namespace, "")
 	for name := range services {
-		if name == "consul" {
+		if name == "consul" || !strings.HasPrefix(name, prefix) {
 			continue
 		}
-		_ = d.getOrCreateWatcher(name)
+		_ = d.getOrCreateWatcher(strings.TrimPrefix(name, prefix))
 	}
 	return nil
 }
@@ -142,22 +148,29 @@ func (d *discovery) getOrCreateWatcher(name string) *Watcher {
 		return watcher
 	}
 
-	watcher = newWatcher(d.ctx, d.wg, d.client, d.config, name)
+	watcher = newWatcher(d.ctx, d.wg, d.client, d.config, name, d.all)
 	d.watchers[name] = watcher
 	return watcher
 }
 
-func (d *discovery) Watch(kind string, listener iface.ServiceChangeHandler) {
+func (d *discovery) Watch(kind string, listener iface.ServiceChangeHandler) uint64 {
+	if kind == iface.AllKinds {
+		return d.all.Add(listener)
+	}
 	watcher := d.getOrCreateWatcher(kind)
-	watcher.listener.Register(listener)
+	return watcher.listener.Add(listener)
 }
 
-func (d *discovery) Unwatch(kind string, listener iface.ServiceChangeHandler) {
+func (d *discovery) Unwatch(kind string, id uint64) {
+	if kind == iface.AllKinds {
+		d.all.Remove(id)
+		return
+	}
 	watcher := d.getWatcher(kind)
 	if watcher == nil {
 		return
 	}
-	watcher.listener.UnRegister(listener)
+	watcher.listener.Remove(id)
 }
 
 func (d *discovery) GetByKind(kind string) map[uint64]*iface.Member {
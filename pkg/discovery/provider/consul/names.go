@@ -0,0 +1,161 @@
+package consul
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+
+	"github.com/duke-git/lancet/v2/convertor"
+	"github.com/hashicorp/consul/api"
+	"go.uber.org/zap"
+)
+
+var _ iface.INameRegistry = (*nameRegistry)(nil)
+
+// nameRegistry 基于 Consul KV 和会话的全局名字注册表
+// 每个节点创建一个与其健康检查绑定的会话，名字通过会话加锁占用，健康检查失败时会话失效，名字随之删除
+type nameRegistry struct {
+	client *api.Client
+	config *Config
+
+	mu       sync.Mutex
+	sessions map[uint64]string
+}
+
+func newNameRegistry(client *api.Client, config *Config) *nameRegistry {
+	return &nameRegistry{
+		client:   client,
+		config:   config,
+		sessions: make(map[uint64]string),
+	}
+}
+
+// key 名字在 Consul KV 中的键，不同命名空间的名字互不冲突
+func (n *nameRegistry) key(name string) string {
+	return n.config.NamePrefix + scopedName(n.config.Namespace, name)
+}
+
+// sessionEntry 节点会话绑定 agent 和节点自身的健康检查，检查 ID 与注册时一样带命名空间
+func (n *nameRegistry) sessionEntry(memberId uint64) *api.SessionEntry {
+	return &api.SessionEntry{
+		Name:      "gas-names-" + serviceId(n.config.Namespace, memberId),
+		Checks:    []string{"serfHealth", checkId(n.config.Namespace, memberId)},
+		Behavior:  api.SessionBehaviorDelete,
+		LockDelay: n.config.NameLockDelay,
+	}
+}
+
+// session 获取节点的会话，不存在时创建，节点需要先注册到 Consul
+func (n *nameRegistry) session(memberId uint64) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if sid, ok := n.sessions[memberId]; ok {
+		return sid, nil
+	}
+	sid, _, err := n.client.Session().Create(n.sessionEntry(memberId), nil)
+	if err != nil {
+		return "", err
+	}
+	n.sessions[memberId] = sid
+	return sid, nil
+}
+
+func (n *nameRegistry) resetSession(memberId uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.sessions, memberId)
+}
+
+func (n *nameRegistry) Claim(name string, memberId uint64) (uint64, error) {
+	acquired, err := n.acquire(name, memberId)
+	if err != nil && strings.Contains(err.Error(), "invalid session") {
+		// 会话已因健康检查失败而失效，重建后重试一次
+		n.resetSession(memberId)
+		acquired, err = n.acquire(name, memberId)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if acquired {
+		return memberId, nil
+	}
+	owner, _ := n.Lookup(name)
+	return owner, iface.ErrNameClaimed
+}
+
+func (n *nameRegistry) acquire(name string, memberId uint64) (bool, error) {
+	sid, err := n.session(memberId)
+	if err != nil {
+		return false, err
+	}
+	pair := &api.KVPair{
+		Key:     n.key(name),
+		Value:   []byte(convertor.ToString(memberId)),
+		Session: sid,
+	}
+	acquired, _, err := n.client.KV().Acquire(pair, nil)
+	return acquired, err
+}
+
+func (n *nameRegistry) Release(name string, memberId uint64) error {
+	n.mu.Lock()
+	sid, ok := n.sessions[memberId]
+	n.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	kv := n.client.KV()
+	pair, _, err := kv.Get(n.key(name), nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil || pair.Session != sid {
+		return nil
+	}
+	_, _, err = kv.DeleteCAS(pair, nil)
+	return err
+}
+
+func (n *nameRegistry) Lookup(name string) (uint64, bool) {
+	pair, _, err := n.client.KV().Get(n.key(name), nil)
+	if err != nil {
+		glog.Error("consul查询名字失败", zap.String("name", name), zap.Error(err))
+		return 0, false
+	}
+	if pair == nil || pair.Session == "" {
+		return 0, false
+	}
+	owner, err := convertor.ToInt(string(pair.Value))
+	if err != nil {
+		return 0, false
+	}
+	return uint64(owner), true
+}
+
+// destroy 销毁节点会话，节点占用的名字随之删除
+func (n *nameRegistry) destroy(memberId uint64) {
+	n.mu.Lock()
+	sid, ok := n.sessions[memberId]
+	delete(n.sessions, memberId)
+	n.mu.Unlock()
+	if !ok {
+		return
+	}
+	if _, err := n.client.Session().Destroy(sid, nil); err != nil {
+		glog.Warn("consul销毁会话失败", zap.Uint64("memberId", memberId), zap.Error(err))
+	}
+}
+
+func (n *nameRegistry) shutdown() {
+	n.mu.Lock()
+	ids := make([]uint64, 0, len(n.sessions))
+	for id := range n.sessions {
+		ids = append(ids, id)
+	}
+	n.mu.Unlock()
+	for _, id := range ids {
+		n.destroy(id)
+	}
+}
@@ -0,0 +1,48 @@
+package consul
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// scopedName 命名空间不为空时加上命名空间前缀，用于 Consul 中的服务名和服务 ID
+// 多个集群共用同一个 Consul 时，不同命名空间中同类型、同 ID 的节点互不覆盖
+func scopedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// serviceId 节点在 Consul 中的服务 ID
+func serviceId(namespace string, memberId uint64) string {
+	return scopedName(namespace, strconv.FormatUint(memberId, 10))
+}
+
+// checkId 节点健康检查的 ID
+func checkId(namespace string, memberId uint64) string {
+	return "service:" + serviceId(namespace, memberId)
+}
+
+// toMember 将 Consul 服务转换为 namespace 中 kind 类型的节点，不属于该命名空间的服务返回 nil
+func toMember(namespace, kind string, service *api.AgentService) (*iface.Member, error) {
+	if service.Meta[iface.MetaNamespace] != namespace {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(service.ID, scopedName(namespace, "")), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &iface.Member{
+		Id:      id,
+		Kind:    kind,
+		Address: service.Address,
+		Port:    service.Port,
+		Tags:    service.Tags,
+		Meta:    service.Meta,
+	}, nil
+}
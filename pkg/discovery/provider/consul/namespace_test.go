@@ -0,0 +1,62 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// TestScopedIds 测试不同命名空间中同类型、同 ID 节点的服务名、服务 ID 和检查 ID 互不相同
+func TestScopedIds(t *testing.T) {
+	if serviceId("", 1) != "1" || scopedName("", "game") != "game" || checkId("", 1) != "service:1" {
+		t.Fatal("默认命名空间应保持原有的 ID 和服务名")
+	}
+	if serviceId("a", 1) == serviceId("b", 1) || checkId("a", 1) == checkId("b", 1) ||
+		scopedName("a", "game") == scopedName("b", "game") {
+		t.Fatal("不同命名空间的 ID 或服务名相同")
+	}
+}
+
+// TestToMember 测试只转换本命名空间的服务，节点 ID 和类型去掉命名空间前缀
+func TestToMember(t *testing.T) {
+	service := &api.AgentService{
+		ID:      serviceId("a", 7),
+		Service: scopedName("a", "game"),
+		Address: "127.0.0.1",
+		Port:    8080,
+		Meta:    map[string]string{iface.MetaNamespace: "a"},
+	}
+	member, err := toMember("a", "game", service)
+	if err != nil || member == nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if member.GetID() != 7 || member.GetKind() != "game" || member.Namespace() != "a" {
+		t.Fatalf("unexpected member %+v", member)
+	}
+
+	for _, namespace := range []string{"", "b"} {
+		if member, err = toMember(namespace, "game", service); err != nil || member != nil {
+			t.Fatalf("命名空间 %q 不应看到其它命名空间的节点: %+v %v", namespace, member, err)
+		}
+	}
+
+	service.ID = "a.x"
+	if _, err = toMember("a", "game", service); err == nil {
+		t.Fatal("want invalid id error")
+	}
+}
+
+// TestSessionEntry 测试名字会话绑定带命名空间的健康检查，名字的键按命名空间隔离
+func TestSessionEntry(t *testing.T) {
+	names := newNameRegistry(nil, &Config{Namespace: "a", NamePrefix: "gas/names/"})
+	entry := names.sessionEntry(7)
+	if entry.Checks[1] != checkId("a", 7) {
+		t.Fatalf("want check %s, got %v", checkId("a", 7), entry.Checks)
+	}
+	other := newNameRegistry(nil, &Config{Namespace: "b", NamePrefix: "gas/names/"})
+	if names.key("Boss") == other.key("Boss") {
+		t.Fatal("不同命名空间的名字键相同")
+	}
+}
@@ -34,7 +34,11 @@ func init() {
 	})
 }
 
-var _ iface.IDiscovery = (*Provider)(nil)
+var (
+	_ iface.IDiscovery    = (*Provider)(nil)
+	_ iface.INameRegistry = (*Provider)(nil)
+	_ iface.INamespaced   = (*Provider)(nil)
+)
 
 func New(config *Config) *Provider {
 	provider := &Provider{
@@ -51,14 +55,20 @@ type Provider struct {
 
 	config *Config
 
-	*discovery // 集群监控
-	*registrar // 注册
+	*discovery    // 集群监控
+	*registrar    // 注册
+	*nameRegistry // 全局名字
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
+// SetNamespace 设置监听的命名空间，需要在 Run 之前调用
+func (c *Provider) SetNamespace(namespace string) {
+	c.config.Namespace = namespace
+}
+
 func (c *Provider) Run(ctx context.Context) error {
 	if err := c.connect(); err != nil {
 		return err
@@ -70,6 +80,8 @@ func (c *Provider) Run(ctx context.Context) error {
 	c.registrar = newRegistrar(c.ctx, &c.wg, c.client, c.config)
 	c.registrar.run()
 
+	c.nameRegistry = newNameRegistry(c.client, c.config)
+
 	return nil
 }
 
@@ -84,11 +96,19 @@ func (c *Provider) connect() error {
 	return nil
 }
 
+// Deregister 注销节点，同时释放节点占用的名字
+func (c *Provider) Deregister(memberId uint64) error {
+	c.nameRegistry.destroy(memberId)
+	return c.registrar.Deregister(memberId)
+}
+
 func (c *Provider) Shutdown(ctx context.Context) error {
 	if !c.Stop() {
 		return nil
 	}
 
+	// 销毁会话，本节点占用的名字立即释放
+	c.nameRegistry.shutdown()
 	c.cancel()
 	c.registrar.shutdown()
 	c.discovery.shutdown()
@@ -3,7 +3,6 @@ package consul
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/dzm2020/gas/pkg/discovery/iface"
 	"github.com/dzm2020/gas/pkg/lib/stopper"
@@ -32,7 +31,7 @@ func newHealthKeeper(ctx context.Context, wg *sync.WaitGroup,
 		client:  client,
 		config:  config,
 		Member:  clonedMember,
-		checkId: fmt.Sprintf("service:%d", member.GetID()),
+		checkId: checkId(member.Namespace(), member.GetID()),
 	}
 	keeper.ctx, keeper.cancel = context.WithCancel(ctx)
 	return keeper
@@ -77,8 +76,8 @@ func (keeper *healthKeeper) Update(member *iface.Member) error {
 func (keeper *healthKeeper) register() error {
 	config := keeper.config
 	registration := &api.AgentServiceRegistration{
-		ID:      convertor.ToString(keeper.GetID()),
-		Name:    keeper.GetKind(),
+		ID:      serviceId(keeper.Namespace(), keeper.GetID()),
+		Name:    scopedName(keeper.Namespace(), keeper.GetKind()),
 		Address: keeper.GetAddress(),
 		Port:    keeper.GetPort(),
 		Tags:    keeper.GetTags(),
@@ -120,7 +119,7 @@ func (keeper *healthKeeper) updateTTL() {
 }
 
 func (keeper *healthKeeper) deregister() error {
-	err := keeper.client.Agent().ServiceDeregister(convertor.ToString(keeper.GetID()))
+	err := keeper.client.Agent().ServiceDeregister(serviceId(keeper.Namespace(), keeper.GetID()))
 	if err != nil {
 		glog.Error("注销服务失败", zap.Uint64("memberId", keeper.GetID()), zap.Error(err))
 		return err
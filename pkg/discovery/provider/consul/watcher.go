@@ -13,18 +13,18 @@ import (
 	"github.com/dzm2020/gas/pkg/lib/grs"
 	"github.com/dzm2020/gas/pkg/lib/stopper"
 
-	"github.com/duke-git/lancet/v2/convertor"
 	"github.com/hashicorp/consul/api"
 	"go.uber.org/zap"
 )
 
-func newWatcher(ctx context.Context, wg *sync.WaitGroup, client *api.Client, config *Config, kind string) *Watcher {
+func newWatcher(ctx context.Context, wg *sync.WaitGroup, client *api.Client, config *Config, kind string, all *event.Listener[*iface.Topology]) *Watcher {
 	watcher := &Watcher{
 		client:    client,
 		config:    config,
 		wg:        wg,
 		waitIndex: 0,
 		listener:  event.NewListener[*iface.Topology](),
+		all:       all,
 		kind:      kind,
 	}
 	watcher.list.Store(iface.NewMemberList(nil))
@@ -45,6 +45,7 @@ type Watcher struct {
 	config *Config
 
 	listener  *event.Listener[*iface.Topology]
+	all       *event.Listener[*iface.Topology]
 	waitIndex uint64
 	list      atomic.Pointer[iface.MemberList] // 并发读写
 	kind      string
@@ -80,7 +81,7 @@ func (w *Watcher) fetch() error {
 		WaitTime:  w.config.WatchWaitTime,
 	}
 	options = options.WithContext(w.ctx)
-	services, meta, err := w.client.Health().Service(w.kind, "", true, options)
+	services, meta, err := w.client.Health().Service(scopedName(w.config.Namespace, w.kind), "", true, options)
 	if err != nil {
 		if !errors.Is(err, context.Canceled) {
 			glog.Error("Consul获取服务失败", zap.String("service", w.kind), zap.Error(err))
@@ -92,18 +93,13 @@ func (w *Watcher) fetch() error {
 
 	nodeDict := make(map[uint64]*iface.Member, len(services))
 	for _, s := range services {
-		id, wrong := convertor.ToInt(s.Service.ID)
+		member, wrong := toMember(w.config.Namespace, w.kind, s.Service)
 		if wrong != nil {
 			glog.Warn("Consul服务ID无效，跳过该服务", zap.String("service", w.kind), zap.Error(wrong))
 			continue
 		}
-		nodeDict[uint64(id)] = &iface.Member{
-			Id:      uint64(id),
-			Kind:    s.Service.Service,
-			Address: s.Service.Address,
-			Port:    s.Service.Port,
-			Tags:    s.Service.Tags,
-			Meta:    s.Service.Meta,
+		if member != nil {
+			nodeDict[member.GetID()] = member
 		}
 	}
 
@@ -118,7 +114,7 @@ func (w *Watcher) fetch() error {
 
 	if topology.IsChange() {
 		w.listener.Notify(topology)
-
+		w.all.Notify(topology)
 	}
 	return nil
 }
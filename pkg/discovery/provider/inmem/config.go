@@ -0,0 +1,15 @@
+package inmem
+
+import "time"
+
+type Config struct {
+	Registry string        `json:"registry" mapstruct:"registry"` // 注册表名称，同一进程内使用相同名称的节点互相可见
+	TTL      time.Duration `json:"ttl" mapstruct:"ttl"`           // 节点注册的有效期，超过有效期未续约的节点被移除，0 表示不过期
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Registry: "default",
+		TTL:      3 * time.Second,
+	}
+}
@@ -0,0 +1,200 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+)
+
+func newProvider(t *testing.T, registry *Registry, ttl time.Duration) *Provider {
+	provider := NewWithRegistry(registry, &Config{TTL: ttl})
+	if err := provider.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+	return provider
+}
+
+func waitTopology(t *testing.T, ch <-chan *iface.Topology) *iface.Topology {
+	t.Helper()
+	select {
+	case topology := <-ch:
+		return topology
+	case <-time.After(time.Second):
+		t.Fatal("未收到拓扑变化")
+		return nil
+	}
+}
+
+// TestWatch 测试注册、更新、注销的拓扑通知
+func TestWatch(t *testing.T) {
+	registry := NewRegistry()
+	a, b := newProvider(t, registry, 0), newProvider(t, registry, 0)
+
+	ch := make(chan *iface.Topology, 10)
+	a.Watch("game", func(topology *iface.Topology) { ch <- topology })
+
+	member := &iface.Member{Id: 1, Kind: "game", Address: "127.0.0.1", Port: 9001}
+	if err := b.Register(member); err != nil {
+		t.Fatal(err)
+	}
+	if topology := waitTopology(t, ch); len(topology.Joined) != 1 || topology.Joined[0].GetID() != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+	if got := a.GetById(1); got == nil || got.GetPort() != 9001 {
+		t.Fatalf("GetById: %+v", got)
+	}
+
+	updated := *member
+	updated.Meta = map[string]string{"version": "1.1.0"}
+	if err := b.Update(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if topology := waitTopology(t, ch); len(topology.Update) != 1 {
+		t.Fatalf("want update, got %+v", topology)
+	}
+	if err := a.Update(&updated); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("非注册者更新应返回 ErrNotRegistered，实际 %v", err)
+	}
+
+	_ = b.Deregister(1)
+	if topology := waitTopology(t, ch); len(topology.Left) != 1 {
+		t.Fatalf("want left, got %+v", topology)
+	}
+	if len(a.GetByKind("game")) != 0 {
+		t.Fatal("注销后仍能查询到节点")
+	}
+}
+
+// TestWatchExisting 测试监听时已有的节点以 Joined 通知
+func TestWatchExisting(t *testing.T) {
+	registry := NewRegistry()
+	a, b := newProvider(t, registry, 0), newProvider(t, registry, 0)
+	_ = b.Register(&iface.Member{Id: 1, Kind: "gate"})
+
+	ch := make(chan *iface.Topology, 10)
+	a.Watch(iface.AllKinds, func(topology *iface.Topology) { ch <- topology })
+	if topology := waitTopology(t, ch); len(topology.Joined) != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+}
+
+// TestWatchExistingEachHandler 测试之后注册的处理函数同样收到已有节点的 Joined，且只收到一次
+func TestWatchExistingEachHandler(t *testing.T) {
+	registry := NewRegistry()
+	a, b := newProvider(t, registry, 0), newProvider(t, registry, 0)
+	_ = b.Register(&iface.Member{Id: 1, Kind: "game"})
+	_ = b.Register(&iface.Member{Id: 2, Kind: "gate"})
+
+	first := make(chan *iface.Topology, 10)
+	a.Watch("game", func(topology *iface.Topology) { first <- topology })
+	if topology := waitTopology(t, first); len(topology.Joined) != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+
+	for _, kind := range []string{"game", iface.AllKinds} {
+		ch := make(chan *iface.Topology, 10)
+		a.Watch(kind, func(topology *iface.Topology) { ch <- topology })
+		want := 1
+		if kind == iface.AllKinds {
+			want = 2
+		}
+		joined := 0
+		deadline := time.After(200 * time.Millisecond)
+	loop:
+		for {
+			select {
+			case topology := <-ch:
+				joined += len(topology.Joined)
+			case <-deadline:
+				break loop
+			}
+		}
+		if joined != want {
+			t.Fatalf("kind=%s: want %d joined, got %d", kind, want, joined)
+		}
+	}
+	select {
+	case topology := <-first:
+		t.Fatalf("已有的处理函数收到重复通知: %+v", topology)
+	default:
+	}
+}
+
+// topologyRecorder 以方法值注册的监听者
+type topologyRecorder struct {
+	ch chan *iface.Topology
+}
+
+func (r *topologyRecorder) onTopology(topology *iface.Topology) {
+	r.ch <- topology
+}
+
+// TestWatchHandle 测试同一方法不同实例的方法值分别注册，按句柄注销时只移除对应的实例
+func TestWatchHandle(t *testing.T) {
+	registry := NewRegistry()
+	a, b := newProvider(t, registry, 0), newProvider(t, registry, 0)
+
+	first := &topologyRecorder{ch: make(chan *iface.Topology, 10)}
+	second := &topologyRecorder{ch: make(chan *iface.Topology, 10)}
+	id := a.Watch("game", first.onTopology)
+	a.Watch("game", second.onTopology)
+	a.Unwatch("game", id)
+
+	_ = b.Register(&iface.Member{Id: 1, Kind: "game"})
+	if topology := waitTopology(t, second.ch); len(topology.Joined) != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+	select {
+	case topology := <-first.ch:
+		t.Fatalf("已注销的监听者收到通知: %+v", topology)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestExpire 测试未续约的节点过期移除，续约的节点保留
+func TestExpire(t *testing.T) {
+	registry := NewRegistry()
+	a := newProvider(t, registry, 60*time.Millisecond)
+	_ = a.Register(&iface.Member{Id: 1, Kind: "game"})
+
+	ch := make(chan *iface.Topology, 10)
+	a.Watch("game", func(topology *iface.Topology) { ch <- topology })
+	waitTopology(t, ch)
+
+	// 模拟崩溃的节点：注册后不再续约
+	registry.put(&iface.Member{Id: 2, Kind: "game"}, 60*time.Millisecond)
+	waitTopology(t, ch)
+	if topology := waitTopology(t, ch); len(topology.Left) != 1 || topology.Left[0].GetID() != 2 {
+		t.Fatalf("want left 2, got %+v", topology)
+	}
+	if a.GetById(1) == nil {
+		t.Fatal("续约的节点不应过期")
+	}
+}
+
+// TestNames 测试全局名字的占用、冲突和节点注销时释放
+func TestNames(t *testing.T) {
+	registry := NewRegistry()
+	a := newProvider(t, registry, 0)
+	if _, err := a.Claim("world", 1); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("未注册节点占用名字应返回 ErrNotRegistered，实际 %v", err)
+	}
+	_ = a.Register(&iface.Member{Id: 1, Kind: "game"})
+	_ = a.Register(&iface.Member{Id: 2, Kind: "game"})
+	if _, err := a.Claim("world", 1); err != nil {
+		t.Fatal(err)
+	}
+	if owner, err := a.Claim("world", 2); !errors.Is(err, iface.ErrNameClaimed) || owner != 1 {
+		t.Fatalf("want ErrNameClaimed by 1, got %d %v", owner, err)
+	}
+	_ = a.Deregister(1)
+	if _, ok := a.Lookup("world"); ok {
+		t.Fatal("节点注销后名字应释放")
+	}
+}
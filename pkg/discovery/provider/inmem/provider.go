@@ -0,0 +1,307 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	discoveryApi "github.com/dzm2020/gas/pkg/discovery"
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/event"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrNotRegistered = errors.New("节点未注册")
+	ErrStopped       = errors.New("服务发现已关闭")
+)
+
+func init() {
+	_ = discoveryApi.GetFactoryMgr().Register("inmem", func(args ...any) (iface.IDiscovery, error) {
+		cfg := DefaultConfig()
+		if len(args) > 0 && args[0] != nil {
+			config, ok := args[0].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("inmem provider: config must be map[string]interface{}, got %T", args[0])
+			}
+			vp := viper.New()
+			vp.Set("", config)
+			if err := vp.UnmarshalKey("", cfg); err != nil {
+				return nil, fmt.Errorf("inmem provider: failed to unmarshal config: %w", err)
+			}
+		}
+		return New(cfg), nil
+	})
+}
+
+var (
+	_ iface.IDiscovery    = (*Provider)(nil)
+	_ iface.INameRegistry = (*Provider)(nil)
+)
+
+// New 创建连接到共享注册表 config.Registry 的提供者
+func New(config *Config) *Provider {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return NewWithRegistry(getRegistry(config.Registry), config)
+}
+
+// NewWithRegistry 创建使用指定注册表的提供者
+func NewWithRegistry(registry *Registry, config *Config) *Provider {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	provider := &Provider{
+		registry: registry,
+		config:   config,
+		own:      make(map[uint64]struct{}),
+		lists:    make(map[string]*iface.MemberList),
+		watchers: make(map[string]*event.Listener[*iface.Topology]),
+		all:      event.NewListener[*iface.Topology](),
+	}
+	provider.ctx, provider.cancel = context.WithCancel(context.Background())
+	return provider
+}
+
+// Provider 基于内存注册表的服务发现，同一进程内的多个节点共享注册表，用于单进程集群和测试
+// 本提供者注册的节点定期续约，关闭时注销；其他节点超过有效期未续约时被移除
+type Provider struct {
+	stopper.Stopper
+
+	registry *Registry
+
+	config *Config
+
+	mu       sync.Mutex
+	own      map[uint64]struct{}                         // 本提供者注册的节点
+	lists    map[string]*iface.MemberList                // 各类型节点上次通知时的列表
+	watchers map[string]*event.Listener[*iface.Topology] // 各类型节点变化的监听者
+	all      *event.Listener[*iface.Topology]            // 所有类型节点变化的监听者
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (p *Provider) Run(ctx context.Context) error {
+	p.registry.attach(p)
+	if p.config.TTL <= 0 {
+		return nil
+	}
+	p.wg.Add(1)
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		p.keepalive()
+	})
+	return nil
+}
+
+// keepalive 按有效期的三分之一续约本提供者注册的节点，并移除注册表中过期的节点
+func (p *Provider) keepalive() {
+	ticker := time.NewTicker(p.config.TTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			for id := range p.own {
+				p.registry.renew(id, p.config.TTL)
+			}
+			p.mu.Unlock()
+			p.registry.expire(now)
+		}
+	}
+}
+
+func (p *Provider) Register(member *iface.Member) error {
+	if p.IsStop() {
+		return ErrStopped
+	}
+	p.mu.Lock()
+	p.own[member.GetID()] = struct{}{}
+	p.mu.Unlock()
+	p.registry.put(member, p.config.TTL)
+	return nil
+}
+
+func (p *Provider) Update(member *iface.Member) error {
+	p.mu.Lock()
+	_, ok := p.own[member.GetID()]
+	p.mu.Unlock()
+	if !ok {
+		return ErrNotRegistered
+	}
+	p.registry.put(member, p.config.TTL)
+	return nil
+}
+
+// Deregister 注销节点，同时释放节点占用的名字
+func (p *Provider) Deregister(memberId uint64) error {
+	p.mu.Lock()
+	delete(p.own, memberId)
+	p.mu.Unlock()
+	p.registry.remove(memberId)
+	return nil
+}
+
+func (p *Provider) GetById(memberId uint64) *iface.Member {
+	return p.registry.get(memberId)
+}
+
+func (p *Provider) GetByKind(kind string) map[uint64]*iface.Member {
+	return p.registry.byKind(kind)
+}
+
+func (p *Provider) GetAll() map[uint64]*iface.Member {
+	return p.registry.byKind("")
+}
+
+// Watch 监听 kind 类型节点的变化，已有的节点以 Joined 通知给新注册的处理函数
+func (p *Provider) Watch(kind string, handler iface.ServiceChangeHandler) uint64 {
+	g := &greeter{kind: kind, handler: handler}
+	var id uint64
+	if kind == iface.AllKinds {
+		id = p.all.Add(g.notify)
+	} else {
+		id = p.watcher(kind).Add(g.notify)
+	}
+	p.registry.greet(p, g)
+	return id
+}
+
+func (p *Provider) Unwatch(kind string, id uint64) {
+	if kind == iface.AllKinds {
+		p.all.Remove(id)
+		return
+	}
+	p.watcher(kind).Remove(id)
+}
+
+func (p *Provider) watcher(kind string) *event.Listener[*iface.Topology] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	listener, ok := p.watchers[kind]
+	if !ok {
+		listener = event.NewListener[*iface.Topology]()
+		p.watchers[kind] = listener
+	}
+	return listener
+}
+
+// kinds 注册表中已有节点的类型
+func (p *Provider) kinds() map[string]struct{} {
+	kinds := make(map[string]struct{})
+	for _, member := range p.registry.byKind("") {
+		kinds[member.GetKind()] = struct{}{}
+	}
+	return kinds
+}
+
+// greeter Watch 注册的处理函数，发出已有节点之前忽略其它通知，保证先收到已有节点再收到之后的变化
+type greeter struct {
+	kind    string
+	handler iface.ServiceChangeHandler
+	ready   atomic.Bool
+}
+
+func (g *greeter) notify(topology *iface.Topology) {
+	if g.ready.Load() {
+		g.handler(topology)
+	}
+}
+
+// greet 在通知协程中执行：先向处理函数发出上次通知时的节点，再对比注册表通知之后的变化
+func (p *Provider) greet(g *greeter) {
+	if p.IsStop() {
+		return
+	}
+	kinds := make(map[string]struct{})
+	var members []*iface.Member
+	p.mu.Lock()
+	for kind, list := range p.lists {
+		if g.kind != iface.AllKinds && kind != g.kind {
+			continue
+		}
+		kinds[kind] = struct{}{}
+		for _, member := range list.Dict {
+			members = append(members, member)
+		}
+	}
+	p.mu.Unlock()
+
+	if len(members) > 0 {
+		g.handler(&iface.Topology{All: members, Joined: members})
+	}
+	g.ready.Store(true)
+
+	if g.kind != iface.AllKinds {
+		kinds[g.kind] = struct{}{}
+	} else {
+		for kind := range p.kinds() {
+			kinds[kind] = struct{}{}
+		}
+	}
+	for kind := range kinds {
+		p.refresh(kind)
+	}
+}
+
+// refresh 对比 kind 类型节点与上次通知时的列表，有变化时通知监听者
+func (p *Provider) refresh(kind string) {
+	if p.IsStop() {
+		return
+	}
+	list := iface.NewMemberList(p.registry.byKind(kind))
+	p.mu.Lock()
+	topology := list.UpdateTopology(p.lists[kind])
+	p.lists[kind] = list
+	listener := p.watchers[kind]
+	p.mu.Unlock()
+
+	if !topology.IsChange() {
+		return
+	}
+	if listener != nil {
+		listener.Notify(topology)
+	}
+	p.all.Notify(topology)
+}
+
+func (p *Provider) Claim(name string, memberId uint64) (uint64, error) {
+	return p.registry.Claim(name, memberId)
+}
+
+func (p *Provider) Release(name string, memberId uint64) error {
+	return p.registry.Release(name, memberId)
+}
+
+func (p *Provider) Lookup(name string) (uint64, bool) {
+	return p.registry.Lookup(name)
+}
+
+// Shutdown 停止续约并注销本提供者注册的节点
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if !p.Stop() {
+		return nil
+	}
+	p.cancel()
+	grs.WaitWithContext(ctx, &p.wg)
+	p.mu.Lock()
+	own := p.own
+	p.own = make(map[uint64]struct{})
+	p.mu.Unlock()
+	for id := range own {
+		p.registry.remove(id)
+	}
+	p.registry.detach(p)
+	return nil
+}
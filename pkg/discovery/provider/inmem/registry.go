@@ -0,0 +1,257 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+)
+
+var (
+	registriesMu sync.Mutex
+	registries   = make(map[string]*Registry)
+)
+
+// getRegistry 获取指定名称的共享注册表，不存在时创建
+func getRegistry(name string) *Registry {
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	registry, ok := registries[name]
+	if !ok {
+		registry = NewRegistry()
+		registries[name] = registry
+	}
+	return registry
+}
+
+type entry struct {
+	member   *iface.Member
+	expireAt time.Time // 零值表示不过期
+}
+
+// change 待通知的变化，target 为空时通知所有连接到注册表的提供者；greeter 不为空时向新的处理函数发出已有节点
+type change struct {
+	kind    string
+	target  *Provider
+	greeter *greeter
+}
+
+// Registry 内存中的节点注册表和全局名字表
+// 节点变化按类型异步通知给连接的提供者，由提供者计算各自的拓扑变化，与 Consul 的 watcher 行为一致
+type Registry struct {
+	mu        sync.RWMutex
+	members   map[uint64]*entry
+	names     map[string]uint64
+	providers map[*Provider]struct{}
+
+	pending     []change
+	dispatching bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		members:   make(map[uint64]*entry),
+		names:     make(map[string]uint64),
+		providers: make(map[*Provider]struct{}),
+	}
+}
+
+// Reset 以 members 替换注册表中的全部节点
+func (r *Registry) Reset(members []*iface.Member) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds := make(map[string]struct{})
+	for id, old := range r.members {
+		kinds[old.member.GetKind()] = struct{}{}
+		delete(r.members, id)
+	}
+	for _, member := range members {
+		r.members[member.GetID()] = &entry{member: member.Clone()}
+		kinds[member.GetKind()] = struct{}{}
+	}
+	for name, owner := range r.names {
+		if _, ok := r.members[owner]; !ok {
+			delete(r.names, name)
+		}
+	}
+	for kind := range kinds {
+		r.enqueue(change{kind: kind})
+	}
+}
+
+// put 注册或更新节点，ttl 为 0 时不过期
+func (r *Registry) put(member *iface.Member, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := &entry{member: member.Clone()}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+	if old, ok := r.members[member.GetID()]; ok && old.member.GetKind() != member.GetKind() {
+		r.enqueue(change{kind: old.member.GetKind()})
+	}
+	r.members[member.GetID()] = e
+	r.enqueue(change{kind: member.GetKind()})
+}
+
+// renew 续约节点，节点已被移除时返回 false
+func (r *Registry) renew(memberId uint64, ttl time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.members[memberId]
+	if !ok {
+		return false
+	}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+	return true
+}
+
+func (r *Registry) remove(memberId uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(memberId)
+}
+
+// removeLocked 移除节点并释放其占用的名字
+func (r *Registry) removeLocked(memberId uint64) {
+	e, ok := r.members[memberId]
+	if !ok {
+		return
+	}
+	delete(r.members, memberId)
+	for name, owner := range r.names {
+		if owner == memberId {
+			delete(r.names, name)
+		}
+	}
+	r.enqueue(change{kind: e.member.GetKind()})
+}
+
+// expire 移除超过有效期的节点
+func (r *Registry) expire(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, e := range r.members {
+		if !e.expireAt.IsZero() && now.After(e.expireAt) {
+			r.removeLocked(id)
+		}
+	}
+}
+
+func (r *Registry) get(memberId uint64) *iface.Member {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if e, ok := r.members[memberId]; ok {
+		return e.member
+	}
+	return nil
+}
+
+// byKind kind 为空时返回所有节点
+func (r *Registry) byKind(kind string) map[uint64]*iface.Member {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[uint64]*iface.Member)
+	for id, e := range r.members {
+		if kind == "" || e.member.GetKind() == kind {
+			result[id] = e.member
+		}
+	}
+	return result
+}
+
+func (r *Registry) attach(p *Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p] = struct{}{}
+}
+
+func (r *Registry) detach(p *Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.providers, p)
+}
+
+// greet 在通知协程中向 Watch 新注册的处理函数发出已有节点的 Joined，与其它变化保持顺序
+func (r *Registry) greet(p *Provider, g *greeter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enqueue(change{target: p, greeter: g})
+}
+
+// enqueue 记录变化，由单独的协程按顺序通知，处理器中可以安全地调用注册表
+func (r *Registry) enqueue(c change) {
+	r.pending = append(r.pending, c)
+	if r.dispatching {
+		return
+	}
+	r.dispatching = true
+	grs.Go(func(ctx context.Context) {
+		r.dispatch()
+	})
+}
+
+func (r *Registry) dispatch() {
+	for {
+		r.mu.Lock()
+		if len(r.pending) == 0 {
+			r.dispatching = false
+			r.mu.Unlock()
+			return
+		}
+		pending := r.pending
+		r.pending = nil
+		providers := make([]*Provider, 0, len(r.providers))
+		for p := range r.providers {
+			providers = append(providers, p)
+		}
+		r.mu.Unlock()
+
+		for _, c := range pending {
+			if c.greeter != nil {
+				c.target.greet(c.greeter)
+				continue
+			}
+			if c.target != nil {
+				c.target.refresh(c.kind)
+				continue
+			}
+			for _, p := range providers {
+				p.refresh(c.kind)
+			}
+		}
+	}
+}
+
+func (r *Registry) Claim(name string, memberId uint64) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if owner, ok := r.names[name]; ok && owner != memberId {
+		return owner, iface.ErrNameClaimed
+	}
+	if _, ok := r.members[memberId]; !ok {
+		return 0, ErrNotRegistered
+	}
+	r.names[name] = memberId
+	return memberId, nil
+}
+
+func (r *Registry) Release(name string, memberId uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if owner, ok := r.names[name]; ok && owner == memberId {
+		delete(r.names, name)
+	}
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (uint64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	owner, ok := r.names[name]
+	return owner, ok
+}
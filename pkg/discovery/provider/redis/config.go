@@ -0,0 +1,23 @@
+package redis
+
+import "time"
+
+type Config struct {
+	Address           string        `json:"address" mapstruct:"address"`
+	Password          string        `json:"password" mapstruct:"password"`
+	DB                int           `json:"db" mapstruct:"db"`
+	Prefix            string        `json:"prefix" mapstruct:"prefix"`                       // 键和通知频道的前缀，多个集群共用 Redis 时用于隔离
+	TTL               time.Duration `json:"ttl" mapstruct:"ttl"`                             // 节点键的有效期，超过有效期未续约的节点被移除
+	HeartbeatInterval time.Duration `json:"heartbeatInterval" mapstruct:"heartbeatInterval"` // 续约间隔，为 0 时使用有效期的三分之一
+	PollInterval      time.Duration `json:"pollInterval" mapstruct:"pollInterval"`           // 全量拉取节点列表的间隔，用于发现过期的节点和重连期间错过的通知
+	KeyspaceEvents    bool          `json:"keyspaceEvents" mapstruct:"keyspaceEvents"`       // 订阅键空间通知，节点过期后立即感知，需要服务器开启 notify-keyspace-events（至少 Kgx）
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Address:      "127.0.0.1:6379",
+		Prefix:       "gas",
+		TTL:          10 * time.Second,
+		PollInterval: 5 * time.Second,
+	}
+}
@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// 名字键的值为占用者的节点ID，与节点键使用相同的有效期并一起续约；
+// 占用者的节点键不存在时（节点已过期或注销），名字视为未被占用
+
+// claimScript KEYS[1] 名字键，KEYS[2] 申请者的节点键；ARGV[1] 申请者ID，ARGV[2] 节点键前缀，ARGV[3] 有效期（毫秒）
+// 申请者未注册时返回空字符串，否则返回占用者ID
+var claimScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return ''
+end
+local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] and redis.call('EXISTS', ARGV[2] .. owner) == 1 then
+	return owner
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+return ARGV[1]
+`)
+
+// releaseScript 只有占用者可以删除名字键
+var releaseScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// renewScript 名字仍被占用者持有时续约，返回 0 表示已被其他节点占用
+var renewScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// lookupScript 返回占用者ID，占用者已失效时返回 nil
+var lookupScript = goredis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if not owner or redis.call('EXISTS', ARGV[1] .. owner) == 0 then
+	return false
+end
+return owner
+`)
+
+func (p *Provider) Claim(name string, memberId uint64) (uint64, error) {
+	id := strconv.FormatUint(memberId, 10)
+	result, err := claimScript.Run(p.ctx, p.client,
+		[]string{p.nameKey(name), p.memberKey(memberId)},
+		id, p.memberPrefix(), p.config.TTL.Milliseconds()).Text()
+	if err != nil {
+		return 0, err
+	}
+	if result == "" {
+		return 0, ErrNotRegistered
+	}
+	owner, err := strconv.ParseUint(result, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if owner != memberId {
+		return owner, iface.ErrNameClaimed
+	}
+	p.mu.Lock()
+	p.names[name] = memberId
+	p.mu.Unlock()
+	return memberId, nil
+}
+
+func (p *Provider) Release(name string, memberId uint64) error {
+	p.mu.Lock()
+	if p.names[name] == memberId {
+		delete(p.names, name)
+	}
+	p.mu.Unlock()
+	return p.release(p.ctx, name, memberId)
+}
+
+func (p *Provider) release(ctx context.Context, name string, memberId uint64) error {
+	return releaseScript.Run(ctx, p.client, []string{p.nameKey(name)}, strconv.FormatUint(memberId, 10)).Err()
+}
+
+// releaseAll 释放节点占用的所有名字
+func (p *Provider) releaseAll(ctx context.Context, memberId uint64) {
+	p.mu.Lock()
+	var names []string
+	for name, owner := range p.names {
+		if owner == memberId {
+			names = append(names, name)
+			delete(p.names, name)
+		}
+	}
+	p.mu.Unlock()
+	for _, name := range names {
+		if err := p.release(ctx, name, memberId); err != nil {
+			glog.Warn("redis服务发现: 释放名字失败", zap.String("name", name), zap.Uint64("memberId", memberId), zap.Error(err))
+		}
+	}
+}
+
+// renewNames 续约本节点占用的名字，已被其他节点占用的名字不再续约
+func (p *Provider) renewNames() {
+	p.mu.Lock()
+	names := make(map[string]uint64, len(p.names))
+	for name, owner := range p.names {
+		names[name] = owner
+	}
+	p.mu.Unlock()
+	for name, owner := range names {
+		renewed, err := renewScript.Run(p.ctx, p.client, []string{p.nameKey(name)},
+			strconv.FormatUint(owner, 10), p.config.TTL.Milliseconds()).Int()
+		if err != nil {
+			if p.ctx.Err() == nil {
+				glog.Warn("redis服务发现: 名字续约失败", zap.String("name", name), zap.Error(err))
+			}
+			continue
+		}
+		if renewed == 0 {
+			glog.Warn("redis服务发现: 名字已被其他节点占用", zap.String("name", name), zap.Uint64("memberId", owner))
+			p.mu.Lock()
+			if p.names[name] == owner {
+				delete(p.names, name)
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *Provider) Lookup(name string) (uint64, bool) {
+	result, err := lookupScript.Run(p.ctx, p.client, []string{p.nameKey(name)}, p.memberPrefix()).Text()
+	if err != nil {
+		if !errors.Is(err, goredis.Nil) {
+			glog.Error("redis查询名字失败", zap.String("name", name), zap.Error(err))
+		}
+		return 0, false
+	}
+	owner, err := strconv.ParseUint(result, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return owner, true
+}
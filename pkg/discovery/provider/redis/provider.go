@@ -0,0 +1,382 @@
+// Package redis 基于 Redis 的服务发现，用于已经部署了 Redis、不想再引入 Consul 的小规模环境
+//
+// 每个节点保存为带有效期的键，由注册它的进程定期续约，进程崩溃后键自动过期。节点注册、更新和注销时在通知频道上广播，
+// 其他进程收到通知后重新拉取节点列表；同时定期全量拉取，发现过期的节点和断线期间错过的通知。
+// 开启 KeyspaceEvents 后，键过期也会立即通知。
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	discoveryApi "github.com/dzm2020/gas/pkg/discovery"
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/discovery/provider/inmem"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotRegistered = errors.New("redis: 节点未注册")
+	ErrStopped       = errors.New("redis: 服务发现已关闭")
+)
+
+func init() {
+	_ = discoveryApi.GetFactoryMgr().Register("redis", func(args ...any) (iface.IDiscovery, error) {
+		cfg := DefaultConfig()
+		if len(args) > 0 && args[0] != nil {
+			config, ok := args[0].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("redis provider: config must be map[string]interface{}, got %T", args[0])
+			}
+			vp := viper.New()
+			vp.Set("", config)
+			if err := vp.UnmarshalKey("", cfg); err != nil {
+				return nil, fmt.Errorf("redis provider: failed to unmarshal config: %w", err)
+			}
+		}
+		return New(cfg), nil
+	})
+}
+
+var (
+	_ iface.IDiscovery    = (*Provider)(nil)
+	_ iface.INameRegistry = (*Provider)(nil)
+)
+
+func New(config *Config) *Provider {
+	def := DefaultConfig()
+	if config == nil {
+		config = def
+	}
+	if config.TTL <= 0 {
+		config.TTL = def.TTL
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = config.TTL / 3
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = def.PollInterval
+	}
+	provider := &Provider{
+		config: config,
+		client: goredis.NewClient(&goredis.Options{
+			Addr:     config.Address,
+			Password: config.Password,
+			DB:       config.DB,
+		}),
+		registry: inmem.NewRegistry(),
+		local:    make(map[uint64]*iface.Member),
+		names:    make(map[string]uint64),
+		trigger:  make(chan struct{}, 1),
+	}
+	provider.members = inmem.NewWithRegistry(provider.registry, &inmem.Config{})
+	provider.ctx, provider.cancel = context.WithCancel(context.Background())
+	return provider
+}
+
+type Provider struct {
+	stopper.Stopper
+
+	config   *Config
+	client   *goredis.Client
+	registry *inmem.Registry
+	members  *inmem.Provider // 由 Redis 中的节点列表生成的视图，负责 Watch 和查询
+
+	mu    sync.Mutex
+	local map[uint64]*iface.Member // 本节点注册的节点
+	names map[string]uint64        // 本节点占用的名字，随节点一起续约
+
+	loadMu  sync.Mutex
+	trigger chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (p *Provider) memberKey(memberId uint64) string {
+	return p.memberPrefix() + strconv.FormatUint(memberId, 10)
+}
+
+func (p *Provider) memberPrefix() string {
+	return p.config.Prefix + ":members:"
+}
+
+func (p *Provider) nameKey(name string) string {
+	return p.config.Prefix + ":names:" + name
+}
+
+// channel 节点变化的通知频道
+func (p *Provider) channel() string {
+	return p.config.Prefix + ":events"
+}
+
+func (p *Provider) Run(ctx context.Context) error {
+	if err := p.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis provider: failed to connect %s: %w", p.config.Address, err)
+	}
+	if err := p.members.Run(ctx); err != nil {
+		return err
+	}
+	pubsub := p.client.Subscribe(p.ctx, p.channel())
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return err
+	}
+	if p.config.KeyspaceEvents {
+		pattern := fmt.Sprintf("__keyspace@%d__:%s*", p.config.DB, p.memberPrefix())
+		if err := pubsub.PSubscribe(ctx, pattern); err != nil {
+			_ = pubsub.Close()
+			return err
+		}
+	}
+	if err := p.load(ctx); err != nil {
+		_ = pubsub.Close()
+		return err
+	}
+
+	p.wg.Add(3)
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		p.watch(pubsub)
+	})
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		p.pollLoop()
+	})
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		p.heartbeatLoop()
+	})
+	return nil
+}
+
+// watch 收到节点变化通知后重新拉取节点列表，断线重连由客户端处理，期间错过的通知由定期拉取弥补
+func (p *Provider) watch(pubsub *goredis.PubSub) {
+	defer pubsub.Close()
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			p.notify()
+		}
+	}
+}
+
+// notify 请求重新拉取节点列表，多次请求合并为一次
+func (p *Provider) notify() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Provider) pollLoop() {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.trigger:
+		}
+		if err := p.load(p.ctx); err != nil && p.ctx.Err() == nil {
+			glog.Warn("redis服务发现: 拉取节点列表失败，保留原列表", zap.Error(err))
+		}
+	}
+}
+
+// load 拉取全部节点写入视图，拉取失败时保留原列表
+func (p *Provider) load(ctx context.Context) error {
+	p.loadMu.Lock()
+	defer p.loadMu.Unlock()
+
+	var keys []string
+	iter := p.client.Scan(ctx, 0, p.memberPrefix()+"*", 256).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	members := make([]*iface.Member, 0, len(keys))
+	if len(keys) > 0 {
+		values, err := p.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				// 键在 SCAN 之后过期
+				continue
+			}
+			member := &iface.Member{}
+			if err = lib.Json.Unmarshal([]byte(data), member); err != nil {
+				glog.Warn("redis服务发现: 节点数据格式错误", zap.String("key", keys[i]), zap.Error(err))
+				continue
+			}
+			members = append(members, member)
+		}
+	}
+	p.registry.Reset(members)
+	return nil
+}
+
+// heartbeatLoop 续约本节点注册的节点和占用的名字，节点键已过期（如 Redis 重启）时重新写入
+func (p *Provider) heartbeatLoop() {
+	ticker := time.NewTicker(p.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.heartbeat()
+		}
+	}
+}
+
+func (p *Provider) heartbeat() {
+	p.mu.Lock()
+	local := make([]*iface.Member, 0, len(p.local))
+	for _, member := range p.local {
+		local = append(local, member)
+	}
+	p.mu.Unlock()
+
+	for _, member := range local {
+		ok, err := p.client.PExpire(p.ctx, p.memberKey(member.GetID()), p.config.TTL).Result()
+		if err == nil && !ok {
+			glog.Warn("redis服务发现: 节点已过期，重新注册", zap.Uint64("memberId", member.GetID()))
+			err = p.put(p.ctx, member)
+		}
+		if err != nil && p.ctx.Err() == nil {
+			glog.Warn("redis服务发现: 节点续约失败", zap.Uint64("memberId", member.GetID()), zap.Error(err))
+		}
+	}
+	p.renewNames()
+}
+
+// put 写入节点并通知其他进程
+func (p *Provider) put(ctx context.Context, member *iface.Member) error {
+	data, err := lib.Json.Marshal(member)
+	if err != nil {
+		return err
+	}
+	if err = p.client.Set(ctx, p.memberKey(member.GetID()), data, p.config.TTL).Err(); err != nil {
+		return err
+	}
+	return p.publish(ctx, member.GetID())
+}
+
+// remove 删除节点和节点占用的名字并通知其他进程
+func (p *Provider) remove(ctx context.Context, memberId uint64) error {
+	p.releaseAll(ctx, memberId)
+	if err := p.client.Del(ctx, p.memberKey(memberId)).Err(); err != nil {
+		return err
+	}
+	return p.publish(ctx, memberId)
+}
+
+func (p *Provider) publish(ctx context.Context, memberId uint64) error {
+	return p.client.Publish(ctx, p.channel(), memberId).Err()
+}
+
+func (p *Provider) Register(member *iface.Member) error {
+	if p.IsStop() {
+		return ErrStopped
+	}
+	if err := p.put(p.ctx, member); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.local[member.GetID()] = member
+	p.mu.Unlock()
+	return p.load(p.ctx)
+}
+
+func (p *Provider) Update(member *iface.Member) error {
+	p.mu.Lock()
+	_, ok := p.local[member.GetID()]
+	if ok {
+		p.local[member.GetID()] = member
+	}
+	p.mu.Unlock()
+	if !ok {
+		return ErrNotRegistered
+	}
+	if err := p.put(p.ctx, member); err != nil {
+		return err
+	}
+	return p.load(p.ctx)
+}
+
+// Deregister 注销节点，同时释放节点占用的名字
+func (p *Provider) Deregister(memberId uint64) error {
+	p.mu.Lock()
+	delete(p.local, memberId)
+	p.mu.Unlock()
+	if err := p.remove(p.ctx, memberId); err != nil {
+		return err
+	}
+	return p.load(p.ctx)
+}
+
+func (p *Provider) GetById(memberId uint64) *iface.Member {
+	return p.members.GetById(memberId)
+}
+
+func (p *Provider) GetByKind(kind string) map[uint64]*iface.Member {
+	return p.members.GetByKind(kind)
+}
+
+func (p *Provider) GetAll() map[uint64]*iface.Member {
+	return p.members.GetAll()
+}
+
+func (p *Provider) Watch(kind string, handler iface.ServiceChangeHandler) uint64 {
+	return p.members.Watch(kind, handler)
+}
+
+func (p *Provider) Unwatch(kind string, id uint64) {
+	p.members.Unwatch(kind, id)
+}
+
+// Shutdown 停止续约并注销本节点注册的节点
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if !p.Stop() {
+		return nil
+	}
+	p.cancel()
+	grs.WaitWithContext(ctx, &p.wg)
+
+	p.mu.Lock()
+	local := p.local
+	p.local = make(map[uint64]*iface.Member)
+	p.mu.Unlock()
+	for id := range local {
+		if err := p.remove(ctx, id); err != nil {
+			glog.Warn("redis服务发现: 注销节点失败，等待节点过期", zap.Uint64("memberId", id), zap.Error(err))
+		}
+	}
+	_ = p.members.Shutdown(ctx)
+	return p.client.Close()
+}
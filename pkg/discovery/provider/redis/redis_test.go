@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// testConfig 连接 GAS_REDIS_ADDR（默认 127.0.0.1:6379），Redis 不可用时跳过测试
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	if addr := os.Getenv("GAS_REDIS_ADDR"); addr != "" {
+		cfg.Address = addr
+	}
+	client := goredis.NewClient(&goredis.Options{Addr: cfg.Address})
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis 不可用: %v", err)
+	}
+	cfg.Prefix = fmt.Sprintf("gas-test-%d", time.Now().UnixNano())
+	cfg.TTL = time.Second
+	cfg.PollInterval = 200 * time.Millisecond
+	return cfg
+}
+
+func newProvider(t *testing.T, cfg *Config) *Provider {
+	t.Helper()
+	c := *cfg
+	provider := New(&c)
+	if err := provider.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func waitTopology(t *testing.T, ch <-chan *iface.Topology) *iface.Topology {
+	t.Helper()
+	select {
+	case topology := <-ch:
+		return topology
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到拓扑变化")
+		return nil
+	}
+}
+
+// TestWatch 测试节点注册、更新、注销和过期通知到其他进程
+func TestWatch(t *testing.T) {
+	cfg := testConfig(t)
+	a := newProvider(t, cfg)
+	defer a.Shutdown(context.Background())
+	b := newProvider(t, cfg)
+	defer b.Shutdown(context.Background())
+
+	ch := make(chan *iface.Topology, 10)
+	b.Watch("game", func(topology *iface.Topology) { ch <- topology })
+
+	member := &iface.Member{Id: 1, Kind: "game", Address: "10.0.0.1", Port: 9001}
+	if err := a.Register(member); err != nil {
+		t.Fatal(err)
+	}
+	if topology := waitTopology(t, ch); len(topology.Joined) != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+
+	// 超过有效期后仍然存在，说明续约生效
+	time.Sleep(2 * cfg.TTL)
+	if b.GetById(1) == nil {
+		t.Fatal("节点未续约")
+	}
+
+	if err := a.Update(&iface.Member{Id: 1, Kind: "game", Address: "10.0.0.1", Port: 9001, Meta: map[string]string{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	if topology := waitTopology(t, ch); len(topology.Update) != 1 || topology.Update[0].GetMeta()["k"] != "v" {
+		t.Fatalf("want update 1, got %+v", topology)
+	}
+
+	if err := a.Deregister(1); err != nil {
+		t.Fatal(err)
+	}
+	if topology := waitTopology(t, ch); len(topology.Left) != 1 {
+		t.Fatalf("want left 1, got %+v", topology)
+	}
+
+	// 模拟进程崩溃：节点键不再续约，过期后通知离开
+	data := `{"id":2,"kind":"game","address":"10.0.0.2","port":9002}`
+	if err := a.client.Set(context.Background(), a.memberKey(2), data, cfg.TTL).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.publish(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if topology := waitTopology(t, ch); len(topology.Joined) != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+	if topology := waitTopology(t, ch); len(topology.Left) != 1 {
+		t.Fatalf("want left 1, got %+v", topology)
+	}
+}
+
+// TestNames 测试名字的占用、冲突和随节点注销释放
+func TestNames(t *testing.T) {
+	cfg := testConfig(t)
+	a := newProvider(t, cfg)
+	defer a.Shutdown(context.Background())
+	b := newProvider(t, cfg)
+	defer b.Shutdown(context.Background())
+
+	if _, err := a.Claim("world", 1); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("want ErrNotRegistered, got %v", err)
+	}
+	if err := a.Register(&iface.Member{Id: 1, Kind: "game"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Register(&iface.Member{Id: 2, Kind: "game"}); err != nil {
+		t.Fatal(err)
+	}
+	if owner, err := a.Claim("world", 1); err != nil || owner != 1 {
+		t.Fatalf("claim: owner=%d err=%v", owner, err)
+	}
+	if owner, err := b.Claim("world", 2); !errors.Is(err, iface.ErrNameClaimed) || owner != 1 {
+		t.Fatalf("want claimed by 1, got owner=%d err=%v", owner, err)
+	}
+
+	// 名字随节点一起续约
+	time.Sleep(2 * cfg.TTL)
+	if owner, ok := b.Lookup("world"); !ok || owner != 1 {
+		t.Fatalf("lookup: owner=%d ok=%v", owner, ok)
+	}
+
+	if err := b.Release("world", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Lookup("world"); !ok {
+		t.Fatal("非占用者不能释放名字")
+	}
+
+	if err := a.Deregister(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Lookup("world"); ok {
+		t.Fatal("节点注销后名字未释放")
+	}
+	if owner, err := b.Claim("world", 2); err != nil || owner != 2 {
+		t.Fatalf("claim: owner=%d err=%v", owner, err)
+	}
+}
@@ -0,0 +1,24 @@
+package static
+
+import "github.com/dzm2020/gas/pkg/discovery/iface"
+
+// Config 静态服务发现配置，节点列表来自 File 指定的 YAML/JSON 文件，未指定文件时使用 Members
+type Config struct {
+	File    string          `json:"file" mapstruct:"file"`       // 节点列表文件，修改后自动重新加载
+	Members []*iface.Member `json:"members" mapstruct:"members"` // 未指定文件时使用的节点列表
+}
+
+// fileConfig 节点列表文件的格式
+//
+//	members:
+//	  - id: 1
+//	    kind: game
+//	    address: 10.0.0.1
+//	    port: 9001
+type fileConfig struct {
+	Members []*iface.Member `json:"members" yaml:"members"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{}
+}
@@ -0,0 +1,222 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	discoveryApi "github.com/dzm2020/gas/pkg/discovery"
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/discovery/provider/inmem"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	_ = discoveryApi.GetFactoryMgr().Register("static", func(args ...any) (iface.IDiscovery, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("static provider: config is required")
+		}
+		config, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("static provider: config must be map[string]interface{}, got %T", args[0])
+		}
+		cfg := DefaultConfig()
+		vp := viper.New()
+		vp.Set("", config)
+		if err := vp.UnmarshalKey("", cfg); err != nil {
+			return nil, fmt.Errorf("static provider: failed to unmarshal config: %w", err)
+		}
+		return New(cfg), nil
+	})
+}
+
+var _ iface.IDiscovery = (*Provider)(nil)
+
+func New(config *Config) *Provider {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	provider := &Provider{
+		config:   config,
+		registry: inmem.NewRegistry(),
+		local:    make(map[uint64]*iface.Member),
+	}
+	provider.members = inmem.NewWithRegistry(provider.registry, &inmem.Config{})
+	provider.ctx, provider.cancel = context.WithCancel(context.Background())
+	return provider
+}
+
+// Provider 静态服务发现，用于无法部署 Consul 的小规模环境
+// 节点列表以配置为准，不做健康检查；本节点注册的信息（如元数据）覆盖列表中的同 ID 节点，只对本节点可见。
+// 节点列表文件修改后重新加载，以与 Consul 相同的拓扑变化通知监听者
+type Provider struct {
+	stopper.Stopper
+
+	config   *Config
+	registry *inmem.Registry
+	members  *inmem.Provider
+
+	mu    sync.Mutex
+	file  []*iface.Member          // 配置中的节点
+	local map[uint64]*iface.Member // 本节点注册的节点
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (p *Provider) Run(ctx context.Context) error {
+	if err := p.members.Run(ctx); err != nil {
+		return err
+	}
+	if p.config.File == "" {
+		p.setFile(p.config.Members)
+		return nil
+	}
+	if err := p.load(); err != nil {
+		return err
+	}
+	return p.watchFile()
+}
+
+// load 加载节点列表文件
+func (p *Provider) load() error {
+	conf := &fileConfig{}
+	if err := lib.LoadConfigFile(p.config.File, conf); err != nil {
+		return fmt.Errorf("static provider: failed to load %s: %w", p.config.File, err)
+	}
+	p.setFile(conf.Members)
+	return nil
+}
+
+// watchFile 监听节点列表文件所在的目录，编辑器保存时常以重命名替换文件，直接监听文件会丢失后续修改
+func (p *Provider) watchFile() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	file := filepath.Clean(p.config.File)
+	if err = watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	p.wg.Add(1)
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		defer watcher.Close()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(e.Name) != file || !e.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				if err := p.load(); err != nil {
+					glog.Warn("静态服务发现: 重新加载节点列表失败，保留原列表", zap.String("file", file), zap.Error(err))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				glog.Warn("静态服务发现: 监听节点列表文件失败", zap.String("file", file), zap.Error(err))
+			}
+		}
+	})
+	return nil
+}
+
+func (p *Provider) setFile(members []*iface.Member) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file = members
+	p.resetLocked()
+}
+
+// resetLocked 合并配置中的节点和本节点注册的节点，写入注册表
+func (p *Provider) resetLocked() {
+	members := make([]*iface.Member, 0, len(p.file)+len(p.local))
+	for _, member := range p.file {
+		if member == nil {
+			continue
+		}
+		if _, ok := p.local[member.GetID()]; !ok {
+			members = append(members, member)
+		}
+	}
+	for _, member := range p.local {
+		members = append(members, member)
+	}
+	p.registry.Reset(members)
+}
+
+func (p *Provider) Register(member *iface.Member) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.local[member.GetID()] = member
+	p.resetLocked()
+	return nil
+}
+
+func (p *Provider) Update(member *iface.Member) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.local[member.GetID()]; !ok {
+		return inmem.ErrNotRegistered
+	}
+	p.local[member.GetID()] = member
+	p.resetLocked()
+	return nil
+}
+
+// Deregister 移除本节点注册的信息，配置中的节点不受影响
+func (p *Provider) Deregister(memberId uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.local[memberId]; !ok {
+		return nil
+	}
+	delete(p.local, memberId)
+	p.resetLocked()
+	return nil
+}
+
+func (p *Provider) GetById(memberId uint64) *iface.Member {
+	return p.members.GetById(memberId)
+}
+
+func (p *Provider) GetByKind(kind string) map[uint64]*iface.Member {
+	return p.members.GetByKind(kind)
+}
+
+func (p *Provider) GetAll() map[uint64]*iface.Member {
+	return p.members.GetAll()
+}
+
+func (p *Provider) Watch(kind string, handler iface.ServiceChangeHandler) uint64 {
+	return p.members.Watch(kind, handler)
+}
+
+func (p *Provider) Unwatch(kind string, id uint64) {
+	p.members.Unwatch(kind, id)
+}
+
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if !p.Stop() {
+		return nil
+	}
+	p.cancel()
+	grs.WaitWithContext(ctx, &p.wg)
+	return p.members.Shutdown(ctx)
+}
@@ -0,0 +1,99 @@
+package static
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	discoveryApi "github.com/dzm2020/gas/pkg/discovery"
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+)
+
+func waitTopology(t *testing.T, ch <-chan *iface.Topology) *iface.Topology {
+	t.Helper()
+	select {
+	case topology := <-ch:
+		return topology
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到拓扑变化")
+		return nil
+	}
+}
+
+// TestFileReload 测试节点列表文件修改后通知拓扑变化
+func TestFileReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "members.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+members:
+  - {id: 1, kind: game, address: 10.0.0.1, port: 9001}
+  - {id: 2, kind: gate, address: 10.0.0.2, port: 9002}
+`)
+	provider := New(&Config{File: file})
+	if err := provider.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	ch := make(chan *iface.Topology, 10)
+	provider.Watch("game", func(topology *iface.Topology) { ch <- topology })
+	if topology := waitTopology(t, ch); len(topology.Joined) != 1 {
+		t.Fatalf("want joined 1, got %+v", topology)
+	}
+	if len(provider.GetAll()) != 2 {
+		t.Fatalf("want 2 members, got %d", len(provider.GetAll()))
+	}
+
+	write(`
+members:
+  - {id: 1, kind: game, address: 10.0.0.1, port: 9101}
+  - {id: 3, kind: game, address: 10.0.0.3, port: 9003}
+`)
+	var updated, joined int
+	for updated == 0 || joined == 0 {
+		topology := waitTopology(t, ch)
+		updated += len(topology.Update)
+		joined += len(topology.Joined)
+	}
+	if provider.GetById(2) != nil || provider.GetById(1).GetPort() != 9101 {
+		t.Fatal("重新加载后节点列表不正确")
+	}
+}
+
+// TestRegisterOverlay 测试本节点注册的信息覆盖配置中的同 ID 节点
+func TestRegisterOverlay(t *testing.T) {
+	creator, ok := discoveryApi.GetFactoryMgr().Get("static")
+	if !ok {
+		t.Fatal("static 提供者未注册")
+	}
+	dis, err := creator(map[string]interface{}{
+		"members": []interface{}{
+			map[string]interface{}{"id": 1, "kind": "game", "address": "10.0.0.1", "port": 9001},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dis.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer dis.Shutdown(context.Background())
+
+	member := &iface.Member{Id: 1, Kind: "game", Address: "10.0.0.1", Port: 9001, Meta: map[string]string{"version": "1.2.0"}}
+	if err = dis.Register(member); err != nil {
+		t.Fatal(err)
+	}
+	if got := dis.GetById(1); got == nil || got.GetMeta()["version"] != "1.2.0" {
+		t.Fatalf("注册信息未生效: %+v", got)
+	}
+	_ = dis.Deregister(1)
+	if got := dis.GetById(1); got == nil || len(got.GetMeta()) != 0 {
+		t.Fatalf("注销后应恢复配置中的节点: %+v", got)
+	}
+}
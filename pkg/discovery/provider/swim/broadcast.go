@@ -0,0 +1,65 @@
+package swim
+
+import (
+	"math"
+	"sort"
+)
+
+type broadcast struct {
+	state     *nodeState
+	transmits int
+}
+
+// broadcastQueue 待传播的状态变化，附带在发出的数据包中，每个变化转发有限次数
+// 同一节点的新状态替换旧状态
+type broadcastQueue struct {
+	items map[string]*broadcast
+}
+
+func newBroadcastQueue() *broadcastQueue {
+	return &broadcastQueue{items: make(map[string]*broadcast)}
+}
+
+func (q *broadcastQueue) push(state *nodeState) {
+	copied := *state
+	q.items[state.Name] = &broadcast{state: &copied}
+}
+
+func (q *broadcastQueue) remove(name string) {
+	delete(q.items, name)
+}
+
+// take 取出转发次数最少的至多 max 个状态，转发次数达到 limit 的状态不再传播
+func (q *broadcastQueue) take(max, limit int) []*nodeState {
+	if len(q.items) == 0 {
+		return nil
+	}
+	items := make([]*broadcast, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].transmits < items[j].transmits
+	})
+	if len(items) > max {
+		items = items[:max]
+	}
+	states := make([]*nodeState, 0, len(items))
+	for _, item := range items {
+		states = append(states, item.state)
+		item.transmits++
+		if item.transmits >= limit {
+			delete(q.items, item.state.Name)
+		}
+	}
+	return states
+}
+
+// retransmitLimit 状态变化的转发次数，随节点数对数增长
+func retransmitLimit(mult, nodes int) int {
+	limit := mult * int(math.Ceil(math.Log10(float64(nodes+1))))
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
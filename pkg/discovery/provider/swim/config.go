@@ -0,0 +1,31 @@
+package swim
+
+import "time"
+
+type Config struct {
+	Bind             string        `json:"bind" mapstruct:"bind"`                         // UDP 监听地址
+	Advertise        string        `json:"advertise" mapstruct:"advertise"`               // 其他节点访问本节点的地址，为空时使用 Bind，监听 0.0.0.0 时必须设置
+	Seeds            []string      `json:"seeds" mapstruct:"seeds"`                       // 种子节点地址，启动时从种子节点同步成员列表
+	ProbeInterval    time.Duration `json:"probeInterval" mapstruct:"probeInterval"`       // 探测周期，每个周期探测一个节点
+	ProbeTimeout     time.Duration `json:"probeTimeout" mapstruct:"probeTimeout"`         // 直接探测的超时时间，超时后通过其他节点间接探测
+	IndirectChecks   int           `json:"indirectChecks" mapstruct:"indirectChecks"`     // 间接探测时委托的节点数
+	SuspicionTimeout time.Duration `json:"suspicionTimeout" mapstruct:"suspicionTimeout"` // 可疑节点在该时间内未反驳则判定为失效
+	DeadRetention    time.Duration `json:"deadRetention" mapstruct:"deadRetention"`       // 失效节点的状态保留时间，防止旧消息使其复活
+	SyncInterval     time.Duration `json:"syncInterval" mapstruct:"syncInterval"`         // 与随机节点全量同步成员列表的间隔
+	RetransmitMult   int           `json:"retransmitMult" mapstruct:"retransmitMult"`     // 状态变化的转发次数系数，实际次数为 系数*log10(节点数+1)
+	MaxPiggyback     int           `json:"maxPiggyback" mapstruct:"maxPiggyback"`         // 每个数据包附带的状态变化数
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Bind:             "127.0.0.1:7946",
+		ProbeInterval:    time.Second,
+		ProbeTimeout:     500 * time.Millisecond,
+		IndirectChecks:   3,
+		SuspicionTimeout: 5 * time.Second,
+		DeadRetention:    30 * time.Second,
+		SyncInterval:     30 * time.Second,
+		RetransmitMult:   4,
+		MaxPiggyback:     8,
+	}
+}
@@ -0,0 +1,122 @@
+package swim
+
+import (
+	"fmt"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib"
+	"github.com/dzm2020/gas/pkg/network"
+
+	"go.uber.org/zap"
+)
+
+// 数据包类型
+const (
+	packetPing    uint8 = iota + 1 // 直接探测
+	packetAck                      // 探测回复
+	packetPingReq                  // 委托其他节点探测 Target
+	packetSync                     // 发送全量状态并请求对方的全量状态
+	packetSyncAck                  // 全量状态回复
+	packetGossip                   // 只传播状态变化，无需回复
+)
+
+// 节点状态
+const (
+	stateAlive uint8 = iota
+	stateSuspect
+	stateDead
+	stateLeft // 主动离开
+)
+
+// nodeState gossip 节点的状态，Members 为该节点注册到服务发现的节点，随状态一起传播
+// Incarnation 只能由节点自身增加，用于反驳其他节点对它的怀疑以及发布成员变化
+type nodeState struct {
+	Name        string          `json:"name"`
+	Incarnation uint64          `json:"inc"`
+	State       uint8           `json:"state"`
+	Members     []*iface.Member `json:"members,omitempty"`
+}
+
+func (s *nodeState) alive() bool {
+	return s.State == stateAlive || s.State == stateSuspect
+}
+
+// supersedes s 是否比 cur 新：版本号更大，或版本号相同时状态更差
+func (s *nodeState) supersedes(cur *nodeState) bool {
+	if s.Incarnation != cur.Incarnation {
+		return s.Incarnation > cur.Incarnation
+	}
+	switch s.State {
+	case stateSuspect:
+		return cur.State == stateAlive
+	case stateDead, stateLeft:
+		return cur.alive()
+	}
+	return false
+}
+
+// maxStatesSize 单个数据包中状态编码后的总长度上限，为包头留出余量，整个数据包低于 UDP 数据报上限（65507 字节）
+const maxStatesSize = 60 * 1024
+
+// packet 节点之间的 UDP 数据包，States 附带状态变化或全量状态
+type packet struct {
+	Type   uint8        `json:"type"`
+	Seq    uint64       `json:"seq,omitempty"`
+	From   string       `json:"from"`
+	Target string       `json:"target,omitempty"`
+	States []*nodeState `json:"states,omitempty"`
+}
+
+var _ network.ICodec = (*codec)(nil)
+
+// codec 每个 UDP 数据包是一个完整的 JSON 编码的 packet
+type codec struct{}
+
+func (c *codec) Encode(msg interface{}) ([]byte, error) {
+	pkt, ok := msg.(*packet)
+	if !ok {
+		return nil, fmt.Errorf("swim: unexpected message %T", msg)
+	}
+	return lib.Json.Marshal(pkt)
+}
+
+func (c *codec) Decode(b []byte) (interface{}, int, error) {
+	if len(b) == 0 {
+		return nil, 0, nil
+	}
+	pkt := &packet{}
+	if err := lib.Json.Unmarshal(b, pkt); err != nil {
+		return nil, 0, err
+	}
+	return pkt, len(b), nil
+}
+
+// splitStates 按编码后的长度将状态分组，每组不超过 limit，至少返回一组
+// 单个超过 limit 的状态无法放入任何数据包，丢弃
+func splitStates(states []*nodeState, limit int) [][]*nodeState {
+	var batches [][]*nodeState
+	var batch []*nodeState
+	size := 0
+	for _, state := range states {
+		data, err := lib.Json.Marshal(state)
+		if err != nil {
+			continue
+		}
+		n := len(data) + 1 // 数组中的逗号
+		if n > limit {
+			glog.Warn("swim: 节点状态超过数据包大小，无法同步", zap.String("node", state.Name), zap.Int("size", len(data)))
+			continue
+		}
+		if size+n > limit && len(batch) > 0 {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, state)
+		size += n
+	}
+	if len(batch) > 0 || len(batches) == 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -0,0 +1,564 @@
+// Package swim 基于 SWIM 协议的去中心化服务发现
+//
+// 每个节点在 UDP 上周期性地探测一个随机节点：直接探测超时后委托其他节点间接探测，仍无回复时将其标记为可疑；
+// 可疑节点在 SuspicionTimeout 内未以更大的 Incarnation 反驳则判定为失效。状态变化附带在探测数据包中传播，
+// 并定期与随机节点全量同步。节点注册的 Member（包括 Tags 和 Meta）随节点状态一起传播。
+package swim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	discoveryApi "github.com/dzm2020/gas/pkg/discovery"
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/discovery/provider/inmem"
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+	"github.com/dzm2020/gas/pkg/network"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNoAdvertise = errors.New("swim: 未配置本节点地址")
+)
+
+func init() {
+	_ = discoveryApi.GetFactoryMgr().Register("swim", func(args ...any) (iface.IDiscovery, error) {
+		if len(args) == 0 {
+			return nil, errors.New("swim provider: config is required")
+		}
+		config, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("swim provider: config must be map[string]interface{}, got %T", args[0])
+		}
+		cfg := DefaultConfig()
+		vp := viper.New()
+		vp.Set("", config)
+		if err := vp.UnmarshalKey("", cfg); err != nil {
+			return nil, fmt.Errorf("swim provider: failed to unmarshal config: %w", err)
+		}
+		return New(cfg), nil
+	})
+}
+
+var _ iface.IDiscovery = (*Provider)(nil)
+
+func New(config *Config) *Provider {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	name := config.Advertise
+	if name == "" {
+		name = config.Bind
+	}
+	provider := &Provider{
+		config:     config,
+		name:       name,
+		registry:   inmem.NewRegistry(),
+		nodes:      make(map[string]*node),
+		local:      make(map[uint64]*iface.Member),
+		broadcasts: newBroadcastQueue(),
+		acks:       make(map[uint64]func()),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	// 以启动时间作为初始版本号，重启的节点能覆盖其他节点保留的失效状态
+	provider.self = &nodeState{Name: name, Incarnation: uint64(time.Now().UnixNano()), State: stateAlive}
+	provider.members = inmem.NewWithRegistry(provider.registry, &inmem.Config{})
+	provider.ctx, provider.cancel = context.WithCancel(context.Background())
+	return provider
+}
+
+// node 其他 gossip 节点的状态
+type node struct {
+	*nodeState
+	changedAt time.Time // 状态变化的时间，用于可疑超时和失效状态清理
+}
+
+type Provider struct {
+	stopper.Stopper
+	network.EmptyHandler
+
+	config   *Config
+	name     string
+	server   *network.UDPServer
+	registry *inmem.Registry
+	members  *inmem.Provider // 由 gossip 状态生成的成员视图，负责 Watch 和查询
+
+	mu         sync.Mutex
+	self       *nodeState
+	nodes      map[string]*node
+	local      map[uint64]*iface.Member // 本节点注册的成员
+	broadcasts *broadcastQueue
+	acks       map[uint64]func() // 等待回复的探测
+	seq        uint64
+	probeOrder []string
+	rand       *rand.Rand
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (p *Provider) Run(ctx context.Context) error {
+	if p.name == "" {
+		return ErrNoAdvertise
+	}
+	server, err := network.NewServer(p, "udp://"+p.config.Bind,
+		network.WithCodec(&codec{}),
+		network.WithReadBufSize(64*1024),
+		network.WithKeepAlive(p.config.SuspicionTimeout),
+		network.WithUntracked())
+	if err != nil {
+		return err
+	}
+	p.server = server.(*network.UDPServer)
+	if err = p.server.Start(); err != nil {
+		return err
+	}
+	if err = p.members.Run(ctx); err != nil {
+		return err
+	}
+	p.join()
+
+	p.wg.Add(2)
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		p.probeLoop()
+	})
+	grs.Go(func(ctx context.Context) {
+		defer p.wg.Done()
+		p.syncLoop()
+	})
+	return nil
+}
+
+// join 向种子节点请求全量状态
+func (p *Provider) join() {
+	for _, seed := range p.config.Seeds {
+		if seed != p.name {
+			p.sendSync(seed, packetSync)
+		}
+	}
+}
+
+func (p *Provider) probeLoop() {
+	ticker := time.NewTicker(p.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.probe()
+			p.checkTimeout()
+		}
+	}
+}
+
+// syncLoop 定期与随机节点全量同步；还没有发现其他节点时重试种子节点
+func (p *Provider) syncLoop() {
+	ticker := time.NewTicker(p.config.ProbeInterval)
+	defer ticker.Stop()
+	lastSync := time.Now()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			targets := p.randomNodes(1, "")
+			if len(targets) == 0 {
+				p.join()
+				continue
+			}
+			if time.Since(lastSync) >= p.config.SyncInterval {
+				lastSync = time.Now()
+				p.sendSync(targets[0], packetSync)
+			}
+		}
+	}
+}
+
+// probe 探测下一个节点：直接探测，超时后委托其他节点间接探测，都没有回复时标记为可疑
+func (p *Provider) probe() {
+	target := p.nextTarget()
+	if target == "" {
+		return
+	}
+	acked := make(chan struct{}, 1)
+	seq := p.addAck(func() {
+		select {
+		case acked <- struct{}{}:
+		default:
+		}
+	})
+	defer p.removeAck(seq)
+
+	p.send(target, &packet{Type: packetPing, Seq: seq})
+	timer := time.NewTimer(p.config.ProbeTimeout)
+	defer timer.Stop()
+	select {
+	case <-acked:
+		return
+	case <-p.ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	for _, helper := range p.randomNodes(p.config.IndirectChecks, target) {
+		p.send(helper, &packet{Type: packetPingReq, Seq: seq, Target: target})
+	}
+	timer.Reset(p.config.ProbeInterval - p.config.ProbeTimeout)
+	select {
+	case <-acked:
+		return
+	case <-p.ctx.Done():
+		return
+	case <-timer.C:
+	}
+	p.suspect(target)
+}
+
+// nextTarget 按随机顺序轮流选择探测目标，每轮覆盖所有节点
+func (p *Provider) nextTarget() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.probeOrder) > 0 {
+		name := p.probeOrder[0]
+		p.probeOrder = p.probeOrder[1:]
+		if n, ok := p.nodes[name]; ok && n.alive() {
+			return name
+		}
+	}
+	for name, n := range p.nodes {
+		if n.alive() {
+			p.probeOrder = append(p.probeOrder, name)
+		}
+	}
+	if len(p.probeOrder) == 0 {
+		return ""
+	}
+	p.rand.Shuffle(len(p.probeOrder), func(i, j int) {
+		p.probeOrder[i], p.probeOrder[j] = p.probeOrder[j], p.probeOrder[i]
+	})
+	name := p.probeOrder[0]
+	p.probeOrder = p.probeOrder[1:]
+	return name
+}
+
+// randomNodes 随机选择至多 count 个存活节点，排除 exclude
+func (p *Provider) randomNodes(count int, exclude string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.nodes))
+	for name, n := range p.nodes {
+		if name != exclude && n.alive() {
+			names = append(names, name)
+		}
+	}
+	p.rand.Shuffle(len(names), func(i, j int) {
+		names[i], names[j] = names[j], names[i]
+	})
+	if len(names) > count {
+		names = names[:count]
+	}
+	return names
+}
+
+func (p *Provider) addAck(callback func()) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	p.acks[p.seq] = callback
+	return p.seq
+}
+
+func (p *Provider) removeAck(seq uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.acks, seq)
+}
+
+func (p *Provider) fireAck(seq uint64) {
+	p.mu.Lock()
+	callback := p.acks[seq]
+	p.mu.Unlock()
+	if callback != nil {
+		callback()
+	}
+}
+
+// send 发送数据包，附带待传播的状态变化
+func (p *Provider) send(to string, pkt *packet) {
+	p.mu.Lock()
+	pkt.States = p.broadcasts.take(p.config.MaxPiggyback, retransmitLimit(p.config.RetransmitMult, len(p.nodes)+1))
+	p.mu.Unlock()
+	p.write(to, pkt)
+}
+
+// sendSync 发送本节点已知的全量状态
+// 超过单个数据包大小时拆分为多个数据包，只有第一个使用 typ，其余作为 packetGossip 发送，避免对方重复回复
+func (p *Provider) sendSync(to string, typ uint8) {
+	p.mu.Lock()
+	states := make([]*nodeState, 0, len(p.nodes)+1)
+	self := *p.self
+	states = append(states, &self)
+	for _, n := range p.nodes {
+		copied := *n.nodeState
+		states = append(states, &copied)
+	}
+	p.mu.Unlock()
+	for _, batch := range splitStates(states, maxStatesSize) {
+		p.write(to, &packet{Type: typ, States: batch})
+		typ = packetGossip
+	}
+}
+
+func (p *Provider) write(to string, pkt *packet) {
+	pkt.From = p.name
+	if err := p.server.SendTo(to, pkt); err != nil {
+		glog.Warn("swim: 发送数据包失败", zap.String("to", to), zap.Uint8("type", pkt.Type), zap.Error(err))
+	}
+}
+
+func (p *Provider) OnMessage(conn network.IConnection, msg interface{}) error {
+	pkt, ok := msg.(*packet)
+	if !ok || pkt.From == "" || pkt.From == p.name {
+		return nil
+	}
+	p.merge(pkt.States)
+
+	switch pkt.Type {
+	case packetPing:
+		p.send(pkt.From, &packet{Type: packetAck, Seq: pkt.Seq})
+	case packetAck:
+		p.fireAck(pkt.Seq)
+	case packetPingReq:
+		p.probeFor(pkt.From, pkt.Target, pkt.Seq)
+	case packetSync:
+		p.sendSync(pkt.From, packetSyncAck)
+	}
+	return nil
+}
+
+// probeFor 受 requester 委托探测 target，收到回复后以 requester 的序号转发回复
+func (p *Provider) probeFor(requester, target string, requesterSeq uint64) {
+	var once sync.Once
+	var seq uint64
+	seq = p.addAck(func() {
+		once.Do(func() {
+			p.removeAck(seq)
+			p.send(requester, &packet{Type: packetAck, Seq: requesterSeq})
+		})
+	})
+	time.AfterFunc(p.config.ProbeTimeout, func() {
+		p.removeAck(seq)
+	})
+	p.send(target, &packet{Type: packetPing, Seq: seq})
+}
+
+// merge 合并收到的状态，有变化的状态继续传播
+func (p *Provider) merge(states []*nodeState) {
+	if len(states) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := false
+	for _, state := range states {
+		if p.mergeLocked(state) {
+			changed = true
+		}
+	}
+	if changed {
+		p.refreshLocked()
+	}
+}
+
+func (p *Provider) mergeLocked(state *nodeState) bool {
+	if state.Name == p.name {
+		// 其他节点认为本节点可疑或失效，提高版本号反驳
+		// p.self 只整体替换，不原地修改，已取出的状态可能正在锁外编码
+		if state.State != stateAlive && state.Incarnation >= p.self.Incarnation && !p.IsStop() {
+			self := *p.self
+			self.Incarnation = state.Incarnation + 1
+			p.self = &self
+			p.broadcasts.push(&self)
+		}
+		return false
+	}
+	copied := *state
+	cur, ok := p.nodes[state.Name]
+	if ok && !copied.supersedes(cur.nodeState) {
+		return false
+	}
+	if !ok && !copied.alive() {
+		// 未知节点的失效状态只需记录，防止之后收到的旧状态使其复活
+		p.nodes[state.Name] = &node{nodeState: &copied, changedAt: time.Now()}
+		return false
+	}
+	n := &node{nodeState: &copied, changedAt: time.Now()}
+	if ok && cur.State == copied.State {
+		n.changedAt = cur.changedAt
+	}
+	p.nodes[state.Name] = n
+	p.broadcasts.push(&copied)
+	if ok && copied.State != cur.State {
+		glog.Info("swim: 节点状态变化", zap.String("node", copied.Name), zap.Uint8("from", cur.State), zap.Uint8("to", copied.State))
+	}
+	return true
+}
+
+// suspect 将未回复探测的节点标记为可疑
+func (p *Provider) suspect(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n, ok := p.nodes[name]
+	if !ok || n.State != stateAlive {
+		return
+	}
+	state := *n.nodeState
+	state.State = stateSuspect
+	if p.mergeLocked(&state) {
+		p.refreshLocked()
+	}
+}
+
+// checkTimeout 可疑超时的节点判定为失效，清理保留时间已过的失效节点
+func (p *Provider) checkTimeout() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	changed := false
+	for name, n := range p.nodes {
+		switch {
+		case n.State == stateSuspect && now.Sub(n.changedAt) > p.config.SuspicionTimeout:
+			state := *n.nodeState
+			state.State = stateDead
+			if p.mergeLocked(&state) {
+				changed = true
+			}
+		case !n.alive() && now.Sub(n.changedAt) > p.config.DeadRetention:
+			delete(p.nodes, name)
+			p.broadcasts.remove(name)
+		}
+	}
+	if changed {
+		p.refreshLocked()
+	}
+}
+
+// refreshLocked 以存活节点（包括可疑节点）的成员更新成员视图
+func (p *Provider) refreshLocked() {
+	members := make([]*iface.Member, 0, len(p.nodes)+len(p.local))
+	if p.self.State == stateAlive {
+		members = append(members, p.self.Members...)
+	}
+	for _, n := range p.nodes {
+		if n.alive() {
+			members = append(members, n.Members...)
+		}
+	}
+	p.registry.Reset(members)
+}
+
+// updateSelfLocked 本节点的成员变化后提高版本号并传播
+func (p *Provider) updateSelfLocked() {
+	members := make([]*iface.Member, 0, len(p.local))
+	for _, member := range p.local {
+		members = append(members, member)
+	}
+	p.self = &nodeState{Name: p.name, Incarnation: p.self.Incarnation + 1, State: p.self.State, Members: members}
+	p.broadcasts.push(p.self)
+	p.refreshLocked()
+}
+
+func (p *Provider) Register(member *iface.Member) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	copied := *member
+	p.local[member.GetID()] = &copied
+	p.updateSelfLocked()
+	return nil
+}
+
+func (p *Provider) Update(member *iface.Member) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.local[member.GetID()]; !ok {
+		return inmem.ErrNotRegistered
+	}
+	copied := *member
+	p.local[member.GetID()] = &copied
+	p.updateSelfLocked()
+	return nil
+}
+
+func (p *Provider) Deregister(memberId uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.local[memberId]; !ok {
+		return nil
+	}
+	delete(p.local, memberId)
+	p.updateSelfLocked()
+	return nil
+}
+
+func (p *Provider) GetById(memberId uint64) *iface.Member {
+	return p.members.GetById(memberId)
+}
+
+func (p *Provider) GetByKind(kind string) map[uint64]*iface.Member {
+	return p.members.GetByKind(kind)
+}
+
+func (p *Provider) GetAll() map[uint64]*iface.Member {
+	return p.members.GetAll()
+}
+
+func (p *Provider) Watch(kind string, handler iface.ServiceChangeHandler) uint64 {
+	return p.members.Watch(kind, handler)
+}
+
+func (p *Provider) Unwatch(kind string, id uint64) {
+	p.members.Unwatch(kind, id)
+}
+
+// Shutdown 通知其他节点本节点主动离开后停止
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.stop(ctx, true)
+}
+
+func (p *Provider) stop(ctx context.Context, leave bool) error {
+	if !p.Stop() {
+		return nil
+	}
+	if leave && p.server != nil {
+		p.mu.Lock()
+		p.self = &nodeState{Name: p.name, Incarnation: p.self.Incarnation + 1, State: stateLeft}
+		left := *p.self
+		targets := make([]string, 0, len(p.nodes))
+		for name, n := range p.nodes {
+			if n.alive() {
+				targets = append(targets, name)
+			}
+		}
+		p.mu.Unlock()
+		for _, target := range targets {
+			p.write(target, &packet{Type: packetGossip, States: []*nodeState{&left}})
+		}
+	}
+	p.cancel()
+	grs.WaitWithContext(ctx, &p.wg)
+	if p.server != nil {
+		p.server.Shutdown(ctx)
+	}
+	return p.members.Shutdown(ctx)
+}
@@ -0,0 +1,181 @@
+package swim
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/discovery/iface"
+	"github.com/dzm2020/gas/pkg/network"
+)
+
+func freeAddress(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+func newProvider(t *testing.T, seeds ...string) *Provider {
+	cfg := DefaultConfig()
+	cfg.Bind = freeAddress(t)
+	cfg.Seeds = seeds
+	cfg.ProbeInterval = 100 * time.Millisecond
+	cfg.ProbeTimeout = 40 * time.Millisecond
+	cfg.SuspicionTimeout = 300 * time.Millisecond
+	cfg.SyncInterval = time.Second
+	provider := New(cfg)
+	if err := provider.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+	return provider
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("等待超时: %s", desc)
+}
+
+// TestSupersedes 测试状态覆盖规则
+func TestSupersedes(t *testing.T) {
+	alive := &nodeState{Incarnation: 2, State: stateAlive}
+	cases := []struct {
+		state *nodeState
+		want  bool
+	}{
+		{&nodeState{Incarnation: 2, State: stateAlive}, false},
+		{&nodeState{Incarnation: 3, State: stateAlive}, true},
+		{&nodeState{Incarnation: 2, State: stateSuspect}, true},
+		{&nodeState{Incarnation: 1, State: stateDead}, false},
+		{&nodeState{Incarnation: 2, State: stateDead}, true},
+	}
+	for i, c := range cases {
+		if got := c.state.supersedes(alive); got != c.want {
+			t.Errorf("case %d: want %v, got %v", i, c.want, got)
+		}
+	}
+}
+
+// TestRefute 测试被怀疑时以新的状态反驳，不修改之前的状态
+func TestRefute(t *testing.T) {
+	p := New(DefaultConfig())
+	old := p.self
+	incarnation := old.Incarnation
+
+	p.mu.Lock()
+	p.mergeLocked(&nodeState{Name: p.name, Incarnation: incarnation, State: stateSuspect})
+	states := p.broadcasts.take(1, 1)
+	self := p.self
+	p.mu.Unlock()
+
+	if old.Incarnation != incarnation {
+		t.Fatalf("之前的状态被修改: %d", old.Incarnation)
+	}
+	if self.Incarnation != incarnation+1 {
+		t.Fatalf("want incarnation %d, got %d", incarnation+1, self.Incarnation)
+	}
+	if len(states) != 1 || states[0] == self || states[0].Incarnation != incarnation+1 {
+		t.Fatalf("反驳的状态未正确传播: %+v", states)
+	}
+}
+
+// TestSplitStates 测试全量状态按数据包大小拆分，超过上限的单个状态被丢弃
+func TestSplitStates(t *testing.T) {
+	meta := map[string]string{"data": strings.Repeat("x", 2048)}
+	states := make([]*nodeState, 0, 101)
+	for i := 0; i < 100; i++ {
+		member := &iface.Member{Id: uint64(i), Kind: "game", Meta: meta}
+		states = append(states, &nodeState{Name: fmt.Sprintf("node-%d", i), Members: []*iface.Member{member}})
+	}
+	huge := &iface.Member{Id: 100, Meta: map[string]string{"data": strings.Repeat("x", maxStatesSize)}}
+	states = append(states, &nodeState{Name: "huge", Members: []*iface.Member{huge}})
+
+	batches := splitStates(states, maxStatesSize)
+	if len(batches) < 2 {
+		t.Fatalf("want multiple batches, got %d", len(batches))
+	}
+	total := 0
+	for _, batch := range batches {
+		data, err := (&codec{}).Encode(&packet{Type: packetSync, From: "127.0.0.1:7946", States: batch})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 65507 {
+			t.Fatalf("数据包超过 UDP 上限: %d", len(data))
+		}
+		for _, state := range batch {
+			if state.Name != fmt.Sprintf("node-%d", total) {
+				t.Fatalf("want node-%d, got %s", total, state.Name)
+			}
+			total++
+		}
+	}
+	if total != 100 {
+		t.Fatalf("want 100 states, got %d", total)
+	}
+	if batches = splitStates(nil, maxStatesSize); len(batches) != 1 || len(batches[0]) != 0 {
+		t.Fatalf("空状态应返回一个空分组: %v", batches)
+	}
+}
+
+// TestMembership 测试加入、元数据传播、主动离开和失效检测
+func TestMembership(t *testing.T) {
+	a := newProvider(t)
+	b := newProvider(t, a.name)
+	c := newProvider(t, a.name)
+
+	events := make(chan *iface.Topology, 100)
+	a.Watch(iface.AllKinds, func(topology *iface.Topology) { events <- topology })
+
+	_ = a.Register(&iface.Member{Id: 1, Kind: "gate", Address: "127.0.0.1", Port: 9001})
+	_ = b.Register(&iface.Member{Id: 2, Kind: "game", Address: "127.0.0.1", Port: 9002, Tags: []string{"canary"}})
+	_ = c.Register(&iface.Member{Id: 3, Kind: "game", Address: "127.0.0.1", Port: 9003})
+	for _, p := range []*Provider{a, b, c} {
+		p := p
+		waitFor(t, p.name+" 发现所有节点", func() bool { return len(p.GetAll()) == 3 })
+	}
+	// gossip 节点之间的 UDP 连接不计入连接数
+	if n := network.ConnectionCount(); n != 0 {
+		t.Fatalf("gossip 连接不应计入连接数, got %d", n)
+	}
+	if got := c.GetById(2); len(got.GetTags()) != 1 || got.GetTags()[0] != "canary" {
+		t.Fatalf("Tags 未传播: %+v", got)
+	}
+
+	_ = b.Update(&iface.Member{Id: 2, Kind: "game", Address: "127.0.0.1", Port: 9002, Meta: map[string]string{"draining": "true"}})
+	waitFor(t, "Meta 传播", func() bool { return c.GetById(2).GetMeta()["draining"] == "true" })
+
+	// 主动离开立即通知其他节点
+	_ = b.Shutdown(context.Background())
+	waitFor(t, "离开的节点被移除", func() bool { return a.GetById(2) == nil && c.GetById(2) == nil })
+
+	// 崩溃的节点经过探测和可疑超时后被移除
+	_ = c.stop(context.Background(), false)
+	waitFor(t, "失效的节点被移除", func() bool { return a.GetById(3) == nil })
+
+	var joined, left int
+	for len(events) > 0 {
+		topology := <-events
+		joined += len(topology.Joined)
+		left += len(topology.Left)
+	}
+	if joined != 3 || left != 2 {
+		t.Fatalf("want joined 3 left 2, got joined %d left %d", joined, left)
+	}
+}
@@ -0,0 +1,200 @@
+// Package compress 提供可插拔的数据压缩，以及带算法标记的压缩帧
+//
+// 帧格式：magic(0x00) + 算法(1 字节) + 压缩数据。
+// protobuf、json、msgpack 编码的消息都不会以 0x00 开头，因此未压缩的数据可以原样传输，
+// 解码时根据首字节区分，使用不同压缩配置的节点之间可以互通。
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("未知的压缩算法")
+	ErrCorruptFrame     = errors.New("压缩帧格式错误")
+	ErrTooLarge         = errors.New("解压后的数据超过上限")
+)
+
+// Algorithm 压缩算法标识，写入压缩帧
+type Algorithm byte
+
+const (
+	None   Algorithm = 0
+	Zstd   Algorithm = 1
+	Snappy Algorithm = 2
+)
+
+const (
+	frameMagic      = 0x00
+	frameHeaderSize = 2
+
+	// DefaultMaxSize 默认的解压后数据上限，防止很小的恶意压缩帧解压出大量数据
+	DefaultMaxSize = 64 << 20
+)
+
+// ICompressor 压缩算法实现
+type ICompressor interface {
+	Name() string
+	Compress(src []byte) ([]byte, error)
+	// Decompress 解压数据，解压后超过 maxSize 字节时返回 ErrTooLarge，且不应分配超过上限的内存
+	Decompress(src []byte, maxSize int) ([]byte, error)
+}
+
+var (
+	mu          sync.RWMutex
+	compressors = map[Algorithm]ICompressor{}
+)
+
+func init() {
+	Register(Zstd, newZstd())
+	Register(Snappy, snappyCompressor{})
+}
+
+// Register 注册压缩算法，相同标识会覆盖
+func Register(algorithm Algorithm, compressor ICompressor) {
+	mu.Lock()
+	defer mu.Unlock()
+	compressors[algorithm] = compressor
+}
+
+// Get 获取压缩算法实现
+func Get(algorithm Algorithm) (ICompressor, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	compressor, ok := compressors[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownAlgorithm, algorithm)
+	}
+	return compressor, nil
+}
+
+// Parse 根据名字获取算法标识，空字符串和 none 表示不压缩
+func Parse(name string) (Algorithm, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "none" {
+		return None, nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for algorithm, compressor := range compressors {
+		if compressor.Name() == name {
+			return algorithm, nil
+		}
+	}
+	return None, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, name)
+}
+
+// Encode 压缩数据并封装为压缩帧
+func Encode(algorithm Algorithm, data []byte) ([]byte, error) {
+	compressor, err := Get(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, frameHeaderSize+len(compressed))
+	frame[0] = frameMagic
+	frame[1] = byte(algorithm)
+	copy(frame[frameHeaderSize:], compressed)
+	return frame, nil
+}
+
+// IsFrame 数据是否为压缩帧
+func IsFrame(data []byte) bool {
+	return len(data) > 0 && data[0] == frameMagic
+}
+
+// Decode 解压压缩帧，不是压缩帧的数据原样返回
+// maxSize 为解压后数据的上限，小于等于 0 时使用 DefaultMaxSize
+func Decode(data []byte, maxSize int) ([]byte, error) {
+	if !IsFrame(data) {
+		return data, nil
+	}
+	if len(data) < frameHeaderSize {
+		return nil, ErrCorruptFrame
+	}
+	compressor, err := Get(Algorithm(data[1]))
+	if err != nil {
+		return nil, err
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return compressor.Decompress(data[frameHeaderSize:], maxSize)
+}
+
+// zstdCompressor 编码器可以并发使用，全局共享一份；解码器按流式读取以限制解压后的大小，放在池中复用
+type zstdCompressor struct {
+	encoder  *zstd.Encoder
+	decoders sync.Pool
+}
+
+func newZstd() *zstdCompressor {
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	z := &zstdCompressor{encoder: encoder}
+	z.decoders.New = func() any {
+		decoder, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(DefaultMaxSize))
+		return decoder
+	}
+	return z
+}
+
+func (z *zstdCompressor) Name() string {
+	return "zstd"
+}
+
+func (z *zstdCompressor) Compress(src []byte) ([]byte, error) {
+	return z.encoder.EncodeAll(src, nil), nil
+}
+
+func (z *zstdCompressor) Decompress(src []byte, maxSize int) ([]byte, error) {
+	decoder := z.decoders.Get().(*zstd.Decoder)
+	defer func() {
+		_ = decoder.Reset(nil)
+		z.decoders.Put(decoder)
+	}()
+	if err := decoder.Reset(bytes.NewReader(src)); err != nil {
+		return nil, err
+	}
+	// 多读一个字节用于判断是否超过上限
+	data, err := io.ReadAll(io.LimitReader(decoder, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%w: > %d", ErrTooLarge, maxSize)
+	}
+	return data, nil
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string {
+	return "snappy"
+}
+
+func (snappyCompressor) Compress(src []byte) ([]byte, error) {
+	return snappy.Encode(nil, src), nil
+}
+
+func (snappyCompressor) Decompress(src []byte, maxSize int) ([]byte, error) {
+	size, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("%w: %d > %d", ErrTooLarge, size, maxSize)
+	}
+	return snappy.Decode(nil, src)
+}
@@ -0,0 +1,71 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestEncodeDecode 测试各算法的压缩帧往返
+func TestEncodeDecode(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"name":"player","level":10}`), 200)
+	for _, algorithm := range []Algorithm{Zstd, Snappy} {
+		frame, err := Encode(algorithm, data)
+		if err != nil {
+			t.Fatalf("algorithm=%d: %v", algorithm, err)
+		}
+		if !IsFrame(frame) || len(frame) >= len(data) {
+			t.Fatalf("algorithm=%d: 压缩帧无效，长度 %d", algorithm, len(frame))
+		}
+		decoded, err := Decode(frame, 0)
+		if err != nil {
+			t.Fatalf("algorithm=%d: %v", algorithm, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Fatalf("algorithm=%d: 解压结果不一致", algorithm)
+		}
+	}
+}
+
+// TestDecodeRaw 测试未压缩数据原样返回
+func TestDecodeRaw(t *testing.T) {
+	data := []byte(`{"method":"Hello"}`)
+	decoded, err := Decode(data, 0)
+	if err != nil || !bytes.Equal(decoded, data) {
+		t.Fatalf("未压缩数据应原样返回: %q %v", decoded, err)
+	}
+	if _, err = Decode([]byte{frameMagic, 99, 1, 2}, 0); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Fatalf("未知算法应返回 ErrUnknownAlgorithm，实际 %v", err)
+	}
+}
+
+// TestDecodeTooLarge 测试解压后超过上限的数据返回 ErrTooLarge
+func TestDecodeTooLarge(t *testing.T) {
+	data := make([]byte, 1<<20)
+	for _, algorithm := range []Algorithm{Zstd, Snappy} {
+		frame, err := Encode(algorithm, data)
+		if err != nil {
+			t.Fatalf("algorithm=%d: %v", algorithm, err)
+		}
+		if _, err = Decode(frame, 1024); !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("algorithm=%d: want ErrTooLarge, got %v", algorithm, err)
+		}
+		decoded, err := Decode(frame, len(data))
+		if err != nil || len(decoded) != len(data) {
+			t.Fatalf("algorithm=%d: 等于上限的数据应解压成功: %d %v", algorithm, len(decoded), err)
+		}
+	}
+}
+
+// TestParse 测试算法名解析
+func TestParse(t *testing.T) {
+	cases := map[string]Algorithm{"": None, "none": None, "zstd": Zstd, "Snappy": Snappy}
+	for name, want := range cases {
+		if got, err := Parse(name); err != nil || got != want {
+			t.Errorf("%q: want %d, got %d (err=%v)", name, want, got, err)
+		}
+	}
+	if _, err := Parse("lz4"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("未知算法应返回 ErrUnknownAlgorithm，实际 %v", err)
+	}
+}
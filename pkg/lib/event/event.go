@@ -11,33 +11,41 @@ func handlerComparable[T any](this, other T) bool {
 	return reflect.ValueOf(this).Pointer() == reflect.ValueOf(other).Pointer()
 }
 
+type entry[V any] struct {
+	id      uint64 // Add 返回的句柄，Register 注册的处理函数为 0
+	handler func(V)
+}
+
 type Listener[V any] struct {
 	mu       sync.RWMutex
-	handlers []func(V)
+	handlers []entry[V]
+	nextId   uint64
 }
 
 func NewListener[V any]() *Listener[V] {
 	return &Listener[V]{}
 }
 
+// Register 注册处理函数，按函数指针去重
+// 同一方法的方法值（例如不同实例的 g.onEvent）函数指针相同，会被视为同一个处理函数，这种情况使用 Add
 func (m *Listener[V]) Register(handler func(V)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	f := func(other func(V)) bool {
-		return handlerComparable(handler, other)
+	f := func(other entry[V]) bool {
+		return other.id == 0 && handlerComparable(handler, other.handler)
 	}
 	if slices.ContainsFunc(m.handlers, f) {
 
 		return
 	}
-	m.handlers = append(m.handlers, handler)
+	m.handlers = append(m.handlers, entry[V]{handler: handler})
 }
 
 func (m *Listener[V]) UnRegister(handler func(V)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	index := slices.IndexFunc(m.handlers, func(other func(V)) bool {
-		return handlerComparable(handler, other)
+	index := slices.IndexFunc(m.handlers, func(other entry[V]) bool {
+		return other.id == 0 && handlerComparable(handler, other.handler)
 	})
 	if index < 0 {
 		return
@@ -45,11 +53,32 @@ func (m *Listener[V]) UnRegister(handler func(V)) {
 	m.handlers = slices.Delete(m.handlers, index, index+1)
 }
 
+// Add 注册处理函数并返回句柄，不去重，通过 Remove 注销
+func (m *Listener[V]) Add(handler func(V)) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextId++
+	m.handlers = append(m.handlers, entry[V]{id: m.nextId, handler: handler})
+	return m.nextId
+}
+
+// Remove 注销 Add 返回的句柄对应的处理函数
+func (m *Listener[V]) Remove(id uint64) {
+	if id == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = slices.DeleteFunc(m.handlers, func(other entry[V]) bool {
+		return other.id == id
+	})
+}
+
 func (m *Listener[V]) Notify(param V) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	handlers := m.handlers
-	for _, handler := range handlers {
-		handler(param)
+	for _, e := range handlers {
+		e.handler(param)
 	}
 }
@@ -0,0 +1,50 @@
+package event
+
+import (
+	"testing"
+)
+
+type counter struct {
+	count int
+}
+
+func (c *counter) on(int) {
+	c.count++
+}
+
+// TestListenerAdd 测试按句柄注册同一方法不同实例的方法值，并只注销对应的处理函数
+func TestListenerAdd(t *testing.T) {
+	l := NewListener[int]()
+	a, b := &counter{}, &counter{}
+	idA := l.Add(a.on)
+	l.Add(b.on)
+	l.Notify(1)
+	if a.count != 1 || b.count != 1 {
+		t.Fatalf("want 1/1, got %d/%d", a.count, b.count)
+	}
+
+	l.Remove(idA)
+	l.Notify(1)
+	if a.count != 1 || b.count != 2 {
+		t.Fatalf("want 1/2, got %d/%d", a.count, b.count)
+	}
+}
+
+// TestListenerRegister 测试 Register 按函数指针去重，且不影响 Add 注册的处理函数
+func TestListenerRegister(t *testing.T) {
+	l := NewListener[int]()
+	a, b := &counter{}, &counter{}
+	l.Add(a.on)
+	l.Register(b.on)
+	l.Register(b.on)
+	l.Notify(1)
+	if a.count != 1 || b.count != 1 {
+		t.Fatalf("want 1/1, got %d/%d", a.count, b.count)
+	}
+
+	l.UnRegister(b.on)
+	l.Notify(1)
+	if a.count != 2 || b.count != 1 {
+		t.Fatalf("want 2/1, got %d/%d", a.count, b.count)
+	}
+}
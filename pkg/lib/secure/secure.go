@@ -0,0 +1,251 @@
+// Package secure 提供节点间消息的签名、加密和防重放
+//
+// 帧格式：
+//
+//	magic(0x01) | flags(1) | keyIdLen(1) | keyId | timestamp(8，毫秒) | nonce(16) | payload | hmac(32)
+//
+// HMAC-SHA256 覆盖 hmac 之前的全部字节；flags 带有 flagEncrypted 时 payload 为 AES-256-GCM 密文，
+// 以帧头作为附加数据。接收方按 keyId 选择密钥，因此轮换密钥时新旧密钥可以同时生效；
+// 时间戳超出窗口或 nonce 在窗口内重复出现的帧视为重放。
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoKey           = errors.New("未配置签名密钥")
+	ErrKeyInvalid      = errors.New("签名密钥不合法")
+	ErrUnsigned        = errors.New("消息未签名")
+	ErrCorruptFrame    = errors.New("签名帧格式错误")
+	ErrUnknownKey      = errors.New("未知的签名密钥")
+	ErrBadSignature    = errors.New("消息签名错误")
+	ErrExpired         = errors.New("消息时间戳超出允许窗口")
+	ErrReplay          = errors.New("重复的消息")
+	ErrDecryptFailed   = errors.New("消息解密失败")
+	ErrKeyIdTooLong    = errors.New("密钥 ID 过长")
+	ErrEncryptRequired = errors.New("消息未加密")
+	ErrInvalidWindow   = errors.New("时间窗口必须大于 0")
+)
+
+const (
+	frameMagic    = 0x01
+	flagEncrypted = 0x01
+
+	nonceSize   = 16
+	macSize     = sha256.Size
+	minKeySize  = 16
+	maxKeyIdLen = 255
+)
+
+// Key 集群共享密钥，Id 写入帧头用于选择验证密钥
+type Key struct {
+	Id     string
+	Secret []byte
+}
+
+type keyMaterial struct {
+	id      string
+	signKey []byte
+	aead    cipher.AEAD
+}
+
+// Sealer 签名/加密与验证/解密，可并发使用
+type Sealer struct {
+	active  *keyMaterial
+	keys    map[string]*keyMaterial
+	encrypt bool
+	window  time.Duration
+	nonces  *nonceCache
+}
+
+// New 创建 Sealer，keys 中第一个为当前签名密钥，其余只用于验证，用于密钥轮换；
+// encrypt 为 true 时加密发出的消息并拒绝未加密的消息；window 为允许的时钟偏差和重放检测窗口
+func New(keys []Key, encrypt bool, window time.Duration) (*Sealer, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+	// 窗口为 0 时所有消息都会被判定为过期
+	if window <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWindow, window)
+	}
+	s := &Sealer{
+		keys:    make(map[string]*keyMaterial, len(keys)),
+		encrypt: encrypt,
+		window:  window,
+		nonces:  newNonceCache(window),
+	}
+	for _, key := range keys {
+		material, err := newKeyMaterial(key)
+		if err != nil {
+			return nil, err
+		}
+		if s.active == nil {
+			s.active = material
+		}
+		s.keys[key.Id] = material
+	}
+	return s, nil
+}
+
+func newKeyMaterial(key Key) (*keyMaterial, error) {
+	if len(key.Id) > maxKeyIdLen {
+		return nil, fmt.Errorf("%w: %s", ErrKeyIdTooLong, key.Id)
+	}
+	if len(key.Secret) < minKeySize {
+		return nil, fmt.Errorf("%w: id=%s 长度至少 %d 字节", ErrKeyInvalid, key.Id, minKeySize)
+	}
+	encKey := derive(key.Secret, "gas-encrypt")
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, nonceSize)
+	if err != nil {
+		return nil, err
+	}
+	return &keyMaterial{
+		id:      key.Id,
+		signKey: derive(key.Secret, "gas-sign"),
+		aead:    aead,
+	}, nil
+}
+
+// derive 为签名和加密派生相互独立的子密钥
+func derive(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// IsFrame 数据是否为签名帧
+func IsFrame(data []byte) bool {
+	return len(data) > 0 && data[0] == frameMagic
+}
+
+// Seal 签名（并按配置加密）数据
+func (s *Sealer) Seal(data []byte) ([]byte, error) {
+	key := s.active
+	var flags byte
+	if s.encrypt {
+		flags |= flagEncrypted
+	}
+	header := make([]byte, 0, 3+len(key.id)+8+nonceSize)
+	header = append(header, frameMagic, flags, byte(len(key.id)))
+	header = append(header, key.id...)
+	header = binary.BigEndian.AppendUint64(header, uint64(time.Now().UnixMilli()))
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+
+	payload := data
+	if s.encrypt {
+		payload = key.aead.Seal(nil, nonce, data, header)
+	}
+	frame := make([]byte, 0, len(header)+len(payload)+macSize)
+	frame = append(frame, header...)
+	frame = append(frame, payload...)
+	mac := hmac.New(sha256.New, key.signKey)
+	mac.Write(frame)
+	return mac.Sum(frame), nil
+}
+
+// Open 验证签名、时间戳和 nonce，返回原始数据
+func (s *Sealer) Open(frame []byte) ([]byte, error) {
+	if !IsFrame(frame) {
+		return nil, ErrUnsigned
+	}
+	if len(frame) < 3 {
+		return nil, ErrCorruptFrame
+	}
+	flags, idLen := frame[1], int(frame[2])
+	headerSize := 3 + idLen + 8 + nonceSize
+	if len(frame) < headerSize+macSize {
+		return nil, ErrCorruptFrame
+	}
+	keyId := string(frame[3 : 3+idLen])
+	key, ok := s.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyId)
+	}
+
+	body, sum := frame[:len(frame)-macSize], frame[len(frame)-macSize:]
+	mac := hmac.New(sha256.New, key.signKey)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, ErrBadSignature
+	}
+
+	header := body[:headerSize]
+	timestamp := time.UnixMilli(int64(binary.BigEndian.Uint64(header[3+idLen:])))
+	if skew := time.Since(timestamp); skew > s.window || skew < -s.window {
+		return nil, fmt.Errorf("%w: %v", ErrExpired, skew)
+	}
+	nonce := header[headerSize-nonceSize:]
+	if !s.nonces.add(nonce) {
+		return nil, ErrReplay
+	}
+
+	payload := body[headerSize:]
+	if flags&flagEncrypted == 0 {
+		if s.encrypt {
+			return nil, ErrEncryptRequired
+		}
+		return payload, nil
+	}
+	plain, err := key.aead.Open(nil, nonce, payload, header)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return plain, nil
+}
+
+// nonceCache 记录窗口内出现过的 nonce
+// 时间戳允许前后各偏差一个窗口，一个 nonce 最多在两个窗口内被接受，因此每代保留两个窗口；
+// 使用两代 map 轮换，内存占用与窗口内的消息量成正比
+type nonceCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	current  map[string]struct{}
+	previous map[string]struct{}
+	rotateAt time.Time
+}
+
+func newNonceCache(window time.Duration) *nonceCache {
+	return &nonceCache{
+		ttl:      2 * window,
+		current:  make(map[string]struct{}),
+		previous: make(map[string]struct{}),
+		rotateAt: time.Now().Add(2 * window),
+	}
+}
+
+// add 记录 nonce，已存在时返回 false
+func (c *nonceCache) add(nonce []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now := time.Now(); now.After(c.rotateAt) {
+		c.previous, c.current = c.current, make(map[string]struct{})
+		c.rotateAt = now.Add(c.ttl)
+	}
+	key := string(nonce)
+	if _, ok := c.current[key]; ok {
+		return false
+	}
+	if _, ok := c.previous[key]; ok {
+		return false
+	}
+	c.current[key] = struct{}{}
+	return true
+}
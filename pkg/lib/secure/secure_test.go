@@ -0,0 +1,114 @@
+package secure
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	oldKey = Key{Id: "k1", Secret: []byte("0123456789abcdef-old")}
+	newKey = Key{Id: "k2", Secret: []byte("0123456789abcdef-new")}
+)
+
+func mustNew(t *testing.T, keys []Key, encrypt bool) *Sealer {
+	t.Helper()
+	s, err := New(keys, encrypt, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestSealOpen 测试签名与加密往返
+func TestSealOpen(t *testing.T) {
+	data := []byte(`{"method":"Hello"}`)
+	for _, encrypt := range []bool{false, true} {
+		s := mustNew(t, []Key{oldKey}, encrypt)
+		frame, err := s.Seal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypt == bytes.Contains(frame, data) {
+			t.Fatalf("encrypt=%v: 密文中不应包含明文", encrypt)
+		}
+		plain, err := s.Open(frame)
+		if err != nil || !bytes.Equal(plain, data) {
+			t.Fatalf("encrypt=%v: 解包失败 %q %v", encrypt, plain, err)
+		}
+	}
+}
+
+// TestReject 测试拒绝未签名、篡改和重放的消息
+func TestReject(t *testing.T) {
+	s := mustNew(t, []Key{oldKey}, false)
+	if _, err := s.Open([]byte(`{"method":"Hello"}`)); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("未签名消息应返回 ErrUnsigned，实际 %v", err)
+	}
+
+	frame, _ := s.Seal([]byte("payload"))
+	tampered := bytes.Clone(frame)
+	tampered[len(tampered)-macSize-1] ^= 0xff
+	if _, err := s.Open(tampered); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("篡改的消息应返回 ErrBadSignature，实际 %v", err)
+	}
+
+	if _, err := s.Open(frame); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(frame); !errors.Is(err, ErrReplay) {
+		t.Fatalf("重放的消息应返回 ErrReplay，实际 %v", err)
+	}
+
+	strict := mustNew(t, []Key{oldKey}, true)
+	plainFrame, _ := s.Seal([]byte("payload"))
+	if _, err := strict.Open(plainFrame); !errors.Is(err, ErrEncryptRequired) {
+		t.Fatalf("要求加密时应拒绝明文消息，实际 %v", err)
+	}
+}
+
+// TestExpired 测试时间戳窗口
+func TestExpired(t *testing.T) {
+	s, err := New([]Key{oldKey}, false, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, _ := s.Seal([]byte("payload"))
+	time.Sleep(30 * time.Millisecond)
+	if _, err = s.Open(frame); !errors.Is(err, ErrExpired) {
+		t.Fatalf("过期消息应返回 ErrExpired，实际 %v", err)
+	}
+}
+
+// TestInvalidWindow 测试时间窗口不大于 0 时创建失败
+func TestInvalidWindow(t *testing.T) {
+	for _, window := range []time.Duration{0, -time.Second} {
+		if _, err := New([]Key{oldKey}, false, window); !errors.Is(err, ErrInvalidWindow) {
+			t.Fatalf("window=%v 应返回 ErrInvalidWindow，实际 %v", window, err)
+		}
+	}
+}
+
+// TestKeyRotation 测试密钥轮换期间新旧密钥同时生效
+func TestKeyRotation(t *testing.T) {
+	before := mustNew(t, []Key{oldKey}, true)
+	during := mustNew(t, []Key{newKey, oldKey}, true)
+	after := mustNew(t, []Key{newKey}, true)
+
+	frame, _ := before.Seal([]byte("old"))
+	if _, err := during.Open(frame); err != nil {
+		t.Fatalf("轮换期间应接受旧密钥: %v", err)
+	}
+	frame, _ = during.Seal([]byte("new"))
+	if _, err := after.Open(frame); err != nil {
+		t.Fatalf("轮换期间应使用新密钥签名: %v", err)
+	}
+	frame, _ = before.Seal([]byte("old"))
+	if _, err := after.Open(frame); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("轮换完成后应拒绝旧密钥，实际 %v", err)
+	}
+	if _, err := New([]Key{{Id: "short", Secret: []byte("123")}}, false, time.Minute); !errors.Is(err, ErrKeyInvalid) {
+		t.Fatalf("过短的密钥应返回 ErrKeyInvalid，实际 %v", err)
+	}
+}
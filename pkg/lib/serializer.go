@@ -3,6 +3,7 @@ package lib
 import (
 	"encoding/json"
 	"errors"
+	"sync"
 
 	"github.com/vmihailenco/msgpack/v5"
 	"google.golang.org/protobuf/proto"
@@ -16,6 +17,9 @@ var (
 	ErrNotPBMsg      = errors.New("不是pb消息")
 	ErrJsonPack      = errors.New("json打包错误")
 	ErrJsonUnPack    = errors.New("json解析错误")
+
+	ErrContentTypeInvalid = errors.New("序列化内容类型不能为 0")
+	ErrContentTypeUnknown = errors.New("未注册的序列化内容类型")
 )
 
 var (
@@ -100,3 +104,50 @@ func (p *pbCodec) Marshal(msg interface{}) ([]byte, error) {
 
 	return data, nil
 }
+
+// 序列化器的内容类型，随消息传输，接收方据此选择反序列化方式
+// 0 表示未指定，由接收方使用默认序列化器
+const (
+	ContentTypeJson    uint32 = 1
+	ContentTypeMsgPack uint32 = 2
+	ContentTypePB      uint32 = 3
+)
+
+var (
+	serializerMu sync.RWMutex
+	serializers  = map[uint32]ISerializer{
+		ContentTypeJson:    Json,
+		ContentTypeMsgPack: MsgPack,
+		ContentTypePB:      PB,
+	}
+)
+
+// RegisterSerializer 注册序列化器，内容类型相同时覆盖
+func RegisterSerializer(contentType uint32, serializer ISerializer) error {
+	if contentType == 0 {
+		return ErrContentTypeInvalid
+	}
+	serializerMu.Lock()
+	defer serializerMu.Unlock()
+	serializers[contentType] = serializer
+	return nil
+}
+
+// GetSerializer 根据内容类型获取序列化器，未注册时返回 nil
+func GetSerializer(contentType uint32) ISerializer {
+	serializerMu.RLock()
+	defer serializerMu.RUnlock()
+	return serializers[contentType]
+}
+
+// ContentTypeOf 获取序列化器注册的内容类型，未注册时返回 0
+func ContentTypeOf(serializer ISerializer) uint32 {
+	serializerMu.RLock()
+	defer serializerMu.RUnlock()
+	for contentType, s := range serializers {
+		if s == serializer {
+			return contentType
+		}
+	}
+	return 0
+}
@@ -13,6 +13,8 @@ type IMessageQue interface {
 	Publish(subject string, data []byte) error
 	// Request 发送请求并等待回复（同步 RPC 模式）
 	Request(subject string, data []byte, timeout time.Duration) ([]byte, error)
+	// RequestWithContext 发送请求并等待回复，ctx 取消或超时时立即返回
+	RequestWithContext(ctx context.Context, subject string, data []byte) ([]byte, error)
 	// Subscribe 订阅主题，接收消息（非阻塞，通过回调处理）
 	Subscribe(subject string, subscriber ISubscriber) (ISubscription, error)
 	// Shutdown 关闭集群连接
@@ -30,6 +32,49 @@ type ISubscriber interface {
 	//  @Description:
 	//  @param request
 	//  @param response  error 回复成功/失败
+	//  @return error 处理失败，持久化投递的实现据此决定确认还是重新投递
 	//
-	OnMessage(request []byte, response func(data []byte) error)
+	OnMessage(request []byte, response func(data []byte) error) error
+}
+
+// IDurable 持久化投递的消息队列实现，消息可能在写入很久之后才投递，处理失败时会重复投递
+type IDurable interface {
+	Durable() bool
+}
+
+// IPeerResolver 解析主题对应节点的地址，由集群实现
+type IPeerResolver interface {
+	// LocalAddress 本节点的监听地址
+	LocalAddress() string
+	// Resolve 主题对应节点的地址，节点不存在时返回 false
+	Resolve(subject string) (address string, ok bool)
+}
+
+// IPeerTransport 节点之间直连的消息队列实现，集群在 Run 之前注入地址解析
+type IPeerTransport interface {
+	SetResolver(resolver IPeerResolver)
+}
+
+// ConnEventType 消息队列连接事件类型
+type ConnEventType int
+
+const (
+	// ConnDisconnected 与服务器的连接断开，客户端正在重连
+	ConnDisconnected ConnEventType = iota + 1
+	// ConnReconnected 重新连接成功，订阅已恢复
+	ConnReconnected
+	// ConnClosed 连接已关闭，不再重连
+	ConnClosed
+)
+
+// ConnEvent 消息队列连接事件
+type ConnEvent struct {
+	Type ConnEventType
+	Url  string // 服务器地址
+	Err  error  // 断开或关闭的原因，可能为空
+}
+
+// IConnEventSource 报告与服务器连接状态变化的消息队列实现，集群在 Run 之前注入处理函数
+type IConnEventSource interface {
+	SetConnEventHandler(handler func(event *ConnEvent))
 }
@@ -0,0 +1,81 @@
+package inproc
+
+import (
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/messageQue"
+
+	"go.uber.org/zap"
+)
+
+var (
+	brokersMu sync.Mutex
+	brokers   = make(map[string]*broker)
+)
+
+// getBroker 获取指定名称的消息代理，不存在时创建
+func getBroker(name string) *broker {
+	brokersMu.Lock()
+	defer brokersMu.Unlock()
+	b, ok := brokers[name]
+	if !ok {
+		b = &broker{subs: make(map[*subscription]struct{})}
+		brokers[name] = b
+	}
+	return b
+}
+
+// message 投递给订阅者的消息，reply 为空表示无需回复
+type message struct {
+	data  []byte
+	reply func(data []byte) error
+}
+
+// broker 按主题在内存中路由消息
+type broker struct {
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+func (b *broker) add(sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+}
+
+func (b *broker) remove(sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sub)
+}
+
+// publish 投递给所有匹配的订阅，返回接收消息的订阅数
+// 每个订阅收到独立的数据副本，发布方之后修改 data 不影响订阅方
+func (b *broker) publish(subject string, data []byte, reply func(data []byte) error) int {
+	tokens := messageQue.SplitSubject(subject)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	delivered := 0
+	for sub := range b.subs {
+		if !sub.match(tokens) {
+			continue
+		}
+		if sub.push(&message{data: clone(data), reply: reply}) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+func clone(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(data)), data...)
+}
+
+// logSlowConsumer 订阅处理过慢时记录日志
+func logSlowConsumer(subject string, limit int) {
+	glog.Warn("inproc: 订阅待处理消息超过上限，丢弃消息", zap.String("subject", subject), zap.Int("limit", limit))
+}
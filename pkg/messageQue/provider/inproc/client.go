@@ -0,0 +1,208 @@
+package inproc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+	"github.com/dzm2020/gas/pkg/messageQue"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrClientClosed   = errors.New("inproc: 客户端已关闭")
+	ErrNoResponders   = errors.New("inproc: 没有订阅者响应")
+	ErrTimeout        = errors.New("inproc: 请求超时")
+	ErrInvalidSubject = errors.New("inproc: 主题不合法")
+)
+
+func init() {
+	_ = messageQue.GetFactoryMgr().Register("inproc", func(args ...any) (iface.IMessageQue, error) {
+		cfg := defaultConfig()
+		if len(args) > 0 && args[0] != nil {
+			vp := viper.New()
+			vp.Set("", args[0])
+			if err := vp.UnmarshalKey("", cfg); err != nil {
+				return nil, err
+			}
+		}
+		return New(cfg), nil
+	})
+}
+
+// New 创建进程内消息队列，连接到同名的消息代理
+func New(cfg *Config) *Client {
+	if cfg == nil {
+		cfg = defaultConfig()
+	}
+	if cfg.PendingLimit <= 0 {
+		cfg.PendingLimit = defaultConfig().PendingLimit
+	}
+	return &Client{
+		cfg:    cfg,
+		broker: getBroker(cfg.Broker),
+		subs:   make(map[*subscription]struct{}),
+	}
+}
+
+// Client 进程内消息队列，同一进程内连接到同一消息代理的客户端互相可见，
+// 用于单进程部署和不依赖外部服务的集成测试
+type Client struct {
+	stopper.Stopper
+	cfg    *Config
+	broker *broker
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+}
+
+func (c *Client) Run(ctx context.Context) error {
+	return nil
+}
+
+func (c *Client) Publish(subject string, data []byte) error {
+	if c.IsStop() {
+		return ErrClientClosed
+	}
+	c.broker.publish(subject, data, nil)
+	return nil
+}
+
+func (c *Client) Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.RequestWithContext(ctx, subject, data)
+}
+
+// RequestWithContext 发送请求并等待第一个回复
+func (c *Client) RequestWithContext(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	if c.IsStop() {
+		return nil, ErrClientClosed
+	}
+	replies := make(chan []byte, 1)
+	reply := func(data []byte) error {
+		select {
+		case replies <- clone(data):
+		default:
+		}
+		return nil
+	}
+	if c.broker.publish(subject, data, reply) == 0 {
+		return nil, xerror.Wrapf(ErrNoResponders, "subject:%s", subject)
+	}
+	select {
+	case data = <-replies:
+		return data, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, xerror.Wrapf(ErrTimeout, "subject:%s", subject)
+		}
+		return nil, xerror.Wrapf(ctx.Err(), "subject:%s", subject)
+	}
+}
+
+// Subscribe 订阅主题，支持 * 和 > 通配符，同一订阅的消息按发布顺序串行处理
+func (c *Client) Subscribe(subject string, subscriber iface.ISubscriber) (iface.ISubscription, error) {
+	if c.IsStop() {
+		return nil, ErrClientClosed
+	}
+	if subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	sub := &subscription{
+		client:     c,
+		subject:    subject,
+		pattern:    messageQue.SplitSubject(subject),
+		subscriber: subscriber,
+		queue:      make(chan *message, c.cfg.PendingLimit),
+		done:       make(chan struct{}),
+	}
+	c.mu.Lock()
+	c.subs[sub] = struct{}{}
+	c.mu.Unlock()
+	c.broker.add(sub)
+	grs.Go(func(ctx context.Context) {
+		sub.loop()
+	})
+	return sub, nil
+}
+
+func (c *Client) Shutdown(ctx context.Context) error {
+	if !c.Stop() {
+		return nil
+	}
+	c.mu.Lock()
+	subs := make([]*subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.mu.Unlock()
+	for _, sub := range subs {
+		_ = sub.Unsubscribe()
+	}
+	return nil
+}
+
+// subscription 订阅，消息在独立的协程中按顺序交给订阅者
+type subscription struct {
+	client     *Client
+	subject    string
+	pattern    []string
+	subscriber iface.ISubscriber
+	queue      chan *message
+	done       chan struct{}
+	once       sync.Once
+}
+
+func (s *subscription) match(tokens []string) bool {
+	return messageQue.MatchSubject(s.pattern, tokens)
+}
+
+// push 放入待处理队列，队列已满或订阅已取消时返回 false
+func (s *subscription) push(msg *message) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.queue <- msg:
+		return true
+	default:
+		logSlowConsumer(s.subject, cap(s.queue))
+		return false
+	}
+}
+
+func (s *subscription) loop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.queue:
+			response := func(data []byte) error {
+				if msg.reply == nil {
+					return nil
+				}
+				return msg.reply(data)
+			}
+			_ = s.subscriber.OnMessage(msg.data, response)
+		}
+	}
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.once.Do(func() {
+		s.client.broker.remove(s)
+		s.client.mu.Lock()
+		delete(s.client.subs, s)
+		s.client.mu.Unlock()
+		close(s.done)
+	})
+	return nil
+}
@@ -0,0 +1,15 @@
+package inproc
+
+// Config 进程内消息队列配置
+type Config struct {
+	Broker       string `json:"broker"`       // 消息代理名称，同一进程内使用相同名称的节点组成集群
+	PendingLimit int    `json:"pendingLimit"` // 每个订阅待处理消息的上限，超过后丢弃新消息
+}
+
+// defaultConfig 返回默认配置
+func defaultConfig() *Config {
+	return &Config{
+		Broker:       "default",
+		PendingLimit: 65536,
+	}
+}
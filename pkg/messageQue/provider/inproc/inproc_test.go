@@ -0,0 +1,98 @@
+package inproc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type subscriberFunc func(request []byte, response func(data []byte) error)
+
+func (f subscriberFunc) OnMessage(request []byte, response func(data []byte) error) error {
+	f(request, response)
+	return nil
+}
+
+func newClient(t *testing.T, broker string) *Client {
+	client := New(&Config{Broker: broker})
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Shutdown(context.Background())
+	})
+	return client
+}
+
+// TestPublishSubscribe 测试不同客户端之间的发布订阅和取消订阅
+func TestPublishSubscribe(t *testing.T) {
+	a, b := newClient(t, t.Name()), newClient(t, t.Name())
+	received := make(chan string, 10)
+	sub, err := b.Subscribe("game.*.move", subscriberFunc(func(request []byte, _ func([]byte) error) {
+		received <- string(request)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("hello")
+	if err = a.Publish("game.room1.move", data); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'x'
+	select {
+	case got := <-received:
+		if got != "hello" {
+			t.Fatalf("want hello, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("未收到消息")
+	}
+
+	_ = sub.Unsubscribe()
+	_ = a.Publish("game.room1.move", []byte("again"))
+	select {
+	case got := <-received:
+		t.Fatalf("取消订阅后仍收到消息: %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestRequest 测试请求回复、超时和无订阅者
+func TestRequest(t *testing.T) {
+	a, b := newClient(t, t.Name()), newClient(t, t.Name())
+	_, err := b.Subscribe("echo", subscriberFunc(func(request []byte, response func([]byte) error) {
+		_ = response(append([]byte("re:"), request...))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = b.Subscribe("silent", subscriberFunc(func([]byte, func([]byte) error) {}))
+
+	reply, err := a.Request("echo", []byte("ping"), time.Second)
+	if err != nil || string(reply) != "re:ping" {
+		t.Fatalf("want re:ping, got %s (err=%v)", reply, err)
+	}
+	if _, err = a.Request("silent", nil, 50*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("want ErrTimeout, got %v", err)
+	}
+	if _, err = a.Request("nobody", nil, time.Second); !errors.Is(err, ErrNoResponders) {
+		t.Fatalf("want ErrNoResponders, got %v", err)
+	}
+}
+
+// TestBrokerIsolation 测试不同消息代理之间互不可见
+func TestBrokerIsolation(t *testing.T) {
+	a, b := newClient(t, t.Name()+"-a"), newClient(t, t.Name()+"-b")
+	_, _ = b.Subscribe("echo", subscriberFunc(func(request []byte, response func([]byte) error) {
+		_ = response(request)
+	}))
+	if _, err := a.Request("echo", nil, time.Second); !errors.Is(err, ErrNoResponders) {
+		t.Fatalf("want ErrNoResponders, got %v", err)
+	}
+	_ = a.Shutdown(context.Background())
+	if err := a.Publish("echo", nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("want ErrClientClosed, got %v", err)
+	}
+}
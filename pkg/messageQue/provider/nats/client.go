@@ -2,8 +2,13 @@ package nats
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
 	"github.com/dzm2020/gas/pkg/lib/stopper"
 	"github.com/dzm2020/gas/pkg/lib/xerror"
 	"github.com/dzm2020/gas/pkg/messageQue"
@@ -11,8 +16,11 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
+var ErrClientClosed = errors.New("nats: 客户端已关闭")
+
 func init() {
 	_ = messageQue.GetFactoryMgr().Register("nats", func(args ...any) (iface.IMessageQue, error) {
 		config := args[0].(map[string]interface{})
@@ -31,72 +39,274 @@ func New(cfg *Config) *Client {
 	if cfg == nil {
 		cfg = defaultConfig()
 	}
-	return &Client{
-		cfg: cfg,
+	client := &Client{
+		cfg:  cfg,
+		subs: make(map[*subscription]struct{}),
 	}
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	return client
 }
 
+var (
+	_ iface.IMessageQue      = (*Client)(nil)
+	_ iface.IConnEventSource = (*Client)(nil)
+)
+
 type Client struct {
 	stopper.Stopper
-	cfg     *Config
-	pool    *ConnPool  // 连接池，用于 Publish 和 Request
+	cfg       *Config
+	pool      *ConnPool  // 共享连接，用于 Publish 和 Request
+	publisher *publisher // 开启 AsyncPublish 时的发送队列
+	stats     stats
+	handler   func(event *iface.ConnEvent) // 连接事件处理，由集群在 Run 之前设置
+	reopening atomic.Bool                  // 正在重新创建订阅连接，同一时间只有一个协程重试，新连接安装后清除
+
+	mu      sync.Mutex
 	subConn *nats.Conn // 专门的订阅连接
+	js      *jetStream // 开启 JetStream 时的持久化投递
+	subs    map[*subscription]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// SetConnEventHandler 设置订阅连接的事件处理，连接池中的连接只记录日志
+func (n *Client) SetConnEventHandler(handler func(event *iface.ConnEvent)) {
+	n.handler = handler
+}
+
+// Durable 开启 JetStream 时消息写入流后投递，节点离线期间的消息在重新订阅后才到达
+func (n *Client) Durable() bool {
+	return n.cfg.JetStream
 }
 
 func (n *Client) Run(ctx context.Context) (err error) {
-	n.pool = NewPool(n.cfg)
-	n.subConn, err = n.pool.get()
+	if n.pool, err = NewPool(n.cfg); err != nil {
+		return
+	}
+	if _, err = n.connect(ctx); err != nil {
+		n.pool.close()
+		return
+	}
+	if n.cfg.AsyncPublish {
+		n.publisher = newPublisher(n, n.cfg)
+	}
 	return
 }
 
-func (n *Client) Subscribe(subject string, subscriber iface.ISubscriber) (iface.ISubscription, error) {
-	conn := n.subConn
-	return conn.Subscribe(subject, func(m *nats.Msg) {
-		response := func(data []byte) error {
-			if m.Reply == "" {
-				return nil
+// connect 创建订阅连接并在新连接上恢复已有的订阅
+// 连接断开后由 nats.go 自动重连并重新订阅，只有连接关闭（如重连次数用尽）后才需要重新创建
+func (n *Client) connect(ctx context.Context) (*nats.Conn, error) {
+	conn, err := n.pool.createConn(connHandlers(n.pool.servers, n.onConnEvent)...)
+	if err != nil {
+		return nil, err
+	}
+	var js *jetStream
+	if n.cfg.JetStream {
+		if js, err = newJetStream(ctx, conn, n.cfg); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.IsStop() {
+		conn.Close()
+		return nil, ErrClientClosed
+	}
+	// 安装前已关闭的连接的关闭事件会被忽略，由调用方重试
+	if conn.IsClosed() {
+		return nil, nats.ErrConnectionClosed
+	}
+	n.subConn, n.js = conn, js
+	// 与安装在同一把锁内清除，新连接随后的关闭事件可以再次触发重建
+	n.reopening.Store(false)
+	for sub := range n.subs {
+		sub.unbind()
+		if err = sub.bind(conn, js); err != nil {
+			glog.Error("nats: 恢复订阅失败", zap.String("subject", sub.subject), zap.Error(err))
+		}
+	}
+	return conn, nil
+}
+
+// onConnEvent 只处理当前订阅连接的事件，创建失败被关闭的连接和已被替换的旧连接的事件忽略
+func (n *Client) onConnEvent(conn *nats.Conn, event *iface.ConnEvent) {
+	n.mu.Lock()
+	current := conn == n.subConn
+	n.mu.Unlock()
+	if !current {
+		return
+	}
+	if event.Type == iface.ConnClosed && !n.IsStop() && n.reopening.CompareAndSwap(false, true) {
+		grs.Go(func(ctx context.Context) {
+			n.reopen()
+		})
+	}
+	if n.handler != nil {
+		n.handler(event)
+	}
+}
+
+// reopen 订阅连接关闭后每隔 ReconnectWait 重新创建，直到成功或客户端关闭
+func (n *Client) reopen() {
+	wait := n.cfg.ReconnectWait
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		conn, err := n.connect(n.ctx)
+		if err == nil {
+			url := conn.ConnectedUrlRedacted()
+			glog.Info("nats订阅连接已重新创建", zap.String("url", url))
+			if n.handler != nil {
+				n.handler(&iface.ConnEvent{Type: iface.ConnReconnected, Url: url})
 			}
-			return m.Respond(data)
+			return
 		}
-		subscriber.OnMessage(m.Data, response)
-	})
+		if errors.Is(err, ErrClientClosed) {
+			return
+		}
+		glog.Warn("nats重新创建订阅连接失败", zap.Error(err))
+	}
+}
+
+// Subscribe 订阅主题；开启 JetStream 时同时订阅主题的持久消费者，请求仍通过核心 NATS 订阅接收
+func (n *Client) Subscribe(subject string, subscriber iface.ISubscriber) (iface.ISubscription, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.IsStop() {
+		return nil, ErrClientClosed
+	}
+	sub := &subscription{client: n, subject: subject, subscriber: subscriber}
+	if err := sub.bind(n.subConn, n.js); err != nil {
+		return nil, err
+	}
+	n.subs[sub] = struct{}{}
+	// 订阅在其他连接上立即可见，避免随后发出的请求没有响应者
+	if err := n.subConn.Flush(); err != nil {
+		glog.Warn("nats等待订阅生效失败", zap.String("subject", subject), zap.Error(err))
+	}
+	return sub, nil
+}
+
+func (n *Client) jetStream() *jetStream {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.js
 }
 
+// Publish 发布消息；开启 AsyncPublish 时放入发送队列后立即返回
 func (n *Client) Publish(subject string, data []byte) error {
-	conn, err := n.pool.get()
+	if n.IsStop() {
+		return ErrClientClosed
+	}
+	if n.publisher != nil {
+		return n.publisher.enqueue(subject, data)
+	}
+	return n.publish(subject, data, false)
+}
+
+// publish 选择连接发送，JetStream 模式下写入流
+func (n *Client) publish(subject string, data []byte, async bool) error {
+	pc, err := n.pool.get()
 	if err != nil {
-		return xerror.Wrapf(err, "从连接池获取连接失败, subject:%s", subject)
+		n.stats.publishes.Add(1)
+		n.stats.publishErrors.Add(1)
+		return xerror.Wrapf(err, "获取连接失败, subject:%s", subject)
 	}
-	defer n.pool.put(conn)
+	return n.publishOn(pc, subject, data, async)
+}
 
-	return conn.Publish(subject, data)
+// publishOn 在指定连接上发送
+func (n *Client) publishOn(pc *poolConn, subject string, data []byte, async bool) (err error) {
+	defer func() {
+		n.stats.publishes.Add(1)
+		if err != nil {
+			n.stats.publishErrors.Add(1)
+		}
+	}()
+	if js := n.jetStream(); js != nil {
+		if err = js.publish(pc.js, subject, data, async); err != nil {
+			return xerror.Wrapf(err, "subject:%s", subject)
+		}
+		return nil
+	}
+	return pc.conn.Publish(subject, data)
 }
 
 func (n *Client) Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
-	conn, err := n.pool.get()
+	return n.request(subject, func(conn *nats.Conn) (*nats.Msg, error) {
+		return conn.Request(subject, data, timeout)
+	})
+}
+
+func (n *Client) RequestWithContext(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	return n.request(subject, func(conn *nats.Conn) (*nats.Msg, error) {
+		return conn.RequestWithContext(ctx, subject, data)
+	})
+}
+
+// request 选择连接发送请求，连接在等待回复期间不被独占，同一连接上的请求通过各自的回复主题区分
+func (n *Client) request(subject string, do func(conn *nats.Conn) (*nats.Msg, error)) ([]byte, error) {
+	pc, err := n.pool.get()
 	if err != nil {
-		return nil, xerror.Wrapf(err, "从连接池获取连接失败, subject:%s", subject)
+		return nil, xerror.Wrapf(err, "获取连接失败, subject:%s", subject)
 	}
-	defer n.pool.put(conn)
+	n.stats.requests.Add(1)
+	n.stats.inFlight.Add(1)
+	defer n.stats.inFlight.Add(-1)
 
-	ret, err := conn.Request(subject, data, timeout)
+	ret, err := do(pc.conn)
 	if err != nil {
+		n.stats.requestErrors.Add(1)
 		return nil, xerror.Wrapf(err, "subject:%s", subject)
 	}
 	return ret.Data, nil
 }
 
+// Flush 等待异步发布队列中已有的消息发送完成，并等待服务器确认收到所有连接上已发送的数据
+func (n *Client) Flush(ctx context.Context) error {
+	if n.publisher != nil {
+		if err := n.publisher.flush(ctx); err != nil {
+			return err
+		}
+	}
+	return n.pool.flush(ctx)
+}
+
+// Shutdown 关闭订阅，发送完异步发布队列中的消息后关闭连接
 func (n *Client) Shutdown(ctx context.Context) error {
 	if !n.Stop() {
 		return nil
 	}
-	if n.subConn != nil && !n.subConn.IsClosed() {
-		n.subConn.Close()
-		n.subConn = nil
+	n.cancel()
+	n.mu.Lock()
+	for sub := range n.subs {
+		sub.unbind()
 	}
-	// 关闭连接池
-	if n.pool != nil {
-		n.pool.close()
+	n.subs = make(map[*subscription]struct{})
+	conn := n.subConn
+	n.subConn = nil
+	n.mu.Unlock()
+	if conn != nil && !conn.IsClosed() {
+		conn.Close()
+	}
+	if n.pool == nil {
+		return nil
+	}
+	if n.publisher != nil {
+		n.publisher.close(ctx)
+	}
+	if err := n.pool.flush(ctx); err != nil {
+		glog.Warn("nats关闭前发送缓冲区中的数据失败", zap.Error(err))
 	}
+	n.pool.close()
 	return nil
 }
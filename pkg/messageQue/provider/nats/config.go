@@ -1,6 +1,7 @@
 package nats
 
 import (
+	"errors"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -21,7 +22,31 @@ type Config struct {
 	Token                string        `json:"token"`                // Token 认证
 	DisableNoEcho        bool          `json:"disableNoEcho"`        // 禁用 NoEcho
 	RetryOnFailedConnect bool          `json:"retryOnFailedConnect"` // 连接失败时重试
-	PoolSize             int           `json:"poolSize"`             // 连接池大小，默认 10
+	PoolSize             int           `json:"poolSize"`             // 连接数，NATS 连接支持多路复用，所有请求和发布共享这些连接，默认 4
+	HealthCheckInterval  time.Duration `json:"healthCheckInterval"`  // 健康检查间隔，替换已关闭的连接并测量往返时间
+	AsyncPublish         bool          `json:"asyncPublish"`         // Publish 放入发送队列后立即返回，由后台协程批量发送
+	PublishQueueSize     int           `json:"publishQueueSize"`     // 异步发布队列大小，队列已满时 Publish 返回错误
+	PublishBatch         int           `json:"publishBatch"`         // 异步发布每批发送的最大消息数，同一批消息合并写入连接
+	InboxPrefix          string        `json:"inboxPrefix"`          // 回复主题前缀，默认 _INBOX，按主题授权时用于限定回复权限
+
+	// TLS 和凭证认证，文件均为 PEM 或 NATS 工具生成的格式
+	TLSCAFile    string `json:"tlsCAFile"`    // CA 证书，用于校验服务器证书
+	TLSCertFile  string `json:"tlsCertFile"`  // 客户端证书，与 TLSKeyFile 一起用于双向 TLS
+	TLSKeyFile   string `json:"tlsKeyFile"`   // 客户端私钥
+	CredsFile    string `json:"credsFile"`    // .creds 文件（用户 JWT 和 NKey 种子）
+	NKeySeedFile string `json:"nkeySeedFile"` // NKey 种子文件，不使用 JWT 时的 NKey 认证
+
+	// JetStream 持久化投递，节点短暂断开期间发给它的消息不会丢失；消息可能超出签名的时间窗口或重复投递，不能与集群消息签名同时使用
+	JetStream         bool          `json:"jetStream"`         // 开启后 Publish 写入流，由每个订阅主题的持久消费者至少投递一次；Request 仍使用核心 NATS
+	Stream            string        `json:"stream"`            // 流名称
+	StreamPrefix      string        `json:"streamPrefix"`      // 写入流的主题前缀，流捕获 前缀.> 的所有主题，不能与集群主题重叠
+	Retention         string        `json:"retention"`         // 保留策略：limits、interest（所有消费者确认后删除）、workqueue（任一消费者确认后删除）
+	MaxAge            time.Duration `json:"maxAge"`            // 消息最长保留时间，0 表示不限
+	Storage           string        `json:"storage"`           // 存储类型：file、memory
+	Replicas          int           `json:"replicas"`          // 流的副本数
+	AckWait           time.Duration `json:"ackWait"`           // 消息投递后未确认超过该时间则重新投递
+	MaxDeliver        int           `json:"maxDeliver"`        // 每条消息的最大投递次数，-1 表示不限
+	InactiveThreshold time.Duration `json:"inactiveThreshold"` // 持久消费者无人订阅超过该时间后删除，0 表示不删除
 }
 
 // defaultConfig 返回默认配置
@@ -36,12 +61,29 @@ func defaultConfig() *Config {
 		MaxPingsOut:          2,
 		AllowReconnect:       true,
 		RetryOnFailedConnect: false,
-		PoolSize:             10, // 默认连接池大小为 10
+		PoolSize:             4,
+		HealthCheckInterval:  5 * time.Second,
+		PublishQueueSize:     65536,
+		PublishBatch:         256,
+		Stream:               "GAS",
+		StreamPrefix:         "_gas_js",
+		Retention:            "interest",
+		MaxAge:               time.Hour,
+		Storage:              "file",
+		Replicas:             1,
+		AckWait:              30 * time.Second,
+		MaxDeliver:           5,
+		InactiveThreshold:    24 * time.Hour,
 	}
 }
 
+var (
+	ErrTLSKeyPair   = errors.New("nats: 客户端证书和私钥必须同时配置")
+	ErrMultipleAuth = errors.New("nats: credsFile 和 nkeySeedFile 只能配置一个")
+)
+
 // toOptions 将 Config 转换为 nats.Option 列表
-func toOptions(cfg *Config) []nats.Option {
+func toOptions(cfg *Config) ([]nats.Option, error) {
 	var natsOpts []nats.Option
 
 	if cfg.Name != "" {
@@ -84,5 +126,33 @@ func toOptions(cfg *Config) []nats.Option {
 		natsOpts = append(natsOpts, nats.RetryOnFailedConnect(true))
 	}
 
-	return natsOpts
+	if cfg.InboxPrefix != "" {
+		natsOpts = append(natsOpts, nats.CustomInboxPrefix(cfg.InboxPrefix))
+	}
+
+	if cfg.TLSCAFile != "" {
+		natsOpts = append(natsOpts, nats.RootCAs(cfg.TLSCAFile))
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, ErrTLSKeyPair
+	}
+	if cfg.TLSCertFile != "" {
+		natsOpts = append(natsOpts, nats.ClientCert(cfg.TLSCertFile, cfg.TLSKeyFile))
+	}
+
+	if cfg.CredsFile != "" && cfg.NKeySeedFile != "" {
+		return nil, ErrMultipleAuth
+	}
+	if cfg.CredsFile != "" {
+		natsOpts = append(natsOpts, nats.UserCredentials(cfg.CredsFile))
+	}
+	if cfg.NKeySeedFile != "" {
+		opt, err := nats.NkeyOptionFromSeed(cfg.NKeySeedFile)
+		if err != nil {
+			return nil, err
+		}
+		natsOpts = append(natsOpts, opt)
+	}
+
+	return natsOpts, nil
 }
@@ -0,0 +1,46 @@
+package nats
+
+import (
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// connHandlers 连接断开、重连和关闭时记录日志，notify 非空时同时通知发生事件的连接
+func connHandlers(servers string, notify func(conn *nats.Conn, event *iface.ConnEvent)) []nats.Option {
+	emit := func(conn *nats.Conn, typ iface.ConnEventType, err error) {
+		url := conn.ConnectedUrlRedacted()
+		if url == "" {
+			url = servers
+		}
+		event := &iface.ConnEvent{Type: typ, Url: url, Err: err}
+		switch typ {
+		case iface.ConnDisconnected:
+			glog.Warn("nats连接断开", zap.String("name", conn.Opts.Name), zap.String("url", url), zap.Error(err))
+		case iface.ConnReconnected:
+			glog.Info("nats重新连接", zap.String("name", conn.Opts.Name), zap.String("url", url))
+		case iface.ConnClosed:
+			glog.Info("nats连接关闭", zap.String("name", conn.Opts.Name), zap.String("url", url), zap.Error(err))
+		}
+		if notify != nil {
+			notify(conn, event)
+		}
+	}
+	return []nats.Option{
+		nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+			// 主动关闭时也会回调，只通知关闭
+			if conn.IsClosed() {
+				return
+			}
+			emit(conn, iface.ConnDisconnected, err)
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			emit(conn, iface.ConnReconnected, nil)
+		}),
+		nats.ClosedHandler(func(conn *nats.Conn) {
+			emit(conn, iface.ConnClosed, conn.LastError())
+		}),
+	}
+}
@@ -0,0 +1,148 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidRetention = errors.New("nats: 不支持的保留策略")
+	ErrInvalidStorage   = errors.New("nats: 不支持的存储类型")
+)
+
+// jetStream 持久化投递：Publish 写入流，每个订阅主题对应一个持久消费者，
+// 订阅者处理完成后确认，处理时 panic 或超过 AckWait 未确认的消息重新投递，最多投递 MaxDeliver 次
+type jetStream struct {
+	cfg    *Config
+	stream jetstream.Stream
+}
+
+// newJetStream 创建或更新流
+func newJetStream(ctx context.Context, conn *nats.Conn, cfg *Config) (*jetStream, error) {
+	streamCfg, err := toStreamConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := js.CreateOrUpdateStream(ctx, streamCfg)
+	if err != nil {
+		return nil, fmt.Errorf("nats: 创建流 %s 失败: %w", cfg.Stream, err)
+	}
+	return &jetStream{cfg: cfg, stream: stream}, nil
+}
+
+func toStreamConfig(cfg *Config) (jetstream.StreamConfig, error) {
+	streamCfg := jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: []string{cfg.StreamPrefix + ".>"},
+		MaxAge:   cfg.MaxAge,
+		Replicas: cfg.Replicas,
+	}
+	switch cfg.Retention {
+	case "", "limits":
+		streamCfg.Retention = jetstream.LimitsPolicy
+	case "interest":
+		streamCfg.Retention = jetstream.InterestPolicy
+	case "workqueue":
+		streamCfg.Retention = jetstream.WorkQueuePolicy
+	default:
+		return streamCfg, fmt.Errorf("%w: %s", ErrInvalidRetention, cfg.Retention)
+	}
+	switch cfg.Storage {
+	case "", "file":
+		streamCfg.Storage = jetstream.FileStorage
+	case "memory":
+		streamCfg.Storage = jetstream.MemoryStorage
+	default:
+		return streamCfg, fmt.Errorf("%w: %s", ErrInvalidStorage, cfg.Storage)
+	}
+	return streamCfg, nil
+}
+
+// subject 主题在流中对应的主题
+func (j *jetStream) subject(subject string) string {
+	return j.cfg.StreamPrefix + "." + subject
+}
+
+// publish 写入流；async 为 false 时等待服务器确认，为 true 时不等待，确认失败由连接池记录日志
+func (j *jetStream) publish(js jetstream.JetStream, subject string, data []byte, async bool) error {
+	if async {
+		_, err := js.PublishAsync(j.subject(subject), data)
+		return err
+	}
+	ctx, cancel := j.timeout()
+	defer cancel()
+	_, err := js.Publish(ctx, j.subject(subject), data)
+	return err
+}
+
+func (j *jetStream) timeout() (context.Context, context.CancelFunc) {
+	if j.cfg.Timeout > 0 {
+		return context.WithTimeout(context.Background(), j.cfg.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+var durableReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_", "/", "_", "\\", "_")
+
+// durableName 主题对应的持久消费者名称，节点的主题包含节点 ID，每个节点有自己的消费者
+func durableName(subject string) string {
+	return durableReplacer.Replace(subject)
+}
+
+// subscribe 创建或恢复主题的持久消费者，节点断开期间写入流的消息在重新订阅后投递
+func (j *jetStream) subscribe(subject string, subscriber iface.ISubscriber) (jetstream.ConsumeContext, error) {
+	ctx, cancel := j.timeout()
+	defer cancel()
+	consumer, err := j.stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:           durableName(subject),
+		FilterSubject:     j.subject(subject),
+		DeliverPolicy:     jetstream.DeliverAllPolicy,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		AckWait:           j.cfg.AckWait,
+		MaxDeliver:        j.cfg.MaxDeliver,
+		InactiveThreshold: j.cfg.InactiveThreshold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nats: 创建持久消费者失败, subject:%s: %w", subject, err)
+	}
+	return consumer.Consume(func(msg jetstream.Msg) {
+		j.handle(subject, msg, subscriber)
+	})
+}
+
+// handle 交给订阅者处理，成功后确认；处理失败或 panic 时通知服务器立即重新投递
+func (j *jetStream) handle(subject string, msg jetstream.Msg, subscriber iface.ISubscriber) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("nats: 处理持久消息时 panic，等待重新投递", zap.String("subject", subject), zap.Any("panic", r))
+			j.nak(subject, msg)
+		}
+	}()
+	if err := subscriber.OnMessage(msg.Data(), func(data []byte) error { return nil }); err != nil {
+		glog.Warn("nats: 处理持久消息失败，等待重新投递", zap.String("subject", subject), zap.Error(err))
+		j.nak(subject, msg)
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		glog.Warn("nats: 持久消息确认失败，等待重新投递", zap.String("subject", subject), zap.Error(err))
+	}
+}
+
+func (j *jetStream) nak(subject string, msg jetstream.Msg) {
+	if err := msg.Nak(); err != nil {
+		glog.Warn("nats: 持久消息 Nak 失败", zap.String("subject", subject), zap.Error(err))
+	}
+}
@@ -0,0 +1,602 @@
+package nats
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mqIface "github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nkeys"
+)
+
+type subscriberFunc func(request []byte, response func(data []byte) error)
+
+func (f subscriberFunc) OnMessage(request []byte, response func(data []byte) error) error {
+	f(request, response)
+	return nil
+}
+
+// runServer 启动开启 JetStream 的内嵌 nats-server
+func runServer(t *testing.T) string {
+	t.Helper()
+	return startServer(t, &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	}).ClientURL()
+}
+
+func startServer(t *testing.T, opts *server.Options) *server.Server {
+	t.Helper()
+	opts.NoLog = true
+	opts.NoSigs = true
+	s, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server 启动超时")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newJetStreamClient(t *testing.T, url string) *Client {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.Servers = []string{url}
+	cfg.PoolSize = 2
+	cfg.JetStream = true
+	cfg.AckWait = time.Second
+	client := New(cfg)
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Shutdown(context.Background())
+	})
+	return client
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case data := <-ch:
+		return data
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到消息")
+		return ""
+	}
+}
+
+// TestJetStreamDurable 测试节点断开期间发给它的消息在重新订阅后投递，已确认的消息不再投递
+func TestJetStreamDurable(t *testing.T) {
+	url := runServer(t)
+	a, b := newJetStreamClient(t, url), newJetStreamClient(t, url)
+
+	received := make(chan string, 10)
+	subscriber := subscriberFunc(func(request []byte, _ func([]byte) error) {
+		received <- string(request)
+	})
+	sub, err := b.Subscribe("gas.2", subscriber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sub.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.Publish("gas.2", []byte("offline")); err != nil {
+		t.Fatal(err)
+	}
+	if sub, err = b.Subscribe("gas.2", subscriber); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, received); got != "offline" {
+		t.Fatalf("want offline, got %s", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	_ = sub.Unsubscribe()
+
+	if _, err = b.Subscribe("gas.2", subscriber); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("已确认的消息被重新投递: %s", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// TestJetStreamRedelivery 测试处理失败的消息重新投递
+func TestJetStreamRedelivery(t *testing.T) {
+	url := runServer(t)
+	a, b := newJetStreamClient(t, url), newJetStreamClient(t, url)
+
+	received := make(chan string, 10)
+	var deliveries int
+	_, err := b.Subscribe("gas.2", subscriberFunc(func(request []byte, _ func([]byte) error) {
+		deliveries++
+		if deliveries == 1 {
+			panic("处理失败")
+		}
+		received <- string(request)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Publish("gas.2", []byte("retry")); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, received); got != "retry" || deliveries != 2 {
+		t.Fatalf("want retry after 2 deliveries, got %s after %d", got, deliveries)
+	}
+}
+
+// failingSubscriber 前 failures 次处理返回错误
+type failingSubscriber struct {
+	failures   int
+	deliveries int
+	received   chan string
+}
+
+func (s *failingSubscriber) OnMessage(request []byte, response func(data []byte) error) error {
+	s.deliveries++
+	if s.deliveries <= s.failures {
+		return errors.New("处理失败")
+	}
+	s.received <- string(request)
+	return nil
+}
+
+// TestJetStreamRedeliveryOnError 测试订阅者返回错误的消息不确认，重新投递
+func TestJetStreamRedeliveryOnError(t *testing.T) {
+	url := runServer(t)
+	a, b := newJetStreamClient(t, url), newJetStreamClient(t, url)
+
+	subscriber := &failingSubscriber{failures: 2, received: make(chan string, 10)}
+	if _, err := b.Subscribe("gas.2", subscriber); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Publish("gas.2", []byte("retry")); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, subscriber.received); got != "retry" || subscriber.deliveries != 3 {
+		t.Fatalf("want retry after 3 deliveries, got %s after %d", got, subscriber.deliveries)
+	}
+}
+
+// TestJetStreamRequest 测试 JetStream 模式下请求仍通过核心 NATS 回复
+func TestJetStreamRequest(t *testing.T) {
+	url := runServer(t)
+	a, b := newJetStreamClient(t, url), newJetStreamClient(t, url)
+
+	_, err := b.Subscribe("gas.2", subscriberFunc(func(request []byte, response func([]byte) error) {
+		_ = response(append([]byte("re:"), request...))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := a.Request("gas.2", []byte("ping"), time.Second)
+	if err != nil || string(reply) != "re:ping" {
+		t.Fatalf("want re:ping, got %s (err=%v)", reply, err)
+	}
+}
+
+// writeCerts 生成 CA、服务器证书和客户端证书，返回文件所在目录
+func writeCerts(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	write := func(name, typ string, der []byte) {
+		data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	caKey := newKey()
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gas-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("ca.pem", "CERTIFICATE", caDer)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) {
+		key := newKey()
+		cert := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, cert, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDer, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(name+".pem", "CERTIFICATE", der)
+		write(name+"-key.pem", "EC PRIVATE KEY", keyDer)
+	}
+	issue("server", 2, x509.ExtKeyUsageServerAuth)
+	issue("client", 3, x509.ExtKeyUsageClientAuth)
+	return dir
+}
+
+// TestTLSAndNKey 测试双向 TLS 和 NKey 认证
+func TestTLSAndNKey(t *testing.T) {
+	dir := writeCerts(t)
+	tlsConfig, err := server.GenTLSConfig(&server.TLSConfigOpts{
+		CertFile: filepath.Join(dir, "server.pem"),
+		KeyFile:  filepath.Join(dir, "server-key.pem"),
+		CaFile:   filepath.Join(dir, "ca.pem"),
+		Verify:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _ := user.PublicKey()
+	seed, _ := user.Seed()
+	seedFile := filepath.Join(dir, "user.nk")
+	if err = os.WriteFile(seedFile, seed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := startServer(t, &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		TLS:       true,
+		TLSVerify: true,
+		TLSConfig: tlsConfig,
+		Nkeys:     []*server.NkeyUser{{Nkey: pub}},
+	})
+
+	newConfig := func() *Config {
+		cfg := defaultConfig()
+		cfg.Servers = []string{fmt.Sprintf("tls://127.0.0.1:%d", s.Addr().(*net.TCPAddr).Port)}
+		cfg.PoolSize = 1
+		cfg.InboxPrefix = "_GAS_INBOX"
+		cfg.TLSCAFile = filepath.Join(dir, "ca.pem")
+		cfg.TLSCertFile = filepath.Join(dir, "client.pem")
+		cfg.TLSKeyFile = filepath.Join(dir, "client-key.pem")
+		cfg.NKeySeedFile = seedFile
+		return cfg
+	}
+
+	client := New(newConfig())
+	if err = client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Shutdown(context.Background())
+	_, err = client.Subscribe("gas.1", subscriberFunc(func(request []byte, response func([]byte) error) {
+		_ = response(request)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := client.Request("gas.1", []byte("ping"), time.Second); err != nil || string(reply) != "ping" {
+		t.Fatalf("want ping, got %s (err=%v)", reply, err)
+	}
+
+	// 没有客户端证书或 NKey 时无法连接
+	noCert := newConfig()
+	noCert.TLSCertFile, noCert.TLSKeyFile = "", ""
+	noKey := newConfig()
+	noKey.NKeySeedFile = ""
+	for _, cfg := range []*Config{noCert, noKey} {
+		cfg.MaxReconnects, cfg.AllowReconnect = 0, false
+		c := New(cfg)
+		if err = c.Run(context.Background()); err == nil {
+			_ = c.Shutdown(context.Background())
+			t.Fatal("未认证的客户端连接成功")
+		}
+	}
+
+	keyOnly := newConfig()
+	keyOnly.TLSCertFile = ""
+	if _, err = toOptions(keyOnly); !errors.Is(err, ErrTLSKeyPair) {
+		t.Fatalf("want ErrTLSKeyPair, got %v", err)
+	}
+}
+
+// restartServer 在相同端口上重新启动服务器
+func restartServer(t *testing.T, s *server.Server) *server.Server {
+	t.Helper()
+	port := s.Addr().(*net.TCPAddr).Port
+	s.Shutdown()
+	s.WaitForShutdown()
+	return startServer(t, &server.Options{Host: "127.0.0.1", Port: port})
+}
+
+func waitConnEvent(t *testing.T, ch <-chan *mqIface.ConnEvent, typ mqIface.ConnEventType) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-ch:
+			if event.Type == typ {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("未收到连接事件 %d", typ)
+		}
+	}
+}
+
+// TestReconnect 测试连接断开、重连的事件通知和重连后的订阅恢复；关闭重连时连接关闭后重新创建
+func TestReconnect(t *testing.T) {
+	for _, allowReconnect := range []bool{true, false} {
+		t.Run(fmt.Sprintf("allowReconnect=%v", allowReconnect), func(t *testing.T) {
+			s := startServer(t, &server.Options{Host: "127.0.0.1", Port: -1})
+			cfg := defaultConfig()
+			cfg.Servers = []string{s.ClientURL()}
+			cfg.PoolSize = 1
+			cfg.ReconnectWait = 50 * time.Millisecond
+			cfg.AllowReconnect = allowReconnect
+			client := New(cfg)
+			events := make(chan *mqIface.ConnEvent, 16)
+			client.SetConnEventHandler(func(event *mqIface.ConnEvent) { events <- event })
+			if err := client.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer client.Shutdown(context.Background())
+			_, err := client.Subscribe("gas.1", subscriberFunc(func(request []byte, response func([]byte) error) {
+				_ = response(request)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s = restartServer(t, s)
+			if allowReconnect {
+				waitConnEvent(t, events, mqIface.ConnDisconnected)
+			} else {
+				waitConnEvent(t, events, mqIface.ConnClosed)
+			}
+			waitConnEvent(t, events, mqIface.ConnReconnected)
+
+			var reply []byte
+			deadline := time.Now().Add(3 * time.Second)
+			for time.Now().Before(deadline) {
+				if reply, err = client.Request("gas.1", []byte("ping"), 200*time.Millisecond); err == nil {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			if string(reply) != "ping" {
+				t.Fatalf("重连后订阅未恢复: %v", err)
+			}
+		})
+	}
+}
+
+// TestReopenJetStreamFailure 测试重新创建订阅连接时创建流失败，被关闭的连接不发出事件也不再触发重建，
+// 只有一个协程重试，服务器恢复后只重建一次
+func TestReopenJetStreamFailure(t *testing.T) {
+	s := startServer(t, &server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	port := s.Addr().(*net.TCPAddr).Port
+	cfg := defaultConfig()
+	cfg.Servers = []string{s.ClientURL()}
+	cfg.PoolSize = 1
+	cfg.ReconnectWait = 50 * time.Millisecond
+	cfg.AllowReconnect = false
+	cfg.JetStream = true
+	client := New(cfg)
+	events := make(chan *mqIface.ConnEvent, 64)
+	client.SetConnEventHandler(func(event *mqIface.ConnEvent) { events <- event })
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Shutdown(context.Background())
+
+	// 重启后的服务器未开启 JetStream，每次重建都在创建流时失败
+	s = restartServer(t, s)
+	waitConnEvent(t, events, mqIface.ConnClosed)
+	time.Sleep(500 * time.Millisecond)
+	if n := len(events); n != 0 {
+		t.Fatalf("创建失败的连接发出了 %d 个事件", n)
+	}
+
+	s.Shutdown()
+	s.WaitForShutdown()
+	startServer(t, &server.Options{Host: "127.0.0.1", Port: port, JetStream: true, StoreDir: t.TempDir()})
+	waitConnEvent(t, events, mqIface.ConnReconnected)
+	time.Sleep(300 * time.Millisecond)
+	if n := len(events); n != 0 {
+		t.Fatalf("重建后又收到 %d 个事件", n)
+	}
+}
+
+func newCoreClient(t *testing.T, cfg *Config) *Client {
+	t.Helper()
+	client := New(cfg)
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Shutdown(context.Background())
+	})
+	return client
+}
+
+// TestMultiplexedRequest 测试一条连接上同时进行多个请求
+func TestMultiplexedRequest(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Servers = []string{runServer(t)}
+	cfg.PoolSize = 1
+	client := newCoreClient(t, cfg)
+
+	release := make(chan struct{})
+	_, err := client.Subscribe("gas.slow", subscriberFunc(func(request []byte, response func([]byte) error) {
+		go func() {
+			<-release
+			_ = response(request)
+		}()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 50
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			want := fmt.Sprintf("req-%d", i)
+			reply, err := client.Request("gas.slow", []byte(want), 3*time.Second)
+			if err == nil && string(reply) != want {
+				err = fmt.Errorf("want %s, got %s", want, reply)
+			}
+			errs <- err
+		}(i)
+	}
+	// 所有请求同时等待回复，说明没有独占连接
+	deadline := time.Now().Add(3 * time.Second)
+	for client.Stats().InFlight < count && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if inFlight := client.Stats().InFlight; inFlight != count {
+		t.Fatalf("want in flight %d, got %d", count, inFlight)
+	}
+	close(release)
+	for i := 0; i < count; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	st := client.Stats()
+	if st.Conns != 1 || st.Connected != 1 || st.InFlight != 0 || st.Requests != count || st.RequestErrors != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	if _, err = client.Request("gas.nobody", nil, time.Second); err == nil {
+		t.Fatal("want no responders")
+	}
+	if st = client.Stats(); st.RequestErrors != 1 {
+		t.Fatalf("want request errors 1, got %+v", st)
+	}
+}
+
+// TestAsyncPublish 测试异步发布的投递、Flush 和队列已满
+func TestAsyncPublish(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("jetStream=%v", jetStream), func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Servers = []string{runServer(t)}
+			cfg.AsyncPublish = true
+			cfg.JetStream = jetStream
+			client := newCoreClient(t, cfg)
+
+			const count = 1000
+			received := make(chan string, count)
+			_, err := client.Subscribe("gas.async", subscriberFunc(func(request []byte, _ func([]byte) error) {
+				received <- string(request)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := make([]byte, 0, 16)
+			for i := 0; i < count; i++ {
+				// 复用缓冲区，入队时已复制
+				data = fmt.Appendf(data[:0], "msg-%d", i)
+				if err = client.Publish("gas.async", data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err = client.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if st := client.Stats(); st.Publishes != count || st.PublishErrors != 0 || st.PublishQueued != 0 {
+				t.Fatalf("unexpected stats %+v", st)
+			}
+			// 不同批次可能经过不同连接，只检查是否全部送达
+			seen := make(map[string]bool, count)
+			for i := 0; i < count; i++ {
+				seen[receive(t, received)] = true
+			}
+			for i := 0; i < count; i++ {
+				if want := fmt.Sprintf("msg-%d", i); !seen[want] {
+					t.Fatalf("未收到 %s", want)
+				}
+			}
+		})
+	}
+
+	t.Run("queueFull", func(t *testing.T) {
+		cfg := defaultConfig()
+		cfg.Servers = []string{runServer(t)}
+		cfg.AsyncPublish = true
+		cfg.PublishQueueSize = 1
+		client := newCoreClient(t, cfg)
+		var rejected error
+		for i := 0; i < 10000 && rejected == nil; i++ {
+			rejected = client.Publish("gas.full", []byte("x"))
+		}
+		if !errors.Is(rejected, ErrPublishQueueFull) {
+			t.Fatalf("want ErrPublishQueueFull, got %v", rejected)
+		}
+		if st := client.Stats(); st.PublishRejected == 0 {
+			t.Fatalf("want rejected, got %+v", st)
+		}
+	})
+}
+
+// TestHealthCheck 测试健康检查替换已关闭的连接并测量往返时间
+func TestHealthCheck(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Servers = []string{runServer(t)}
+	cfg.PoolSize = 2
+	cfg.HealthCheckInterval = 50 * time.Millisecond
+	client := newCoreClient(t, cfg)
+
+	closed := client.pool.slots[0].Load()
+	closed.conn.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if st := client.Stats(); st.Connected == 2 && st.MaxRTT > 0 && client.pool.slots[0].Load() != closed {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("连接未被替换: %+v", client.Stats())
+}
@@ -1,87 +1,236 @@
 package nats
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
 	"github.com/dzm2020/gas/pkg/lib/stopper"
+
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPoolClosed   = errors.New("nats: 连接池已关闭")
+	ErrNoConnection = errors.New("nats: 没有可用的连接")
 )
 
-func NewPool(cfg *Config) *ConnPool {
-	servers := strings.Join(cfg.Servers, ",")
-	natsOpts := toOptions(cfg)
-	// 创建连接池
-	poolSize := cfg.PoolSize
-	if poolSize <= 0 {
-		poolSize = 10 // 默认大小
+func NewPool(cfg *Config) (*ConnPool, error) {
+	natsOpts, err := toOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	size := cfg.PoolSize
+	if size <= 0 {
+		size = defaultConfig().PoolSize
 	}
 	pool := &ConnPool{
-		pool:    make(chan *nats.Conn, poolSize),
-		servers: servers,
+		cfg:     cfg,
+		servers: strings.Join(cfg.Servers, ","),
 		opts:    natsOpts,
-		size:    poolSize,
+		slots:   make([]atomic.Pointer[poolConn], size),
 	}
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
 
-	// 预创建连接池中的连接
-	for i := 0; i < poolSize; i++ {
-		conn, err := pool.createConn()
-		if err != nil {
-			// 如果预创建失败，继续尝试，连接会在使用时创建
-			continue
+	// 预先创建连接，失败的连接由健康检查补上
+	for i := range pool.slots {
+		if pc, err := pool.dial(); err != nil {
+			glog.Warn("nats创建连接失败，等待健康检查重试", zap.String("servers", pool.servers), zap.Error(err))
+		} else {
+			pool.slots[i].Store(pc)
 		}
-		pool.put(conn)
 	}
-	return pool
+	pool.wg.Add(1)
+	grs.Go(func(ctx context.Context) {
+		defer pool.wg.Done()
+		pool.healthLoop()
+	})
+	return pool, nil
+}
+
+// poolConn 连接池中的连接，开启 JetStream 时附带该连接上的 JetStream 上下文
+type poolConn struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+	rtt  atomic.Int64 // 健康检查测得的往返时间
 }
 
-// ConnPool 连接池
+// ConnPool 固定数量的共享连接
+// NATS 连接支持多路复用，请求和发布按轮询选择连接而不独占，一条连接上可以同时进行任意数量的请求；
+// 健康检查定期替换已关闭的连接，选择时优先使用已连接的连接，正在重连的连接只在没有其他选择时使用（发布暂存在重连缓冲区）
 type ConnPool struct {
 	stopper.Stopper
-	pool    chan *nats.Conn
-	mu      sync.RWMutex
+	cfg     *Config
 	servers string
 	opts    []nats.Option
-	size    int
+	slots   []atomic.Pointer[poolConn]
+	next    atomic.Uint64
+	dialMu  sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
-// get 从连接池获取连接
-func (p *ConnPool) get() (*nats.Conn, error) {
-	if p.IsStop() {
-		return nil, errors.New("连接池已关闭")
+// createConn 创建新连接，连接状态变化记录到日志；extra 在配置的选项之后生效
+func (p *ConnPool) createConn(extra ...nats.Option) (*nats.Conn, error) {
+	opts := make([]nats.Option, 0, len(p.opts)+len(extra)+3)
+	opts = append(opts, p.opts...)
+	opts = append(opts, connHandlers(p.servers, nil)...)
+	opts = append(opts, extra...)
+	return nats.Connect(p.servers, opts...)
+}
+
+// dial 创建连接池中的连接
+func (p *ConnPool) dial() (*poolConn, error) {
+	conn, err := p.createConn()
+	if err != nil {
+		return nil, err
 	}
+	pc := &poolConn{conn: conn}
+	if p.cfg.JetStream {
+		pc.js, err = jetstream.New(conn, jetstream.WithPublishAsyncErrHandler(func(_ jetstream.JetStream, msg *nats.Msg, err error) {
+			glog.Warn("nats异步写入流失败", zap.String("subject", msg.Subject), zap.Error(err))
+		}))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return pc, nil
+}
 
-	select {
-	case conn := <-p.pool:
-		// 检查连接是否有效
-		if conn != nil && !conn.IsClosed() {
-			return conn, nil
+// get 轮询选择连接
+func (p *ConnPool) get() (*poolConn, error) {
+	if p.IsStop() {
+		return nil, ErrPoolClosed
+	}
+	size := uint64(len(p.slots))
+	start := p.next.Add(1)
+	var reconnecting *poolConn
+	for i := uint64(0); i < size; i++ {
+		pc := p.slots[(start+i)%size].Load()
+		if pc == nil || pc.conn.IsClosed() {
+			continue
+		}
+		if pc.conn.IsConnected() {
+			return pc, nil
 		}
-		// 连接已关闭，创建新连接
-		return p.createConn()
-	default:
-		// 连接池为空，创建新连接
-		return p.createConn()
+		if reconnecting == nil {
+			reconnecting = pc
+		}
+	}
+	if reconnecting != nil {
+		return reconnecting, nil
 	}
+	return p.redial()
 }
 
-// put 归还连接到连接池
-func (p *ConnPool) put(conn *nats.Conn) {
+// redial 所有连接都已关闭（如关闭了自动重连）时立即替换一个连接，不等待健康检查
+func (p *ConnPool) redial() (*poolConn, error) {
+	p.dialMu.Lock()
+	defer p.dialMu.Unlock()
+	for i := range p.slots {
+		if pc := p.slots[i].Load(); pc != nil && !pc.conn.IsClosed() {
+			return pc, nil
+		}
+	}
 	if p.IsStop() {
-		conn.Close()
-		return
+		return nil, ErrPoolClosed
+	}
+	fresh, err := p.dial()
+	if err != nil {
+		return nil, errors.Join(ErrNoConnection, err)
+	}
+	p.slots[0].Store(fresh)
+	return fresh, nil
+}
+
+func (p *ConnPool) healthLoop() {
+	interval := p.cfg.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultConfig().HealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.check()
+		}
 	}
-	select {
-	case p.pool <- conn:
-	default:
-		conn.Close()
+}
+
+// check 替换已关闭或未创建的连接，测量已连接的连接的往返时间
+func (p *ConnPool) check() {
+	for i := range p.slots {
+		if p.IsStop() {
+			return
+		}
+		pc := p.slots[i].Load()
+		if pc == nil || pc.conn.IsClosed() {
+			fresh, err := p.dial()
+			if err != nil {
+				glog.Warn("nats替换已关闭的连接失败", zap.String("servers", p.servers), zap.Error(err))
+				continue
+			}
+			if !p.slots[i].CompareAndSwap(pc, fresh) || p.IsStop() {
+				fresh.conn.Close()
+			}
+			continue
+		}
+		if !pc.conn.IsConnected() {
+			continue
+		}
+		if rtt, err := pc.conn.RTT(); err == nil {
+			pc.rtt.Store(int64(rtt))
+		} else {
+			glog.Warn("nats连接健康检查失败", zap.String("url", pc.conn.ConnectedUrlRedacted()), zap.Error(err))
+		}
 	}
 }
 
-// createConn 创建新连接
-func (p *ConnPool) createConn() (*nats.Conn, error) {
-	return nats.Connect(p.servers, p.opts...)
+// conns 当前的所有连接
+func (p *ConnPool) conns() []*poolConn {
+	conns := make([]*poolConn, 0, len(p.slots))
+	for i := range p.slots {
+		if pc := p.slots[i].Load(); pc != nil {
+			conns = append(conns, pc)
+		}
+	}
+	return conns
+}
+
+// flush 等待所有连接写出缓冲区中的数据并收到服务器确认，JetStream 模式下同时等待异步写入流的确认
+func (p *ConnPool) flush(ctx context.Context) error {
+	var errs []error
+	for _, pc := range p.conns() {
+		if !pc.conn.IsConnected() {
+			continue
+		}
+		if err := pc.conn.FlushWithContext(ctx); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pc.js == nil {
+			continue
+		}
+		select {
+		case <-pc.js.PublishAsyncComplete():
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // close 关闭连接池中的所有连接
@@ -89,16 +238,11 @@ func (p *ConnPool) close() {
 	if !p.Stop() {
 		return
 	}
-	// 关闭所有连接
-	for {
-		select {
-		case conn := <-p.pool:
-			if conn != nil && !conn.IsClosed() {
-				conn.Close()
-			}
-		default:
-			close(p.pool)
-			return
+	p.cancel()
+	p.wg.Wait()
+	for i := range p.slots {
+		if pc := p.slots[i].Swap(nil); pc != nil {
+			pc.conn.Close()
 		}
 	}
 }
@@ -0,0 +1,156 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+
+	"go.uber.org/zap"
+)
+
+var ErrPublishQueueFull = errors.New("nats: 异步发布队列已满")
+
+// outgoing 待发送的消息，flushed 非空时是 Flush 的标记，之前入队的消息发送后关闭
+type outgoing struct {
+	subject string
+	data    []byte
+	flushed chan struct{}
+}
+
+// publisher 异步发布队列
+// 后台协程每次取出一批消息连续写入连接，nats.go 的写协程将同一批消息合并为一次写出；JetStream 模式下使用 PublishAsync，不逐条等待确认
+type publisher struct {
+	client *Client
+	queue  chan *outgoing
+	batch  int
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newPublisher(client *Client, cfg *Config) *publisher {
+	def := defaultConfig()
+	size, batch := cfg.PublishQueueSize, cfg.PublishBatch
+	if size <= 0 {
+		size = def.PublishQueueSize
+	}
+	if batch <= 0 {
+		batch = def.PublishBatch
+	}
+	p := &publisher{
+		client: client,
+		queue:  make(chan *outgoing, size),
+		batch:  batch,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	grs.Go(func(ctx context.Context) {
+		p.loop()
+	})
+	return p
+}
+
+// enqueue 放入发送队列，队列已满时返回 ErrPublishQueueFull；data 被复制，调用方可以立即复用
+func (p *publisher) enqueue(subject string, data []byte) error {
+	msg := &outgoing{subject: subject, data: append([]byte(nil), data...)}
+	select {
+	case p.queue <- msg:
+		return nil
+	default:
+		p.client.stats.publishRejected.Add(1)
+		return xerror.Wrapf(ErrPublishQueueFull, "subject:%s", subject)
+	}
+}
+
+func (p *publisher) loop() {
+	defer close(p.done)
+	batch := make([]*outgoing, 0, p.batch)
+	for {
+		select {
+		case msg := <-p.queue:
+			batch = p.collect(append(batch[:0], msg))
+			p.send(batch)
+		case <-p.stop:
+			// 发送停止前已入队的消息
+			for {
+				select {
+				case msg := <-p.queue:
+					batch = p.collect(append(batch[:0], msg))
+					p.send(batch)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect 不等待地取出队列中的消息，直到达到批量上限
+func (p *publisher) collect(batch []*outgoing) []*outgoing {
+	for len(batch) < p.batch {
+		select {
+		case msg := <-p.queue:
+			batch = append(batch, msg)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// send 同一批消息写入同一条连接
+func (p *publisher) send(batch []*outgoing) {
+	pc, connErr := p.client.pool.get()
+	for _, msg := range batch {
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+		var err error
+		if connErr != nil {
+			p.client.stats.publishes.Add(1)
+			p.client.stats.publishErrors.Add(1)
+			err = connErr
+		} else {
+			err = p.client.publishOn(pc, msg.subject, msg.data, true)
+		}
+		if err != nil {
+			glog.Warn("nats异步发布失败", zap.String("subject", msg.subject), zap.Error(err))
+		}
+	}
+}
+
+// flush 等待之前入队的消息全部写入连接
+func (p *publisher) flush(ctx context.Context) error {
+	marker := &outgoing{flushed: make(chan struct{})}
+	select {
+	case p.queue <- marker:
+	case <-p.done:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-marker.flushed:
+		return nil
+	case <-p.done:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close 停止接收并发送队列中剩余的消息，ctx 到期时不再等待
+func (p *publisher) close(ctx context.Context) {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+	}
+}
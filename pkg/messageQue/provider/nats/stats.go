@@ -0,0 +1,59 @@
+package nats
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats 消息队列的运行指标，通过 Client.Stats 获取
+type Stats struct {
+	Conns           int           // 连接池中的连接数
+	Connected       int           // 已连接的连接数，其余正在重连或已关闭
+	Reconnects      uint64        // 连接池中的连接累计重连次数
+	MaxRTT          time.Duration // 健康检查测得的最大往返时间
+	InFlight        int64         // 正在等待回复的请求数
+	Requests        uint64        // 累计请求数
+	RequestErrors   uint64        // 累计失败的请求数，包括超时和没有订阅者
+	Publishes       uint64        // 累计发送的消息数
+	PublishErrors   uint64        // 累计发送失败的消息数
+	PublishQueued   int           // 异步发布队列中等待发送的消息数
+	PublishRejected uint64        // 异步发布队列已满被拒绝的消息数
+}
+
+type stats struct {
+	inFlight        atomic.Int64
+	requests        atomic.Uint64
+	requestErrors   atomic.Uint64
+	publishes       atomic.Uint64
+	publishErrors   atomic.Uint64
+	publishRejected atomic.Uint64
+}
+
+// Stats 当前的运行指标
+func (n *Client) Stats() Stats {
+	st := Stats{
+		InFlight:        n.stats.inFlight.Load(),
+		Requests:        n.stats.requests.Load(),
+		RequestErrors:   n.stats.requestErrors.Load(),
+		Publishes:       n.stats.publishes.Load(),
+		PublishErrors:   n.stats.publishErrors.Load(),
+		PublishRejected: n.stats.publishRejected.Load(),
+	}
+	if n.publisher != nil {
+		st.PublishQueued = len(n.publisher.queue)
+	}
+	if n.pool == nil {
+		return st
+	}
+	for _, pc := range n.pool.conns() {
+		st.Conns++
+		if pc.conn.IsConnected() {
+			st.Connected++
+		}
+		st.Reconnects += pc.conn.Stats().Reconnects
+		if rtt := time.Duration(pc.rtt.Load()); rtt > st.MaxRTT {
+			st.MaxRTT = rtt
+		}
+	}
+	return st
+}
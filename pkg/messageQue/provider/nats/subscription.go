@@ -0,0 +1,59 @@
+package nats
+
+import (
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// subscription 核心 NATS 订阅（接收发布和请求）和开启 JetStream 时的持久消费者
+// 订阅连接重新创建后在新连接上重新绑定
+type subscription struct {
+	client     *Client
+	subject    string
+	subscriber iface.ISubscriber
+	core       *nats.Subscription
+	consume    jetstream.ConsumeContext
+}
+
+// bind 在连接上订阅，调用方持有 client.mu
+func (s *subscription) bind(conn *nats.Conn, js *jetStream) (err error) {
+	s.core, err = conn.Subscribe(s.subject, func(m *nats.Msg) {
+		response := func(data []byte) error {
+			if m.Reply == "" {
+				return nil
+			}
+			return m.Respond(data)
+		}
+		_ = s.subscriber.OnMessage(m.Data, response)
+	})
+	if err != nil || js == nil {
+		return err
+	}
+	if s.consume, err = js.subscribe(s.subject, s.subscriber); err != nil {
+		_ = s.core.Unsubscribe()
+		s.core = nil
+	}
+	return err
+}
+
+// unbind 取消订阅，持久消费者保留在服务器上，调用方持有 client.mu
+func (s *subscription) unbind() {
+	if s.consume != nil {
+		s.consume.Stop()
+		s.consume = nil
+	}
+	if s.core != nil {
+		_ = s.core.Unsubscribe()
+		s.core = nil
+	}
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.client.mu.Lock()
+	defer s.client.mu.Unlock()
+	delete(s.client.subs, s)
+	s.unbind()
+	return nil
+}
@@ -0,0 +1,374 @@
+// Package redis 基于 Redis 发布订阅的消息队列，用于已经部署了 Redis、不想再引入 NATS 的小规模环境
+//
+// 主题映射为带前缀的频道，含通配符的主题通过 PSUBSCRIBE 订阅后再按 NATS 规则过滤。
+// 请求附带本客户端的回复频道（inbox）和 id，订阅者将回复发布到该频道。
+// Redis 发布订阅不保存消息，断线期间的消息会丢失，与 NATS 核心的投递语义一致。
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+	"github.com/dzm2020/gas/pkg/messageQue"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrClientClosed   = errors.New("redis: 客户端已关闭")
+	ErrNoResponders   = errors.New("redis: 没有订阅者响应")
+	ErrTimeout        = errors.New("redis: 请求超时")
+	ErrInvalidSubject = errors.New("redis: 主题不合法")
+)
+
+func init() {
+	_ = messageQue.GetFactoryMgr().Register("redis", func(args ...any) (iface.IMessageQue, error) {
+		cfg := defaultConfig()
+		if len(args) > 0 && args[0] != nil {
+			vp := viper.New()
+			vp.Set("", args[0])
+			if err := vp.UnmarshalKey("", cfg); err != nil {
+				return nil, err
+			}
+		}
+		return New(cfg), nil
+	})
+}
+
+var _ iface.IMessageQue = (*Client)(nil)
+
+func New(cfg *Config) *Client {
+	if cfg == nil {
+		cfg = defaultConfig()
+	}
+	if cfg.PendingLimit <= 0 {
+		cfg.PendingLimit = defaultConfig().PendingLimit
+	}
+	client := &Client{
+		cfg: cfg,
+		client: goredis.NewClient(&goredis.Options{
+			Addr:     cfg.Address,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+			PoolSize: cfg.PoolSize,
+		}),
+		inbox:    fmt.Sprintf("%s:_INBOX.%016x", cfg.Prefix, rand.Uint64()),
+		channels: make(map[string]map[*subscription]struct{}),
+		patterns: make(map[string]map[*subscription]struct{}),
+		pending:  make(map[uint64]chan []byte),
+	}
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	// 不带频道时不会立即建立连接，Run 之前也可以订阅
+	client.pubsub = client.client.Subscribe(client.ctx)
+	return client
+}
+
+// Client Redis 消息队列，所有订阅共用一条发布订阅连接，断线后由 go-redis 重连并恢复订阅
+type Client struct {
+	stopper.Stopper
+
+	cfg    *Config
+	client *goredis.Client
+	pubsub *goredis.PubSub
+	inbox  string // 本客户端接收回复的频道
+
+	subMu    sync.Mutex
+	channels map[string]map[*subscription]struct{} // 按频道订阅
+	patterns map[string]map[*subscription]struct{} // 按模式订阅，对应含通配符的主题
+
+	callMu  sync.Mutex
+	pending map[uint64]chan []byte
+	seq     atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (c *Client) Run(ctx context.Context) error {
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis: 连接 %s 失败: %w", c.cfg.Address, err)
+	}
+	if err := c.pubsub.Subscribe(ctx, c.inbox); err != nil {
+		return err
+	}
+	c.wg.Add(1)
+	grs.Go(func(ctx context.Context) {
+		defer c.wg.Done()
+		c.loop()
+	})
+	return nil
+}
+
+// channel 主题对应的频道
+func (c *Client) channel(subject string) string {
+	return c.cfg.Prefix + ":" + subject
+}
+
+// globPattern 将含通配符的主题转换为 PSUBSCRIBE 的模式，* 和 > 都转换为 *，匹配结果再按 NATS 规则过滤
+func (c *Client) globPattern(tokens []string) string {
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		if token == "*" || token == ">" {
+			parts[i] = "*"
+			continue
+		}
+		parts[i] = globEscaper.Replace(token)
+	}
+	return globEscaper.Replace(c.cfg.Prefix) + ":" + strings.Join(parts, ".")
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+func hasWildcard(tokens []string) bool {
+	for _, token := range tokens {
+		if token == "*" || token == ">" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) loop() {
+	ch := c.pubsub.Channel()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.dispatch(msg)
+		}
+	}
+}
+
+// dispatch 将回复交给等待的请求，其他消息交给匹配的订阅
+func (c *Client) dispatch(msg *goredis.Message) {
+	e, err := decodeEnvelope([]byte(msg.Payload))
+	if err != nil {
+		glog.Warn("redis: 丢弃格式错误的消息", zap.String("channel", msg.Channel), zap.Error(err))
+		return
+	}
+	if msg.Channel == c.inbox {
+		if e.kind == kindReply {
+			c.resolve(e)
+		}
+		return
+	}
+
+	var targets []*subscription
+	c.subMu.Lock()
+	if msg.Pattern != "" {
+		tokens := messageQue.SplitSubject(strings.TrimPrefix(msg.Channel, c.cfg.Prefix+":"))
+		for sub := range c.patterns[msg.Pattern] {
+			if sub.match(tokens) {
+				targets = append(targets, sub)
+			}
+		}
+	} else {
+		for sub := range c.channels[msg.Channel] {
+			targets = append(targets, sub)
+		}
+	}
+	c.subMu.Unlock()
+	for _, sub := range targets {
+		sub.push(e)
+	}
+}
+
+func (c *Client) resolve(e *envelope) {
+	c.callMu.Lock()
+	reply, ok := c.pending[e.id]
+	c.callMu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case reply <- e.data:
+	default:
+	}
+}
+
+func (c *Client) Publish(subject string, data []byte) error {
+	if c.IsStop() {
+		return ErrClientClosed
+	}
+	e := &envelope{kind: kindPublish, data: data}
+	return c.client.Publish(c.ctx, c.channel(subject), e.encode()).Err()
+}
+
+func (c *Client) Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.RequestWithContext(ctx, subject, data)
+}
+
+// RequestWithContext 发送请求并等待第一个回复，发布时没有客户端订阅该频道则立即返回 ErrNoResponders
+func (c *Client) RequestWithContext(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	if c.IsStop() {
+		return nil, ErrClientClosed
+	}
+	id := c.seq.Add(1)
+	reply := make(chan []byte, 1)
+	c.callMu.Lock()
+	c.pending[id] = reply
+	c.callMu.Unlock()
+	defer func() {
+		c.callMu.Lock()
+		delete(c.pending, id)
+		c.callMu.Unlock()
+	}()
+
+	e := &envelope{kind: kindRequest, id: id, reply: c.inbox, data: data}
+	receivers, err := c.client.Publish(ctx, c.channel(subject), e.encode()).Result()
+	if err != nil {
+		return nil, xerror.Wrapf(err, "subject:%s", subject)
+	}
+	if receivers == 0 {
+		return nil, xerror.Wrapf(ErrNoResponders, "subject:%s", subject)
+	}
+	select {
+	case data = <-reply:
+		return data, nil
+	case <-c.ctx.Done():
+		return nil, ErrClientClosed
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, xerror.Wrapf(ErrTimeout, "subject:%s", subject)
+		}
+		return nil, xerror.Wrapf(ctx.Err(), "subject:%s", subject)
+	}
+}
+
+// reply 将回复发布到请求者的回复频道
+func (c *Client) reply(request *envelope, data []byte) error {
+	if c.IsStop() {
+		return ErrClientClosed
+	}
+	e := &envelope{kind: kindReply, id: request.id, data: data}
+	return c.client.Publish(c.ctx, request.reply, e.encode()).Err()
+}
+
+// Subscribe 订阅主题，支持 * 和 > 通配符，同一订阅的消息按发布顺序串行处理
+func (c *Client) Subscribe(subject string, subscriber iface.ISubscriber) (iface.ISubscription, error) {
+	if c.IsStop() {
+		return nil, ErrClientClosed
+	}
+	if subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	tokens := messageQue.SplitSubject(subject)
+	sub := &subscription{
+		client:     c,
+		subject:    subject,
+		pattern:    tokens,
+		key:        c.channel(subject),
+		wildcard:   hasWildcard(tokens),
+		subscriber: subscriber,
+		queue:      make(chan *envelope, c.cfg.PendingLimit),
+		done:       make(chan struct{}),
+	}
+	if sub.wildcard {
+		sub.key = c.globPattern(tokens)
+	}
+	if err := c.addSubscription(sub); err != nil {
+		return nil, err
+	}
+	grs.Go(func(ctx context.Context) {
+		sub.loop()
+	})
+	return sub, nil
+}
+
+// addSubscription 同一频道或模式的多个订阅共用一个 Redis 订阅
+func (c *Client) addSubscription(sub *subscription) error {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	subs := c.subscriptions(sub)
+	set, ok := subs[sub.key]
+	if !ok {
+		var err error
+		if sub.wildcard {
+			err = c.pubsub.PSubscribe(c.ctx, sub.key)
+		} else {
+			err = c.pubsub.Subscribe(c.ctx, sub.key)
+		}
+		if err != nil {
+			return err
+		}
+		set = make(map[*subscription]struct{})
+		subs[sub.key] = set
+	}
+	set[sub] = struct{}{}
+	return nil
+}
+
+func (c *Client) removeSubscription(sub *subscription) error {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	subs := c.subscriptions(sub)
+	set, ok := subs[sub.key]
+	if !ok {
+		return nil
+	}
+	delete(set, sub)
+	if len(set) > 0 {
+		return nil
+	}
+	delete(subs, sub.key)
+	if c.IsStop() {
+		return nil
+	}
+	if sub.wildcard {
+		return c.pubsub.PUnsubscribe(c.ctx, sub.key)
+	}
+	return c.pubsub.Unsubscribe(c.ctx, sub.key)
+}
+
+func (c *Client) subscriptions(sub *subscription) map[string]map[*subscription]struct{} {
+	if sub.wildcard {
+		return c.patterns
+	}
+	return c.channels
+}
+
+func (c *Client) Shutdown(ctx context.Context) error {
+	if !c.Stop() {
+		return nil
+	}
+	c.subMu.Lock()
+	var subs []*subscription
+	for _, set := range c.channels {
+		for sub := range set {
+			subs = append(subs, sub)
+		}
+	}
+	for _, set := range c.patterns {
+		for sub := range set {
+			subs = append(subs, sub)
+		}
+	}
+	c.subMu.Unlock()
+	for _, sub := range subs {
+		_ = sub.Unsubscribe()
+	}
+	c.cancel()
+	_ = c.pubsub.Close()
+	grs.WaitWithContext(ctx, &c.wg)
+	return c.client.Close()
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var ErrCorruptEnvelope = errors.New("redis: 消息格式错误")
+
+// 消息类型
+const (
+	kindPublish byte = iota + 1 // 发布，无需回复
+	kindRequest                 // 请求，回复发往 reply 频道，id 用于关联回复
+	kindReply                   // 回复
+)
+
+// envelopeHeadLen kind(1) + id(8) + replyLen(2)
+const envelopeHeadLen = 1 + 8 + 2
+
+// envelope 频道上传输的消息
+//
+//	kind(1) | id(8) | replyLen(2) | reply | data
+type envelope struct {
+	kind  byte
+	id    uint64
+	reply string
+	data  []byte
+}
+
+func (e *envelope) encode() []byte {
+	buf := make([]byte, envelopeHeadLen, envelopeHeadLen+len(e.reply)+len(e.data))
+	buf[0] = e.kind
+	binary.BigEndian.PutUint64(buf[1:], e.id)
+	binary.BigEndian.PutUint16(buf[9:], uint16(len(e.reply)))
+	buf = append(buf, e.reply...)
+	return append(buf, e.data...)
+}
+
+func decodeEnvelope(b []byte) (*envelope, error) {
+	if len(b) < envelopeHeadLen {
+		return nil, ErrCorruptEnvelope
+	}
+	e := &envelope{
+		kind: b[0],
+		id:   binary.BigEndian.Uint64(b[1:]),
+	}
+	replyLen := int(binary.BigEndian.Uint16(b[9:]))
+	if len(b) < envelopeHeadLen+replyLen {
+		return nil, ErrCorruptEnvelope
+	}
+	e.reply = string(b[envelopeHeadLen : envelopeHeadLen+replyLen])
+	e.data = b[envelopeHeadLen+replyLen:]
+	return e, nil
+}
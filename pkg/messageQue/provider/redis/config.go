@@ -0,0 +1,20 @@
+package redis
+
+// Config Redis 消息队列配置
+type Config struct {
+	Address      string `json:"address"`      // Redis 地址
+	Password     string `json:"password"`     // 密码
+	DB           int    `json:"db"`           // 数据库，发布订阅不区分数据库，只影响连接
+	Prefix       string `json:"prefix"`       // 频道前缀，多个集群共用 Redis 时用于隔离
+	PoolSize     int    `json:"poolSize"`     // 发布使用的连接池大小，0 表示使用 go-redis 的默认值
+	PendingLimit int    `json:"pendingLimit"` // 每个订阅待处理消息的上限，超过后丢弃新消息
+}
+
+// defaultConfig 返回默认配置
+func defaultConfig() *Config {
+	return &Config{
+		Address:      "127.0.0.1:6379",
+		Prefix:       "gas",
+		PendingLimit: 65536,
+	}
+}
@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+type subscriberFunc func(request []byte, response func(data []byte) error)
+
+func (f subscriberFunc) OnMessage(request []byte, response func(data []byte) error) error {
+	f(request, response)
+	return nil
+}
+
+// testConfig 连接 GAS_REDIS_ADDR（默认 127.0.0.1:6379），Redis 不可用时跳过测试
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := defaultConfig()
+	if addr := os.Getenv("GAS_REDIS_ADDR"); addr != "" {
+		cfg.Address = addr
+	}
+	client := goredis.NewClient(&goredis.Options{Addr: cfg.Address})
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis 不可用: %v", err)
+	}
+	cfg.Prefix = fmt.Sprintf("gas-test-%d", time.Now().UnixNano())
+	return cfg
+}
+
+func newClient(t *testing.T, cfg *Config) *Client {
+	c := *cfg
+	client := New(&c)
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Shutdown(context.Background())
+	})
+	return client
+}
+
+// waitSubscribed 等待 Redis 确认订阅，订阅命令发出后不等待确认
+func waitSubscribed(t *testing.T, c *Client, subject string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		counts, err := c.client.PubSubNumSub(context.Background(), c.channel(subject)).Result()
+		if err == nil && counts[c.channel(subject)] > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("订阅 %s 未生效", subject)
+}
+
+// TestPublishSubscribe 测试不同客户端之间的发布订阅、通配符和取消订阅
+func TestPublishSubscribe(t *testing.T) {
+	cfg := testConfig(t)
+	a, b := newClient(t, cfg), newClient(t, cfg)
+	received := make(chan string, 10)
+	sub, err := b.Subscribe("game.*.move", subscriberFunc(func(request []byte, _ func([]byte) error) {
+		received <- string(request)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模式订阅的确认无法通过 NUMSUB 查询，重复发布直到收到第一条
+	deadline := time.Now().Add(time.Second)
+	for len(received) == 0 && time.Now().Before(deadline) {
+		_ = a.Publish("game.room0.move", []byte("probe"))
+		time.Sleep(20 * time.Millisecond)
+	}
+	for len(received) > 0 {
+		<-received
+	}
+
+	// 不符合 NATS 规则的频道被过滤
+	if err = a.Publish("game.room1.extra.move", []byte("skip")); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Publish("game.room1.move", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if got != "hello" {
+			t.Fatalf("want hello, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("未收到消息")
+	}
+
+	_ = sub.Unsubscribe()
+	_ = a.Publish("game.room1.move", []byte("again"))
+	select {
+	case got := <-received:
+		t.Fatalf("取消订阅后仍收到消息: %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestRequest 测试请求回复、超时和无订阅者
+func TestRequest(t *testing.T) {
+	cfg := testConfig(t)
+	a, b := newClient(t, cfg), newClient(t, cfg)
+	_, err := b.Subscribe("echo", subscriberFunc(func(request []byte, response func([]byte) error) {
+		_ = response(append([]byte("re:"), request...))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = b.Subscribe("silent", subscriberFunc(func([]byte, func([]byte) error) {}))
+	waitSubscribed(t, a, "echo")
+	waitSubscribed(t, a, "silent")
+
+	reply, err := a.Request("echo", []byte("ping"), time.Second)
+	if err != nil || string(reply) != "re:ping" {
+		t.Fatalf("want re:ping, got %s (err=%v)", reply, err)
+	}
+	if _, err = a.Request("silent", nil, 50*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("want ErrTimeout, got %v", err)
+	}
+	if _, err = a.Request("nobody", nil, time.Second); !errors.Is(err, ErrNoResponders) {
+		t.Fatalf("want ErrNoResponders, got %v", err)
+	}
+	_ = a.Shutdown(context.Background())
+	if err = a.Publish("echo", nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("want ErrClientClosed, got %v", err)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	e := &envelope{kind: kindRequest, id: 42, reply: "gas:_INBOX.1", data: []byte("data")}
+	got, err := decodeEnvelope(e.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.kind != e.kind || got.id != e.id || got.reply != e.reply || string(got.data) != "data" {
+		t.Fatalf("want %+v, got %+v", e, got)
+	}
+	if _, err = decodeEnvelope(e.encode()[:5]); !errors.Is(err, ErrCorruptEnvelope) {
+		t.Fatalf("want ErrCorruptEnvelope, got %v", err)
+	}
+}
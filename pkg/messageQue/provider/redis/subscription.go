@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/messageQue"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"go.uber.org/zap"
+)
+
+// subscription 订阅，消息在独立的协程中按顺序交给订阅者
+type subscription struct {
+	client     *Client
+	subject    string
+	pattern    []string
+	key        string // 订阅的频道，含通配符时为 PSUBSCRIBE 的模式
+	wildcard   bool
+	subscriber iface.ISubscriber
+	queue      chan *envelope
+	done       chan struct{}
+	once       sync.Once
+}
+
+func (s *subscription) match(tokens []string) bool {
+	return messageQue.MatchSubject(s.pattern, tokens)
+}
+
+// push 放入待处理队列，队列已满或订阅已取消时返回 false
+func (s *subscription) push(e *envelope) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.queue <- e:
+		return true
+	default:
+		glog.Warn("redis: 订阅待处理消息超过上限，丢弃消息", zap.String("subject", s.subject), zap.Int("limit", cap(s.queue)))
+		return false
+	}
+}
+
+func (s *subscription) loop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case e := <-s.queue:
+			response := func(data []byte) error {
+				if e.kind != kindRequest {
+					return nil
+				}
+				return s.client.reply(e, data)
+			}
+			_ = s.subscriber.OnMessage(e.data, response)
+		}
+	}
+}
+
+func (s *subscription) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		err = s.client.removeSubscription(s)
+		close(s.done)
+	})
+	return err
+}
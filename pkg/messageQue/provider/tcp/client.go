@@ -0,0 +1,435 @@
+// Package tcp 节点之间直连的消息队列，不依赖 NATS 等外部服务
+//
+// 每个节点在 Address:Port 上监听，发往某个主题的消息由集群解析出目标节点地址后直接发送；
+// 每个目标节点维护一条连接，断开后在下次发送时重新连接。请求通过帧中的 id 关联回复。
+package tcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+	"github.com/dzm2020/gas/pkg/messageQue"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+	"github.com/dzm2020/gas/pkg/network"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrClientClosed    = errors.New("tcp: 客户端已关闭")
+	ErrNoListenAddress = errors.New("tcp: 未配置监听地址")
+	ErrNoResolver      = errors.New("tcp: 未设置节点地址解析")
+	ErrUnknownSubject  = errors.New("tcp: 无法解析主题对应的节点")
+	ErrPeerUnavailable = errors.New("tcp: 节点暂时无法连接")
+	ErrNoResponders    = errors.New("tcp: 没有订阅者响应")
+	ErrTimeout         = errors.New("tcp: 请求超时")
+	ErrConnectionLost  = errors.New("tcp: 等待回复时连接断开")
+	ErrInvalidSubject  = errors.New("tcp: 主题不合法")
+)
+
+func init() {
+	_ = messageQue.GetFactoryMgr().Register("tcp", func(args ...any) (iface.IMessageQue, error) {
+		cfg := defaultConfig()
+		if len(args) > 0 && args[0] != nil {
+			vp := viper.New()
+			vp.Set("", args[0])
+			if err := vp.UnmarshalKey("", cfg); err != nil {
+				return nil, err
+			}
+		}
+		return New(cfg), nil
+	})
+}
+
+var (
+	_ iface.IMessageQue    = (*Client)(nil)
+	_ iface.IPeerTransport = (*Client)(nil)
+	_ network.IHandler     = (*Client)(nil)
+)
+
+func New(cfg *Config) *Client {
+	def := defaultConfig()
+	if cfg == nil {
+		cfg = def
+	}
+	if cfg.HeartTimeout <= 0 {
+		cfg.HeartTimeout = def.HeartTimeout
+	}
+	if cfg.SendQueueSize <= 0 {
+		cfg.SendQueueSize = def.SendQueueSize
+	}
+	if cfg.PendingLimit <= 0 {
+		cfg.PendingLimit = def.PendingLimit
+	}
+	if cfg.MaxFrameSize <= 0 {
+		cfg.MaxFrameSize = def.MaxFrameSize
+	}
+	client := &Client{
+		cfg:     cfg,
+		codec:   &codec{maxFrameSize: cfg.MaxFrameSize},
+		subs:    make(map[*subscription]struct{}),
+		peers:   make(map[string]*peer),
+		pending: make(map[uint64]*call),
+	}
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	return client
+}
+
+// call 等待回复的请求
+type call struct {
+	connId int64
+	reply  chan *frame
+}
+
+// Client 节点直连的消息队列
+type Client struct {
+	stopper.Stopper
+
+	cfg      *Config
+	codec    *codec
+	resolver iface.IPeerResolver
+	server   network.IServer
+
+	subMu sync.RWMutex
+	subs  map[*subscription]struct{}
+
+	peerMu sync.Mutex
+	peers  map[string]*peer
+
+	callMu  sync.Mutex
+	pending map[uint64]*call
+	seq     atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// SetResolver 设置主题到节点地址的解析，由集群在 Run 之前调用
+func (c *Client) SetResolver(resolver iface.IPeerResolver) {
+	c.resolver = resolver
+}
+
+func (c *Client) options() []network.Option {
+	return []network.Option{
+		network.WithCodec(c.codec),
+		network.WithKeepAlive(c.cfg.HeartTimeout),
+		network.WithDialTimeout(c.cfg.DialTimeout),
+		network.WithSendChanSize(c.cfg.SendQueueSize),
+		network.WithUntracked(),
+	}
+}
+
+func (c *Client) Run(ctx context.Context) (err error) {
+	listen := c.cfg.Listen
+	if listen == "" && c.resolver != nil {
+		listen = c.resolver.LocalAddress()
+	}
+	if listen == "" {
+		return ErrNoListenAddress
+	}
+	if c.server, err = network.NewServer(c, "tcp://"+listen, c.options()...); err != nil {
+		return err
+	}
+	if err = c.server.Start(); err != nil {
+		return xerror.Wrapf(err, "监听失败, address:%s", listen)
+	}
+	c.wg.Add(1)
+	grs.Go(func(ctx context.Context) {
+		defer c.wg.Done()
+		c.heartbeat()
+	})
+	return nil
+}
+
+// heartbeat 定期向已连接的节点发送心跳，避免空闲连接被对端的心跳检测关闭
+func (c *Client) heartbeat() {
+	ticker := time.NewTicker(c.cfg.HeartTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, p := range c.allPeers() {
+				p.ping()
+			}
+		}
+	}
+}
+
+func (c *Client) Publish(subject string, data []byte) error {
+	if c.IsStop() {
+		return ErrClientClosed
+	}
+	// 本节点订阅的主题直接投递
+	if c.deliver(subject, clone(data), nil) > 0 {
+		return nil
+	}
+	p, err := c.peer(subject)
+	if err != nil {
+		return err
+	}
+	f := &frame{kind: framePublish, subject: subject, data: data}
+	if err = c.codec.validate(f); err != nil {
+		return err
+	}
+	_, err = p.send(f)
+	return err
+}
+
+func (c *Client) Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.RequestWithContext(ctx, subject, data)
+}
+
+func (c *Client) RequestWithContext(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	if c.IsStop() {
+		return nil, ErrClientClosed
+	}
+	reply := make(chan *frame, 1)
+	respond := func(data []byte) error {
+		select {
+		case reply <- &frame{kind: frameReply, data: clone(data)}:
+		default:
+		}
+		return nil
+	}
+	if c.deliver(subject, clone(data), respond) == 0 {
+		p, err := c.peer(subject)
+		if err != nil {
+			return nil, err
+		}
+		f := &frame{kind: frameRequest, id: c.seq.Add(1), subject: subject, data: data}
+		if err = c.codec.validate(f); err != nil {
+			return nil, err
+		}
+		// 先登记再发送，避免回复先于登记到达
+		c.addCall(f.id, &call{reply: reply})
+		defer c.removeCall(f.id)
+		conn, err := p.send(f)
+		if err != nil {
+			return nil, err
+		}
+		// 登记连接之前连接已断开时，OnClose 无法通知本请求
+		if c.bindCall(f.id, conn.ID()) && conn.IsStop() {
+			return nil, xerror.Wrapf(ErrConnectionLost, "subject:%s", subject)
+		}
+	}
+
+	select {
+	case f := <-reply:
+		switch f.kind {
+		case frameNoResponders:
+			return nil, xerror.Wrapf(ErrNoResponders, "subject:%s", subject)
+		case frameReply:
+			return f.data, nil
+		default:
+			return nil, xerror.Wrapf(ErrConnectionLost, "subject:%s", subject)
+		}
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, xerror.Wrapf(ErrTimeout, "subject:%s", subject)
+		}
+		return nil, xerror.Wrapf(ctx.Err(), "subject:%s", subject)
+	}
+}
+
+// Subscribe 订阅主题，支持 * 和 > 通配符，同一订阅的消息按到达顺序串行处理
+func (c *Client) Subscribe(subject string, subscriber iface.ISubscriber) (iface.ISubscription, error) {
+	if c.IsStop() {
+		return nil, ErrClientClosed
+	}
+	if subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	sub := &subscription{
+		client:     c,
+		subject:    subject,
+		pattern:    messageQue.SplitSubject(subject),
+		subscriber: subscriber,
+		queue:      make(chan *delivery, c.cfg.PendingLimit),
+		done:       make(chan struct{}),
+	}
+	c.subMu.Lock()
+	c.subs[sub] = struct{}{}
+	c.subMu.Unlock()
+	grs.Go(func(ctx context.Context) {
+		sub.loop()
+	})
+	return sub, nil
+}
+
+func (c *Client) removeSubscription(sub *subscription) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	delete(c.subs, sub)
+}
+
+// deliver 投递给本节点匹配的订阅，返回接收消息的订阅数
+func (c *Client) deliver(subject string, data []byte, reply func(data []byte) error) int {
+	tokens := messageQue.SplitSubject(subject)
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+	delivered := 0
+	for sub := range c.subs {
+		if sub.match(tokens) && sub.push(&delivery{data: data, reply: reply}) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// peer 获取主题对应节点的连接
+func (c *Client) peer(subject string) (*peer, error) {
+	if c.resolver == nil {
+		return nil, ErrNoResolver
+	}
+	address, ok := c.resolver.Resolve(subject)
+	if !ok {
+		return nil, xerror.Wrapf(ErrUnknownSubject, "subject:%s", subject)
+	}
+	c.peerMu.Lock()
+	defer c.peerMu.Unlock()
+	p, ok := c.peers[address]
+	if !ok {
+		p = &peer{client: c, address: address}
+		c.peers[address] = p
+	}
+	return p, nil
+}
+
+func (c *Client) allPeers() []*peer {
+	c.peerMu.Lock()
+	defer c.peerMu.Unlock()
+	peers := make([]*peer, 0, len(c.peers))
+	for _, p := range c.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+func (c *Client) addCall(id uint64, cl *call) {
+	c.callMu.Lock()
+	defer c.callMu.Unlock()
+	c.pending[id] = cl
+}
+
+// bindCall 记录请求所在的连接，连接断开时据此通知等待方；请求已完成时返回 false
+func (c *Client) bindCall(id uint64, connId int64) bool {
+	c.callMu.Lock()
+	defer c.callMu.Unlock()
+	cl, ok := c.pending[id]
+	if ok {
+		cl.connId = connId
+	}
+	return ok
+}
+
+func (c *Client) removeCall(id uint64) {
+	c.callMu.Lock()
+	defer c.callMu.Unlock()
+	delete(c.pending, id)
+}
+
+// complete 将回复交给等待方
+func (c *Client) complete(f *frame) {
+	c.callMu.Lock()
+	cl, ok := c.pending[f.id]
+	delete(c.pending, f.id)
+	c.callMu.Unlock()
+	if ok {
+		cl.reply <- f
+	}
+}
+
+func (c *Client) OnConnect(conn network.IConnection) error {
+	return nil
+}
+
+func (c *Client) OnMessage(conn network.IConnection, msg interface{}) error {
+	f, ok := msg.(*frame)
+	if !ok {
+		return nil
+	}
+	switch f.kind {
+	case framePublish:
+		c.deliver(f.subject, f.data, nil)
+	case frameRequest:
+		id := f.id
+		var replied atomic.Bool
+		reply := func(data []byte) error {
+			if !replied.CompareAndSwap(false, true) {
+				return nil
+			}
+			return conn.Send(&frame{kind: frameReply, id: id, data: data})
+		}
+		if c.deliver(f.subject, f.data, reply) == 0 {
+			return conn.Send(&frame{kind: frameNoResponders, id: id})
+		}
+	case frameReply, frameNoResponders:
+		c.complete(f)
+	case framePing:
+		return conn.Send(&frame{kind: framePong})
+	case framePong:
+	default:
+		glog.Warn("tcp: 未知的帧类型", zap.Uint8("kind", f.kind), zap.String("remote", conn.RemoteAddr()))
+	}
+	return nil
+}
+
+// OnClose 连接断开时通知在该连接上等待回复的请求
+func (c *Client) OnClose(conn network.IConnection, err error) {
+	c.callMu.Lock()
+	var lost []*call
+	for id, cl := range c.pending {
+		if cl.connId == conn.ID() {
+			lost = append(lost, cl)
+			delete(c.pending, id)
+		}
+	}
+	c.callMu.Unlock()
+	for _, cl := range lost {
+		cl.reply <- &frame{}
+	}
+}
+
+func (c *Client) Shutdown(ctx context.Context) error {
+	if !c.Stop() {
+		return nil
+	}
+	c.cancel()
+	for _, p := range c.allPeers() {
+		p.close()
+	}
+	if c.server != nil {
+		c.server.Shutdown(ctx)
+	}
+	c.subMu.Lock()
+	subs := make([]*subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.subMu.Unlock()
+	for _, sub := range subs {
+		_ = sub.Unsubscribe()
+	}
+	grs.WaitWithContext(ctx, &c.wg)
+	return nil
+}
+
+func clone(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(data)), data...)
+}
@@ -0,0 +1,101 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dzm2020/gas/pkg/network"
+)
+
+var (
+	ErrFrameTooLarge = errors.New("tcp: 帧超过最大长度")
+	ErrCorruptFrame  = errors.New("tcp: 帧格式错误")
+)
+
+// 帧类型
+const (
+	framePublish      byte = iota + 1 // 发布，无需回复
+	frameRequest                      // 请求，id 用于关联回复
+	frameReply                        // 回复
+	frameNoResponders                 // 对端没有匹配的订阅
+	framePing                         // 心跳
+	framePong                         // 心跳回复
+)
+
+// frameHeadLen length(4) + kind(1) + id(8) + subjectLen(2)
+const frameHeadLen = 4 + 1 + 8 + 2
+
+// frame 节点之间传输的帧
+//
+//	length(4，不含自身) | kind(1) | id(8) | subjectLen(2) | subject | data
+type frame struct {
+	kind    byte
+	id      uint64
+	subject string
+	data    []byte
+}
+
+var _ network.ICodec = (*codec)(nil)
+
+type codec struct {
+	maxFrameSize int
+}
+
+func (c *codec) Encode(msg interface{}) ([]byte, error) {
+	f, ok := msg.(*frame)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrCorruptFrame, msg)
+	}
+	if err := c.validate(f); err != nil {
+		return nil, err
+	}
+	size := frameHeadLen + len(f.subject) + len(f.data)
+	buf := make([]byte, frameHeadLen, size)
+	binary.BigEndian.PutUint32(buf, uint32(size-4))
+	buf[4] = f.kind
+	binary.BigEndian.PutUint64(buf[5:], f.id)
+	binary.BigEndian.PutUint16(buf[13:], uint16(len(f.subject)))
+	buf = append(buf, f.subject...)
+	return append(buf, f.data...), nil
+}
+
+// validate 检查帧能否编码，发送前调用，编码失败会导致写协程关闭连接
+func (c *codec) validate(f *frame) error {
+	if len(f.subject) > 0xFFFF {
+		return fmt.Errorf("%w: subject 长度 %d", ErrCorruptFrame, len(f.subject))
+	}
+	if size := frameHeadLen + len(f.subject) + len(f.data); size > c.maxFrameSize {
+		return fmt.Errorf("%w: %d", ErrFrameTooLarge, size)
+	}
+	return nil
+}
+
+func (c *codec) Decode(b []byte) (interface{}, int, error) {
+	if len(b) < 4 {
+		return nil, 0, nil
+	}
+	size := 4 + int(binary.BigEndian.Uint32(b))
+	if size > c.maxFrameSize {
+		return nil, 0, fmt.Errorf("%w: %d", ErrFrameTooLarge, size)
+	}
+	if size < frameHeadLen {
+		return nil, 0, ErrCorruptFrame
+	}
+	if len(b) < size {
+		return nil, 0, nil
+	}
+	subjectLen := int(binary.BigEndian.Uint16(b[13:]))
+	if frameHeadLen+subjectLen > size {
+		return nil, 0, ErrCorruptFrame
+	}
+	f := &frame{
+		kind:    b[4],
+		id:      binary.BigEndian.Uint64(b[5:]),
+		subject: string(b[frameHeadLen : frameHeadLen+subjectLen]),
+	}
+	if data := b[frameHeadLen+subjectLen : size]; len(data) > 0 {
+		f.data = append(make([]byte, 0, len(data)), data...)
+	}
+	return f, size, nil
+}
@@ -0,0 +1,26 @@
+package tcp
+
+import "time"
+
+// Config 节点直连消息队列配置
+type Config struct {
+	Listen        string        `json:"listen"`        // 监听地址，为空时使用服务发现中本节点的 Address:Port
+	DialTimeout   time.Duration `json:"dialTimeout"`   // 连接超时时间
+	ReconnectWait time.Duration `json:"reconnectWait"` // 连接失败后再次尝试连接的最小间隔
+	HeartTimeout  time.Duration `json:"heartTimeout"`  // 连接空闲超时时间，每三分之一超时时间发送一次心跳
+	SendQueueSize int           `json:"sendQueueSize"` // 每个连接的发送队列大小
+	PendingLimit  int           `json:"pendingLimit"`  // 每个订阅待处理消息的上限，超过后丢弃新消息
+	MaxFrameSize  int           `json:"maxFrameSize"`  // 单个帧的最大字节数
+}
+
+// defaultConfig 返回默认配置
+func defaultConfig() *Config {
+	return &Config{
+		DialTimeout:   time.Second,
+		ReconnectWait: time.Second,
+		HeartTimeout:  15 * time.Second,
+		SendQueueSize: 4096,
+		PendingLimit:  65536,
+		MaxFrameSize:  64 << 20,
+	}
+}
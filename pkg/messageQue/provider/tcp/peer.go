@@ -0,0 +1,73 @@
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/lib/xerror"
+	"github.com/dzm2020/gas/pkg/network"
+)
+
+// peer 到其他节点的连接，断开后在下次发送时重新连接
+type peer struct {
+	client  *Client
+	address string
+
+	mu       sync.Mutex
+	conn     network.IConnection
+	failedAt time.Time // 上次连接失败的时间
+}
+
+// connection 获取可用的连接，连接失败后 ReconnectWait 内不再尝试，直接返回错误
+func (p *peer) connection() (network.IConnection, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil && !p.conn.IsStop() {
+		return p.conn, nil
+	}
+	if wait := p.client.cfg.ReconnectWait; !p.failedAt.IsZero() && time.Since(p.failedAt) < wait {
+		return nil, xerror.Wrapf(ErrPeerUnavailable, "address:%s", p.address)
+	}
+	conn, err := network.Dial(p.client, "tcp://"+p.address, p.client.options()...)
+	if err != nil {
+		p.failedAt = time.Now()
+		return nil, xerror.Wrapf(err, "连接节点失败, address:%s", p.address)
+	}
+	p.conn, p.failedAt = conn, time.Time{}
+	return conn, nil
+}
+
+// send 发送帧，连接在发送前恰好断开时重新连接一次
+func (p *peer) send(f *frame) (network.IConnection, error) {
+	for retry := 0; ; retry++ {
+		conn, err := p.connection()
+		if err != nil {
+			return nil, err
+		}
+		err = conn.Send(f)
+		if errors.Is(err, network.ErrConnectionClosed) && retry == 0 {
+			continue
+		}
+		return conn, err
+	}
+}
+
+// ping 连接存在时发送心跳
+func (p *peer) ping() {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	if conn != nil && !conn.IsStop() {
+		_ = conn.Send(&frame{kind: framePing})
+	}
+}
+
+func (p *peer) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		_ = p.conn.Close(nil)
+		p.conn = nil
+	}
+}
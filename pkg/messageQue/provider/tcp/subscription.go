@@ -0,0 +1,73 @@
+package tcp
+
+import (
+	"sync"
+
+	"github.com/dzm2020/gas/pkg/glog"
+	"github.com/dzm2020/gas/pkg/messageQue"
+	"github.com/dzm2020/gas/pkg/messageQue/iface"
+
+	"go.uber.org/zap"
+)
+
+// delivery 投递给订阅者的消息，reply 为空表示无需回复
+type delivery struct {
+	data  []byte
+	reply func(data []byte) error
+}
+
+// subscription 订阅，消息在独立的协程中按顺序交给订阅者
+type subscription struct {
+	client     *Client
+	subject    string
+	pattern    []string
+	subscriber iface.ISubscriber
+	queue      chan *delivery
+	done       chan struct{}
+	once       sync.Once
+}
+
+func (s *subscription) match(tokens []string) bool {
+	return messageQue.MatchSubject(s.pattern, tokens)
+}
+
+// push 放入待处理队列，队列已满或订阅已取消时返回 false
+func (s *subscription) push(d *delivery) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.queue <- d:
+		return true
+	default:
+		glog.Warn("tcp: 订阅待处理消息超过上限，丢弃消息", zap.String("subject", s.subject), zap.Int("limit", cap(s.queue)))
+		return false
+	}
+}
+
+func (s *subscription) loop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case d := <-s.queue:
+			response := func(data []byte) error {
+				if d.reply == nil {
+					return nil
+				}
+				return d.reply(data)
+			}
+			_ = s.subscriber.OnMessage(d.data, response)
+		}
+	}
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.once.Do(func() {
+		s.client.removeSubscription(s)
+		close(s.done)
+	})
+	return nil
+}
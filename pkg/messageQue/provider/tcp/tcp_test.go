@@ -0,0 +1,170 @@
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/network"
+)
+
+type subscriberFunc func(request []byte, response func(data []byte) error)
+
+func (f subscriberFunc) OnMessage(request []byte, response func(data []byte) error) error {
+	f(request, response)
+	return nil
+}
+
+// staticResolver 以固定的主题和地址映射解析节点
+type staticResolver struct {
+	mu    sync.RWMutex
+	local string
+	peers map[string]string
+}
+
+func (r *staticResolver) LocalAddress() string {
+	return r.local
+}
+
+func (r *staticResolver) Resolve(subject string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	address, ok := r.peers[subject]
+	return address, ok
+}
+
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func newClient(t *testing.T, local string, peers map[string]string) *Client {
+	client := New(&Config{ReconnectWait: 10 * time.Millisecond, DialTimeout: time.Second})
+	client.SetResolver(&staticResolver{local: local, peers: peers})
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Shutdown(context.Background())
+	})
+	return client
+}
+
+// TestCodec 测试帧编解码，包括不完整的数据和一次读取多个帧
+func TestCodec(t *testing.T) {
+	c := &codec{maxFrameSize: 1024}
+	first, _ := c.Encode(&frame{kind: frameRequest, id: 7, subject: "cluster.1", data: []byte("ping")})
+	second, _ := c.Encode(&frame{kind: framePing})
+	buf := append(append([]byte{}, first...), second...)
+
+	if msg, n, err := c.Decode(buf[:len(first)-1]); msg != nil || n != 0 || err != nil {
+		t.Fatalf("不完整的数据应等待更多数据: %v %d %v", msg, n, err)
+	}
+	msg, n, err := c.Decode(buf)
+	f, ok := msg.(*frame)
+	if err != nil || !ok || n != len(first) || f.id != 7 || f.subject != "cluster.1" || !bytes.Equal(f.data, []byte("ping")) {
+		t.Fatalf("解码结果不正确: %+v %d %v", msg, n, err)
+	}
+	if msg, _, _ = c.Decode(buf[n:]); msg.(*frame).kind != framePing {
+		t.Fatalf("第二个帧解码错误: %+v", msg)
+	}
+	if _, err = c.Encode(&frame{kind: framePublish, data: make([]byte, 2048)}); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("want ErrFrameTooLarge, got %v", err)
+	}
+}
+
+// TestPublishRequest 测试节点之间的发布和请求
+func TestPublishRequest(t *testing.T) {
+	addrA, addrB := freeAddress(t), freeAddress(t)
+	a := newClient(t, addrA, map[string]string{"cluster.2": addrB, "cluster.3": addrB})
+	b := newClient(t, addrB, nil)
+
+	received := make(chan string, 10)
+	_, err := b.Subscribe("cluster.2", subscriberFunc(func(request []byte, response func([]byte) error) {
+		received <- string(request)
+		_ = response(append([]byte("re:"), request...))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.Publish("cluster.2", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if got != "hello" {
+			t.Fatalf("want hello, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("未收到消息")
+	}
+
+	reply, err := a.Request("cluster.2", []byte("ping"), time.Second)
+	if err != nil || string(reply) != "re:ping" {
+		t.Fatalf("want re:ping, got %s (err=%v)", reply, err)
+	}
+	if _, err = a.Request("cluster.3", nil, time.Second); !errors.Is(err, ErrNoResponders) {
+		t.Fatalf("want ErrNoResponders, got %v", err)
+	}
+	if err = a.Publish("cluster.9", nil); !errors.Is(err, ErrUnknownSubject) {
+		t.Fatalf("want ErrUnknownSubject, got %v", err)
+	}
+	// 节点间的连接不是客户端连接，不计入连接数
+	if n := network.ConnectionCount(); n != 0 {
+		t.Fatalf("传输连接不应计入连接数, got %d", n)
+	}
+}
+
+// TestReconnect 测试对端重启后重新连接，断开时等待中的请求立即失败
+func TestReconnect(t *testing.T) {
+	addrA, addrB := freeAddress(t), freeAddress(t)
+	a := newClient(t, addrA, map[string]string{"cluster.2": addrB})
+	b := New(nil)
+	b.SetResolver(&staticResolver{local: addrB})
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	block := make(chan struct{})
+	_, _ = b.Subscribe("cluster.2", subscriberFunc(func(request []byte, response func([]byte) error) {
+		<-block
+	}))
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := a.Request("cluster.2", nil, 5*time.Second)
+		result <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	_ = b.Shutdown(context.Background())
+	close(block)
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Fatalf("want ErrConnectionLost, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("连接断开后请求未立即失败")
+	}
+
+	b = newClient(t, addrB, nil)
+	_, _ = b.Subscribe("cluster.2", subscriberFunc(func(request []byte, response func([]byte) error) {
+		_ = response(request)
+	}))
+	var err error
+	for i := 0; i < 50; i++ {
+		if _, err = a.Request("cluster.2", []byte("x"), time.Second); err == nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("对端重启后未能重新连接: %v", err)
+}
@@ -0,0 +1,24 @@
+package messageQue
+
+import "strings"
+
+// SplitSubject 将主题按层级拆分
+func SplitSubject(subject string) []string {
+	return strings.Split(subject, ".")
+}
+
+// MatchSubject 按 NATS 规则匹配已拆分的主题：* 匹配一个层级，> 匹配之后的一个或多个层级
+func MatchSubject(pattern, tokens []string) bool {
+	for i, token := range pattern {
+		if token == ">" {
+			return len(tokens) > i
+		}
+		if i >= len(tokens) {
+			return false
+		}
+		if token != "*" && token != tokens[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(tokens)
+}
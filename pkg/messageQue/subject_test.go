@@ -0,0 +1,24 @@
+package messageQue
+
+import "testing"
+
+// TestMatchSubject 测试通配符匹配
+func TestMatchSubject(t *testing.T) {
+	cases := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"cluster.node.1", "cluster.node.1", true},
+		{"cluster.node.1", "cluster.node.2", false},
+		{"cluster.*.1", "cluster.node.1", true},
+		{"cluster.*", "cluster.node.1", false},
+		{"cluster.>", "cluster.node.1", true},
+		{"cluster.>", "cluster", false},
+		{"cluster.node", "cluster.node.1", false},
+	}
+	for _, c := range cases {
+		if got := MatchSubject(SplitSubject(c.pattern), SplitSubject(c.subject)); got != c.want {
+			t.Errorf("%s ~ %s: want %v, got %v", c.pattern, c.subject, c.want, got)
+		}
+	}
+}
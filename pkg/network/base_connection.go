@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/dzm2020/gas/pkg/glog"
@@ -35,12 +36,12 @@ func newBaseConn(ctx context.Context, network string,
 		options:     options,
 		conn:        conn,
 		remoteAddr:  remoteAddr,
-		lastActive:  time.Now(),
 		typ:         typ,
 		sendChan:    make(chan interface{}, options.SendChanSize),
 		writeBuffer: buffer.New(options.SendBufferSize),
 		readBuffer:  buffer.New(options.ReadBufSize),
 	}
+	bc.lastActive.Store(time.Now().UnixNano())
 	bc.ctx, bc.cancel = context.WithCancel(ctx)
 	glog.Info("新建网络连接", zap.Int64("connectionId", bc.ID()),
 		zap.String("network", bc.Network()),
@@ -60,7 +61,7 @@ type baseConn struct {
 	handler     IHandler         // 业务处理回调接口
 	options     *Options         // 连接选项配置
 	conn        net.Conn         // 底层的原生网络连接
-	lastActive  time.Time        // 最后活动时间，用于心跳超时检测
+	lastActive  atomic.Int64     // 最后活动时间（UnixNano），读循环与心跳循环并发访问
 	typ         ConnType         // 连接类型（Accept 或 Connect）
 	user        interface{}      // 用户自定义上下文数据（通过 SetContext 设置）
 	sendChan    chan interface{} // 发送消息的通道，用于异步发送
@@ -191,7 +192,7 @@ func (b *baseConn) heartLoop(connection IConnection) {
 		case <-b.ctx.Done():
 			return
 		case <-ticker.C:
-			if time.Since(b.lastActive) > timeout {
+			if time.Since(time.Unix(0, b.lastActive.Load())) > timeout {
 				err = ErrConnHeartTimeout
 				return
 			}
@@ -318,7 +319,8 @@ func (b *baseConn) write(c io.Writer, msgList ...interface{}) error {
 //   2. 将数据追加到读缓冲区
 //   3. 尝试从缓冲区解码出完整消息
 //   4. 如果解码成功，移除已处理的数据，调用 OnMessage
-//   5. 如果解码失败（数据不完整），等待更多数据
+//   5. 缓冲区中还有完整消息时继续解码，一次读取可能包含多个消息
+//   6. 如果解码失败（数据不完整），等待更多数据
 //
 // 参数:
 //   - connection: 连接接口
@@ -328,17 +330,34 @@ func (b *baseConn) write(c io.Writer, msgList ...interface{}) error {
 //   - int: 已处理的字节数
 //   - error: 解码或消息处理过程中的错误
 func (b *baseConn) process(connection IConnection, data []byte) (int, error) {
-	b.lastActive = time.Now()
+	b.lastActive.Store(time.Now().UnixNano())
 	_, _ = b.readBuffer.Write(data)
 	msg, n, err := b.options.Codec.Decode(b.readBuffer.Bytes())
 	if err != nil {
 		return n, err
 	}
 	_ = b.readBuffer.Skip(n)
-	return n, b.OnMessage(connection, msg)
+	if err = b.OnMessage(connection, msg); err != nil || n == 0 {
+		return n, err
+	}
+	for b.readBuffer.Len() > 0 {
+		msg, next, err := b.options.Codec.Decode(b.readBuffer.Bytes())
+		if err != nil {
+			return n, err
+		}
+		if next == 0 {
+			break
+		}
+		_ = b.readBuffer.Skip(next)
+		n += next
+		if err = b.OnMessage(connection, msg); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
 }
 
-// Close 关闭连接（线程安全，可重复调用）
+// Close 关闭连接
 // 执行优雅关闭流程：调用业务回调、从管理器移除、取消上下文
 //
 // 参数:
@@ -346,18 +365,13 @@ func (b *baseConn) process(connection IConnection, data []byte) (int, error) {
 //   - err: 关闭原因，可能为 nil（正常关闭）
 //
 // 工作流程:
-//   1. 尝试停止连接（如果已停止则直接返回）
-//   2. 调用业务处理器的 OnClose 回调
-//   3. 从连接管理器中移除该连接
-//   4. 取消上下文，通知所有等待的 goroutine
+//   1. 调用业务处理器的 OnClose 回调
+//   2. 从连接管理器中移除该连接
+//   3. 取消上下文，通知所有等待的 goroutine
 //
 // 注意:
-//   - 该方法是线程安全的，可以多次调用
-//   - 只有第一次调用会真正执行关闭操作
+//   - 由具体连接类型的 Close 在成功停止连接（Stop 返回 true）后调用，保证只执行一次
 func (b *baseConn) Close(connection IConnection, err error) {
-	if !b.Stop() {
-		return
-	}
 	b.OnClose(connection, err)
 	RemoveConnection(connection)
 	b.cancel()
@@ -14,16 +14,19 @@ type Options struct {
 	CheckOrigin func(r *http.Request) bool // WebSocket Origin 检查函数（nil 表示不检查）
 
 	Codec          ICodec        // 协议编解码器
+	DialTimeout    time.Duration // 主动连接的超时时间
 	HeartTimeout   time.Duration // 心跳超时（0表示不检测超时）
 	SendBufferSize int           // 发送队列缓冲大小
 	ReadBufSize    int           // 读缓冲区大小
 	SendChanSize   int
 	UdpRcvChanSize int
+	Untracked      bool // 连接不登记到全局连接管理器，不计入 ConnectionCount，用于节点间的内部连接
 }
 
 func loadOptions(options ...Option) *Options {
 	opts := &Options{
 		Codec:          &EmptyCodec{},
+		DialTimeout:    3 * time.Second,
 		HeartTimeout:   5 * time.Second,
 		SendBufferSize: 1024 * 4,
 		ReadBufSize:    1024 * 4,
@@ -72,6 +75,16 @@ func WithKeepAlive(heartInterval time.Duration) Option {
 	}
 }
 
+// WithDialTimeout 设置主动连接的超时时间
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		if timeout <= 0 {
+			return
+		}
+		opts.DialTimeout = timeout
+	}
+}
+
 // WithTLS 设置 TLS 证书和私钥文件路径
 func WithTLS(certFile, keyFile string) Option {
 	return func(opts *Options) {
@@ -106,6 +119,14 @@ func WithUdpRcvChanSize(udpRcvChanSize int) Option {
 	}
 }
 
+// WithUntracked 连接不登记到全局连接管理器
+// 节点间的内部连接（集群传输、成员协议）不是客户端连接，不应计入 ConnectionCount 和负载
+func WithUntracked() Option {
+	return func(opts *Options) {
+		opts.Untracked = true
+	}
+}
+
 // WithCheckOrigin 设置 WebSocket Origin 检查函数
 // 如果为 nil，则使用默认行为（允许所有 Origin，不推荐用于生产环境）
 func WithCheckOrigin(checkOrigin func(r *http.Request) bool) Option {
@@ -0,0 +1,53 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/dzm2020/gas/pkg/lib/grs"
+)
+
+// Dial 主动连接服务器，目前只支持 TCP
+// 连接与服务器接受的连接使用相同的读写协程、编解码器和心跳检测，Type 为 Connect
+//
+// 参数:
+//   - handler: 业务处理回调接口，不能为 nil
+//   - protoAddr: 协议地址，例如 "tcp://127.0.0.1:8080"
+//   - option: 可选的配置项
+//
+// 示例:
+//
+//	conn, err := Dial(handler, "tcp://127.0.0.1:8080", WithCodec(codec))
+func Dial(handler IHandler, protoAddr string, option ...Option) (IConnection, error) {
+	if handler == nil {
+		return nil, errors.New("handler is nil")
+	}
+	network, address := parseProtoAddr(protoAddr)
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, ErrUnsupportedProtocol(network)
+	}
+	options := loadOptions(option...)
+	conn, err := net.DialTimeout(network, address, options.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	connection := newTCPConnection(context.Background(), conn.(*net.TCPConn), Connect, options)
+	connection.SetHandler(handler)
+	if !options.Untracked {
+		AddConnection(connection)
+	}
+
+	grs.Go(func(ctx context.Context) {
+		connection.readLoop()
+	})
+	grs.Go(func(ctx context.Context) {
+		connection.writeLoop()
+	})
+	grs.Go(func(ctx context.Context) {
+		connection.heartLoop(connection)
+	})
+	return connection, nil
+}
@@ -71,7 +71,9 @@ func (s *TCPServer) newTcpCon(conn net.Conn) {
 	}
 	connection := newTCPConnection(s.ctx, tcpCon, Accept, s.options)
 	connection.SetHandler(s.handler)
-	AddConnection(connection)
+	if !s.options.Untracked {
+		AddConnection(connection)
+	}
 
 	s.waitGroup.Add(1)
 	grs.Go(func(ctx context.Context) {
@@ -19,10 +19,9 @@ type UDPConnection struct {
 	sendChan   chan<- *udpPacket
 }
 
-func newUDPConnection(ctx context.Context, conn *net.UDPConn, typ ConnType, remoteAddr *net.UDPAddr, server *UDPServer) *UDPConnection {
+func newUDPConnection(ctx context.Context, conn *net.UDPConn, typ ConnType, connKey string, remoteAddr *net.UDPAddr, server *UDPServer) *UDPConnection {
 	base := newBaseConn(ctx, "udp", typ, conn, remoteAddr, server.options)
 
-	connKey := remoteAddr.String()
 	udpConn := &UDPConnection{
 		baseConn:   base,
 		remoteAddr: remoteAddr,
@@ -97,7 +97,7 @@ func (s *UDPServer) readLoop() {
 			continue
 		}
 
-		connKey := remoteAddrCopy.String()
+		connKey := s.connKey(remoteAddrCopy)
 		udpConn, exists := GetUDPConnection(connKey)
 		if !exists {
 			udpConn = s.addConnection(connKey, remoteAddrCopy)
@@ -127,6 +127,33 @@ func (s *UDPServer) writeLoop() {
 	}
 }
 
+// connKey 虚拟连接的键，包含本地地址，同一进程内的多个 UDP 服务器收到同一远程地址的数据包时互不干扰
+func (s *UDPServer) connKey(remoteAddr *net.UDPAddr) string {
+	return s.conn.LocalAddr().String() + "<-" + remoteAddr.String()
+}
+
+// SendTo 向指定地址发送消息，不需要对方先发来数据包，用于主动发起通信的协议（如 gossip）
+// 消息经过编码器编码后由写协程发送
+func (s *UDPServer) SendTo(address string, msg interface{}) error {
+	if s.IsStop() {
+		return ErrConnectionClosed
+	}
+	remoteAddr, err := net.ResolveUDPAddr(s.network, address)
+	if err != nil {
+		return err
+	}
+	data, err := s.options.Codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case s.sendChan <- &udpPacket{data: data, remoteAddr: remoteAddr}:
+	default:
+		return ErrChannelFull
+	}
+	return nil
+}
+
 func (s *UDPServer) addConnection(connKey string, remoteAddr *net.UDPAddr) *UDPConnection {
 	// 双重检查，避免并发创建
 	udpConn, exists := GetUDPConnection(connKey)
@@ -134,7 +161,7 @@ func (s *UDPServer) addConnection(connKey string, remoteAddr *net.UDPAddr) *UDPC
 		return udpConn
 	}
 
-	udpConn = newUDPConnection(s.ctx, s.conn, Accept, remoteAddr, s)
+	udpConn = newUDPConnection(s.ctx, s.conn, Accept, connKey, remoteAddr, s)
 
 	// 双重检查：如果添加时已存在，使用已存在的连接
 	existingConn, added := AddUDPConnection(connKey, udpConn)
@@ -142,7 +169,9 @@ func (s *UDPServer) addConnection(connKey string, remoteAddr *net.UDPAddr) *UDPC
 		return existingConn
 	}
 	udpConn.SetHandler(s.handler)
-	AddConnection(udpConn)
+	if !s.options.Untracked {
+		AddConnection(udpConn)
+	}
 
 	// 只有成功添加后才启动 goroutine
 	s.waitGroup.Add(1)
@@ -160,11 +189,24 @@ func (s *UDPServer) addConnection(connKey string, remoteAddr *net.UDPAddr) *UDPC
 	return udpConn
 }
 
+// flush 关闭前发送队列中剩余的数据包，例如节点离开时的通知
+func (s *UDPServer) flush() {
+	for {
+		select {
+		case packet := <-s.sendChan:
+			_, _ = s.conn.WriteToUDP(packet.data, packet.remoteAddr)
+		default:
+			return
+		}
+	}
+}
+
 func (s *UDPServer) Shutdown(ctx context.Context) {
 	if !s.Stop() {
 		return
 	}
 
+	s.flush()
 	_ = s.conn.Close()
 	s.baseServer.Shutdown(ctx)
 
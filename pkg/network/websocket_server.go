@@ -106,7 +106,9 @@ func (s *WebSocketServer) handleWebSocket(w http.ResponseWriter, r *http.Request
 	wsConn := newWebSocketConnection(s.ctx, conn, Accept, s.options)
 	wsConn.SetHandler(s.handler)
 
-	AddConnection(wsConn)
+	if !s.options.Untracked {
+		AddConnection(wsConn)
+	}
 
 	s.waitGroup.Add(1)
 	grs.Go(func(ctx context.Context) {
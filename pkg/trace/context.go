@@ -0,0 +1,105 @@
+// Package trace 提供轻量级分布式链路追踪，使用 W3C traceparent 格式在节点间传播上下文
+package trace
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"strings"
+)
+
+var (
+	ErrInvalidTraceParent = errors.New("traceparent 格式错误")
+)
+
+const (
+	traceParentVersion = "00"
+	flagSampled        = 0x01
+)
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext 跨进程传播的追踪上下文
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+// TraceParent 按 W3C 格式编码: version-traceId-spanId-flags
+func (c SpanContext) TraceParent() string {
+	if !c.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return traceParentVersion + "-" + c.TraceID.String() + "-" + c.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent 解析 W3C traceparent 字符串
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceParent
+	}
+	if parts[0] == "ff" {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceParent
+	}
+	sc.Sampled = flags[0]&flagSampled == flagSampled
+	return sc, nil
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return
+}
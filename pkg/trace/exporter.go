@@ -0,0 +1,30 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/dzm2020/gas/pkg/lib/factory"
+)
+
+var (
+	exporterMgr = factory.New[IExporter]()
+)
+
+// GetFactoryMgr 获取导出器工厂，自定义导出器通过它注册
+func GetFactoryMgr() *factory.Manager[IExporter] {
+	return exporterMgr
+}
+
+// ExporterConfig 导出器配置
+type ExporterConfig struct {
+	Type   string                 `json:"type" yaml:"type"`     // 导出器类型，如 "file"
+	Config map[string]interface{} `json:"config" yaml:"config"` // 导出器配置
+}
+
+// IExporter span 导出器
+type IExporter interface {
+	// Export 导出一个已结束的 span，需要并发安全
+	Export(span *SpanData) error
+	// Shutdown 刷新缓冲并释放资源
+	Shutdown(ctx context.Context) error
+}
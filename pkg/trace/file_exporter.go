@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/lib/grs"
+	"github.com/dzm2020/gas/pkg/lib/stopper"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	_ = exporterMgr.Register("file", func(args ...any) (IExporter, error) {
+		cfg := defaultFileConfig()
+		if len(args) > 0 && args[0] != nil {
+			config, ok := args[0].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("file exporter: config must be map[string]interface{}, got %T", args[0])
+			}
+			vp := viper.New()
+			vp.Set("", config)
+			if err := vp.UnmarshalKey("", cfg); err != nil {
+				return nil, fmt.Errorf("file exporter: failed to unmarshal config: %w", err)
+			}
+		}
+		return NewFileExporter(cfg)
+	})
+}
+
+// FileConfig 文件导出器配置
+type FileConfig struct {
+	Path          string        `json:"path"`          // 输出文件路径
+	FlushInterval time.Duration `json:"flushInterval"` // 刷新间隔
+}
+
+func defaultFileConfig() *FileConfig {
+	return &FileConfig{
+		Path:          "./logs/trace.log",
+		FlushInterval: time.Second,
+	}
+}
+
+var _ IExporter = (*FileExporter)(nil)
+
+// FileExporter 以 JSON Lines 格式将 span 写入本地文件
+type FileExporter struct {
+	stopper.Stopper
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	enc    *json.Encoder
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewFileExporter(cfg *FileConfig) (*FileExporter, error) {
+	if cfg == nil {
+		cfg = defaultFileConfig()
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	e := &FileExporter{
+		file:   file,
+		writer: writer,
+		enc:    json.NewEncoder(writer),
+		done:   make(chan struct{}),
+	}
+
+	interval := cfg.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	var ctx context.Context
+	ctx, e.cancel = context.WithCancel(context.Background())
+	grs.Go(func(context.Context) {
+		e.flushLoop(ctx, interval)
+	})
+	return e, nil
+}
+
+func (e *FileExporter) Export(span *SpanData) error {
+	if e.IsStop() {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(span)
+}
+
+func (e *FileExporter) flushLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer func() {
+		ticker.Stop()
+		close(e.done)
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+func (e *FileExporter) flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.writer.Flush()
+}
+
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	if !e.Stop() {
+		return nil
+	}
+	e.cancel()
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.writer.Flush(); err != nil {
+		_ = e.file.Close()
+		return err
+	}
+	return e.file.Close()
+}
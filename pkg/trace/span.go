@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"sync"
+	"time"
+)
+
+// SpanData 导出给 Exporter 的 span 快照
+type SpanData struct {
+	TraceID    string            `json:"traceId"`
+	SpanID     string            `json:"spanId"`
+	ParentID   string            `json:"parentId,omitempty"`
+	Name       string            `json:"name"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Duration   time.Duration     `json:"duration"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Span 一次操作的追踪记录，nil Span 的所有方法都是安全的空操作
+type Span struct {
+	mu       sync.Mutex
+	tracer   *Tracer
+	ctx      SpanContext
+	parentID SpanID
+	name     string
+	start    time.Time
+	attrs    map[string]string
+	ended    bool
+}
+
+// Context 返回 span 的传播上下文
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// TraceParent 返回用于向下游传播的 traceparent
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return s.ctx.TraceParent()
+}
+
+// IsRecording 是否被采样，调用方可据此跳过昂贵的属性计算
+func (s *Span) IsRecording() bool {
+	return s != nil && s.ctx.Sampled
+}
+
+// SetAttr 设置 span 属性
+func (s *Span) SetAttr(key, value string) *Span {
+	if !s.IsRecording() {
+		return s
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = value
+	return s
+}
+
+// End 结束 span，未采样的 span 不会导出
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.mu.Unlock()
+
+	if !s.ctx.Sampled {
+		return
+	}
+
+	end := time.Now()
+	data := &SpanData{
+		TraceID:    s.ctx.TraceID.String(),
+		SpanID:     s.ctx.SpanID.String(),
+		Name:       s.name,
+		Start:      s.start,
+		End:        end,
+		Duration:   end.Sub(s.start),
+		Attributes: s.attrs,
+	}
+	if s.parentID.IsValid() {
+		data.ParentID = s.parentID.String()
+	}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	s.tracer.export(data)
+}
@@ -0,0 +1,112 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTraceParent 测试 traceparent 编解码
+func TestTraceParent(t *testing.T) {
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	parsed, err := ParseTraceParent(sc.TraceParent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != sc {
+		t.Fatalf("解析结果不一致: %+v != %+v", parsed, sc)
+	}
+
+	for _, s := range []string{
+		"",
+		"00-00000000000000000000000000000000-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err = ParseTraceParent(s); err == nil {
+			t.Fatalf("非法 traceparent 未报错: %q", s)
+		}
+	}
+}
+
+// TestFileExporter 测试父子 span 的传播和文件导出
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	cfg := &Config{
+		Enable:      true,
+		SampleRatio: 1,
+		Exporter: ExporterConfig{
+			Type:   "file",
+			Config: map[string]interface{}{"path": path},
+		},
+	}
+	if err := Init(cfg, map[string]string{"nodeId": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	root := Start("root", "")
+	child := Start("child", root.TraceParent())
+	child.End(nil)
+	root.End(nil)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if Start("noop", "") != nil {
+		t.Fatal("关闭后应返回空 span")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var spans []SpanData
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var data SpanData
+		if err = json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			t.Fatal(err)
+		}
+		spans = append(spans, data)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("期望导出 2 个 span，实际 %d", len(spans))
+	}
+	if spans[0].TraceID != spans[1].TraceID || spans[0].ParentID != spans[1].SpanID {
+		t.Fatalf("父子关系错误: %+v", spans)
+	}
+	if spans[0].Attributes["nodeId"] != "1" {
+		t.Fatal("缺少节点属性")
+	}
+}
+
+// TestSampleRatio 测试采样率为 0 时不导出但仍传播上下文
+func TestSampleRatio(t *testing.T) {
+	cfg := &Config{
+		Enable:      true,
+		SampleRatio: 0,
+		Exporter: ExporterConfig{
+			Type:   "file",
+			Config: map[string]interface{}{"path": filepath.Join(t.TempDir(), "trace.log")},
+		},
+	}
+	if err := Init(cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer Shutdown(context.Background())
+
+	span := Start("root", "")
+	if span.IsRecording() {
+		t.Fatal("采样率为 0 不应记录")
+	}
+	child := Start("child", span.TraceParent())
+	if child.Context().TraceID != span.Context().TraceID {
+		t.Fatal("未采样的 span 也应传播 traceId")
+	}
+}
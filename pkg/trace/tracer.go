@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/dzm2020/gas/pkg/glog"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnsupportedExporter = errors.New("不支持的追踪导出器类型")
+)
+
+var (
+	global atomic.Pointer[Tracer]
+)
+
+// Config 链路追踪配置
+type Config struct {
+	Enable      bool           `json:"enable" yaml:"enable"`           // 是否开启追踪
+	SampleRatio float64        `json:"sampleRatio" yaml:"sampleRatio"` // 根 span 采样率 [0,1]
+	Exporter    ExporterConfig `json:"exporter" yaml:"exporter"`       // 导出器配置
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Enable:      false,
+		SampleRatio: 1,
+		Exporter: ExporterConfig{
+			Type: "file",
+		},
+	}
+}
+
+// Tracer 负责采样决策和 span 导出
+type Tracer struct {
+	ratio    float64
+	exporter IExporter
+	resource map[string]string // 附加到每个 span 的节点信息
+}
+
+// Init 根据配置初始化全局 Tracer，未开启时所有 Start 调用都返回 nil span
+func Init(cfg *Config, resource map[string]string) error {
+	if cfg == nil || !cfg.Enable {
+		global.Store(nil)
+		return nil
+	}
+	creator, ok := exporterMgr.Get(cfg.Exporter.Type)
+	if !ok {
+		return ErrUnsupportedExporter
+	}
+	exporter, err := creator(cfg.Exporter.Config)
+	if err != nil {
+		return err
+	}
+	global.Store(&Tracer{
+		ratio:    cfg.SampleRatio,
+		exporter: exporter,
+		resource: resource,
+	})
+	return nil
+}
+
+// Shutdown 关闭全局 Tracer 并刷新导出器
+func Shutdown(ctx context.Context) error {
+	t := global.Swap(nil)
+	if t == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// Start 创建 span，parent 为上游传入的 traceparent，为空时创建根 span
+func Start(name string, parent string) *Span {
+	t := global.Load()
+	if t == nil {
+		return nil
+	}
+	return t.Start(name, parent)
+}
+
+func (t *Tracer) Start(name string, parent string) *Span {
+	span := &Span{
+		tracer: t,
+		name:   name,
+		start:  time.Now(),
+	}
+	if parentCtx, err := ParseTraceParent(parent); err == nil {
+		span.ctx = SpanContext{
+			TraceID: parentCtx.TraceID,
+			SpanID:  newSpanID(),
+			Sampled: parentCtx.Sampled,
+		}
+		span.parentID = parentCtx.SpanID
+	} else {
+		span.ctx = SpanContext{
+			TraceID: newTraceID(),
+			SpanID:  newSpanID(),
+			Sampled: t.shouldSample(),
+		}
+	}
+	if span.ctx.Sampled && len(t.resource) > 0 {
+		span.attrs = make(map[string]string, len(t.resource))
+		for k, v := range t.resource {
+			span.attrs[k] = v
+		}
+	}
+	return span
+}
+
+// shouldSample 根 span 的采样决策，子 span 跟随父 span
+func (t *Tracer) shouldSample() bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	if t.ratio <= 0 {
+		return false
+	}
+	return rand.Float64() < t.ratio
+}
+
+func (t *Tracer) export(data *SpanData) {
+	if err := t.exporter.Export(data); err != nil {
+		glog.Error("导出追踪数据失败", zap.String("span", data.Name), zap.Error(err))
+	}
+}